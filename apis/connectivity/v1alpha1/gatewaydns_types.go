@@ -21,6 +21,88 @@ type GatewayDNSSpec struct {
 	// resolutionType indicates the method the controller will use to discover
 	// the ip of the service.
 	ResolutionType GatewayResolutionType `json:"resolutionType,omitempty"`
+
+	// clusterWeights assign relative weights to the gateways of the clusters
+	// matched by their clusterSelector. The first matching entry applies and
+	// takes precedence over the gateway-weight label of the cluster.
+	// +optional
+	ClusterWeights []ClusterWeight `json:"clusterWeights,omitempty"`
+
+	// deletionTimeout is how long the deletion of the GatewayDNS waits for
+	// every cluster in its namespace to confirm the removal of its records,
+	// before giving up on the clusters that did not. Defaults to 10m.
+	// +optional
+	DeletionTimeout *metav1.Duration `json:"deletionTimeout,omitempty"`
+
+	// clusterLifecycle sets how the GatewayDNS treats the Clusters that are
+	// being deleted, paused or not ready.
+	// +optional
+	ClusterLifecycle ClusterLifecyclePolicy `json:"clusterLifecycle,omitempty"`
+
+	// staleRecordTTL is how long the records of a gateway are kept after its
+	// cluster became unreachable. Unset, they are kept until it is reachable
+	// again.
+	// +optional
+	StaleRecordTTL *metav1.Duration `json:"staleRecordTTL,omitempty"`
+}
+
+// ClusterLifecyclePolicy sets how the Cluster API lifecycle of the clusters
+// affects a GatewayDNS.
+type ClusterLifecyclePolicy struct {
+	// deleting sets what happens to the gateways of the Clusters being
+	// deleted. Withdraw, the default, stops publishing them. Publish keeps
+	// publishing them until the Cluster is gone.
+	// +kubebuilder:validation:Enum=Withdraw;Publish
+	// +optional
+	Deleting DeletingClusterPolicy `json:"deleting,omitempty"`
+
+	// paused sets whether the EndpointSlices of paused Clusters are written.
+	// Skip, the default, leaves them as they are until the Cluster is
+	// resumed. Converge writes them as usual.
+	// +kubebuilder:validation:Enum=Skip;Converge
+	// +optional
+	Paused PausedClusterPolicy `json:"paused,omitempty"`
+
+	// notReady sets how the Clusters whose ControlPlaneReady or
+	// InfrastructureReady condition is not True are treated. Unreachable, the
+	// default, treats their gateways as unreachable without querying them and
+	// does not write their EndpointSlices. Ignore treats them as usual.
+	// +kubebuilder:validation:Enum=Unreachable;Ignore
+	// +optional
+	NotReady NotReadyClusterPolicy `json:"notReady,omitempty"`
+}
+
+type DeletingClusterPolicy string
+
+const (
+	DeletingClusterPolicyWithdraw DeletingClusterPolicy = "Withdraw"
+	DeletingClusterPolicyPublish  DeletingClusterPolicy = "Publish"
+)
+
+type PausedClusterPolicy string
+
+const (
+	PausedClusterPolicySkip     PausedClusterPolicy = "Skip"
+	PausedClusterPolicyConverge PausedClusterPolicy = "Converge"
+)
+
+type NotReadyClusterPolicy string
+
+const (
+	NotReadyClusterPolicyUnreachable NotReadyClusterPolicy = "Unreachable"
+	NotReadyClusterPolicyIgnore      NotReadyClusterPolicy = "Ignore"
+)
+
+// ClusterWeight assigns a weight to the gateways of the matching clusters.
+type ClusterWeight struct {
+	// clusterSelector is a label selector that matches the clusters this
+	// weight applies to.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	// weight is the relative weight of the gateways of the matching clusters
+	// in DNS answers. Gateways without a weight have a weight of 1.
+	// +kubebuilder:validation:Minimum=1
+	Weight int32 `json:"weight"`
 }
 
 type GatewayResolutionType string
@@ -29,12 +111,65 @@ const (
 	ResolutionTypeLoadBalancer GatewayResolutionType = "loadBalancer"
 )
 
+// RecordsRemovedCondition reports whether the records of a GatewayDNS being
+// deleted are removed from every cluster in its namespace.
+const RecordsRemovedCondition = "RecordsRemoved"
+
+// ConsumersInstalledCondition reports whether the dns-server is installed on
+// every cluster in the namespace of a GatewayDNS that its records are
+// published to.
+const ConsumersInstalledCondition = "ConsumersInstalled"
+
 // GatewayDNSStatus defines the observed state of GatewayDNS
 type GatewayDNSStatus struct {
 	// Important: Run "make generate" to regenerate code after modifying this file
+
+	// conditions describe the state of the GatewayDNS.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// consumersNotInstalled are the namespace/name of the clusters that the
+	// records are not published to because the dns-server is not installed
+	// on them.
+	// +optional
+	ConsumersNotInstalled []string `json:"consumersNotInstalled,omitempty"`
+
+	// pendingRemovalClusters are the namespace/name of the clusters that have
+	// not confirmed the removal of the records of the GatewayDNS while it is
+	// being deleted.
+	// +optional
+	PendingRemovalClusters []string `json:"pendingRemovalClusters,omitempty"`
+
+	// skippedRemovalClusters are the namespace/name of the clusters the
+	// records of the GatewayDNS are not removed from while it is being
+	// deleted, because clusterLifecycle skips writes to them. The deletion
+	// does not wait for them.
+	// +optional
+	SkippedRemovalClusters []string `json:"skippedRemovalClusters,omitempty"`
+
+	// unreachableGateways are the gateways whose cluster could not be
+	// reached, with the time it was first found unreachable. The gateway was
+	// last confirmed at most a polling interval before that.
+	// +optional
+	UnreachableGateways []UnreachableGateway `json:"unreachableGateways,omitempty"`
+}
+
+// UnreachableGateway is a gateway whose cluster could not be reached.
+type UnreachableGateway struct {
+	// cluster is the namespace/name of the Cluster of the gateway.
+	Cluster string `json:"cluster"`
+
+	// since is the time the cluster was first found unreachable.
+	Since metav1.Time `json:"since"`
+
+	// withdrawn is true once the records of the gateway were withdrawn after
+	// staleRecordTTL.
+	// +optional
+	Withdrawn bool `json:"withdrawn,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 // GatewayDNS is the Schema for the gatewaydns API
 // +kubebuilder:printcolumn:name="Resolution Type",type=string,JSONPath=`.spec.resolutionType`
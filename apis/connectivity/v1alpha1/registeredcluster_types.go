@@ -0,0 +1,60 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RegisteredClusterSpec defines the desired state of RegisteredCluster
+type RegisteredClusterSpec struct {
+	// Important: Run "make generate" to regenerate code after modifying this file
+
+	// kubeconfigSecretRef is the Secret, in the namespace of the
+	// RegisteredCluster, holding the kubeconfig xcc-dns-controller connects
+	// to the cluster with.
+	KubeconfigSecretRef SecretKeyReference `json:"kubeconfigSecretRef"`
+}
+
+// SecretKeyReference is a key of a Secret in the same namespace.
+type SecretKeyReference struct {
+	// name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// key of the kubeconfig in the data of the Secret. Defaults to value,
+	// like the kubeconfig Secrets of Cluster API.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// DefaultKubeconfigSecretKey is the key of the kubeconfig in a Secret
+// referenced without one.
+const DefaultKubeconfigSecretKey = "value"
+
+// +kubebuilder:object:root=true
+
+// RegisteredCluster is a workload cluster that is not managed by Cluster API,
+// such as an EKS or GKE cluster. Its labels are matched by the
+// clusterSelector of GatewayDNS and XCCInstallation like those of a Cluster.
+// +kubebuilder:printcolumn:name="Kubeconfig Secret",type=string,JSONPath=`.spec.kubeconfigSecretRef.name`
+type RegisteredCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RegisteredClusterSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RegisteredClusterList contains a list of RegisteredCluster
+type RegisteredClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegisteredCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegisteredCluster{}, &RegisteredClusterList{})
+}
@@ -6,4 +6,34 @@ package v1alpha1
 const (
 	DNSHostnameAnnotation   = "connectivity.tanzu.vmware.com/dns-hostname"
 	GatewayDNSRefAnnotation = "connectivity.tanzu.vmware.com/gateway-dns-ref"
+
+	// GatewayDNSFinalizer keeps a deleted GatewayDNS until its records are
+	// removed from every cluster in its namespace, or its deletionTimeout
+	// expires.
+	GatewayDNSFinalizer = "connectivity.tanzu.vmware.com/gatewaydns-records"
+
+	// ClusterRefAnnotation on an EndpointSlice holds the namespace/name of the
+	// Cluster of its gateway.
+	ClusterRefAnnotation = "connectivity.tanzu.vmware.com/cluster-ref"
+
+	// EndpointSliceManagedBy is the value of the
+	// endpointslice.kubernetes.io/managed-by label on the EndpointSlices that
+	// xcc-dns-controller publishes to workload clusters.
+	EndpointSliceManagedBy = "xcc-dns-controller.connectivity.tanzu.vmware.com"
+
+	// GatewayWeightKey is the label of a Cluster that sets the relative weight
+	// of its gateways in DNS answers, and the annotation that carries it on
+	// the EndpointSlice of a gateway to the dns-server.
+	GatewayWeightKey = "connectivity.tanzu.vmware.com/gateway-weight"
+
+	// GatewayRegionAnnotation and GatewayZoneAnnotation carry the topology of
+	// a gateway on its EndpointSlice to the dns-server.
+	GatewayRegionAnnotation = "connectivity.tanzu.vmware.com/gateway-region"
+	GatewayZoneAnnotation   = "connectivity.tanzu.vmware.com/gateway-zone"
+
+	// ViewAddressesAnnotationPrefix followed by a view name, such as
+	// "connectivity.tanzu.vmware.com/addresses.private", holds a comma
+	// separated list of the addresses of a gateway in that view. It is set on
+	// the gateway Service and carried on its EndpointSlice to the dns-server.
+	ViewAddressesAnnotationPrefix = "connectivity.tanzu.vmware.com/addresses."
 )
@@ -0,0 +1,104 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// XCCInstallationSpec defines the desired state of XCCInstallation
+type XCCInstallationSpec struct {
+	// Important: Run "make generate" to regenerate code after modifying this file
+
+	// clusterSelector is a label selector that matches the clusters in the
+	// namespace of the XCCInstallation the dns-server and the
+	// dns-config-patcher are installed on.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// domainSuffix is the zone the cluster DNS of each cluster forwards to
+	// the dns-server. It must be the DOMAIN_SUFFIX of xcc-dns-controller.
+	// +kubebuilder:validation:MinLength=1
+	DomainSuffix string `json:"domainSuffix"`
+
+	// version is the release of the dns-server and the dns-config-patcher to
+	// install, the tag of their images. Changing it upgrades every matching
+	// cluster.
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// imageRepository is the repository the images are pulled from. Defaults
+	// to the repository of the manifests.
+	// +optional
+	ImageRepository string `json:"imageRepository,omitempty"`
+}
+
+// InstalledCondition reports whether the version of an XCCInstallation is
+// installed on every cluster it matches.
+const InstalledCondition = "Installed"
+
+// XCCInstallationStatus defines the observed state of XCCInstallation
+type XCCInstallationStatus struct {
+	// Important: Run "make generate" to regenerate code after modifying this file
+
+	// conditions describe the state of the XCCInstallation.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// clusters are the installations on the matching clusters.
+	// +optional
+	Clusters []ClusterInstallation `json:"clusters,omitempty"`
+}
+
+// ClusterInstallation is the installation of the dns-server and the
+// dns-config-patcher on a cluster.
+type ClusterInstallation struct {
+	// cluster is the namespace/name of the Cluster.
+	Cluster string `json:"cluster"`
+
+	// version is the version installed on the cluster.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// observedGeneration is the generation of the XCCInstallation that was
+	// installed on the cluster.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// lastInstallTime is when the manifests were last applied to the
+	// cluster.
+	// +optional
+	LastInstallTime *metav1.Time `json:"lastInstallTime,omitempty"`
+
+	// error is why the last installation on the cluster failed.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// XCCInstallation is the Schema for the xccinstallations API
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Domain Suffix",type=string,JSONPath=`.spec.domainSuffix`
+// +kubebuilder:printcolumn:name="Installed",type=string,JSONPath=`.status.conditions[?(@.type=="Installed")].status`
+type XCCInstallation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   XCCInstallationSpec   `json:"spec,omitempty"`
+	Status XCCInstallationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// XCCInstallationList contains a list of XCCInstallation
+type XCCInstallationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []XCCInstallation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&XCCInstallation{}, &XCCInstallationList{})
+}
@@ -8,16 +8,67 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterInstallation) DeepCopyInto(out *ClusterInstallation) {
+	*out = *in
+	if in.LastInstallTime != nil {
+		in, out := &in.LastInstallTime, &out.LastInstallTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterInstallation.
+func (in *ClusterInstallation) DeepCopy() *ClusterInstallation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterInstallation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterLifecyclePolicy) DeepCopyInto(out *ClusterLifecyclePolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterLifecyclePolicy.
+func (in *ClusterLifecyclePolicy) DeepCopy() *ClusterLifecyclePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterLifecyclePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterWeight) DeepCopyInto(out *ClusterWeight) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterWeight.
+func (in *ClusterWeight) DeepCopy() *ClusterWeight {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterWeight)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GatewayDNS) DeepCopyInto(out *GatewayDNS) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayDNS.
@@ -74,6 +125,24 @@ func (in *GatewayDNSList) DeepCopyObject() runtime.Object {
 func (in *GatewayDNSSpec) DeepCopyInto(out *GatewayDNSSpec) {
 	*out = *in
 	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	if in.ClusterWeights != nil {
+		in, out := &in.ClusterWeights, &out.ClusterWeights
+		*out = make([]ClusterWeight, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeletionTimeout != nil {
+		in, out := &in.DeletionTimeout, &out.DeletionTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	out.ClusterLifecycle = in.ClusterLifecycle
+	if in.StaleRecordTTL != nil {
+		in, out := &in.StaleRecordTTL, &out.StaleRecordTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayDNSSpec.
@@ -89,6 +158,35 @@ func (in *GatewayDNSSpec) DeepCopy() *GatewayDNSSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GatewayDNSStatus) DeepCopyInto(out *GatewayDNSStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConsumersNotInstalled != nil {
+		in, out := &in.ConsumersNotInstalled, &out.ConsumersNotInstalled
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingRemovalClusters != nil {
+		in, out := &in.PendingRemovalClusters, &out.PendingRemovalClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkippedRemovalClusters != nil {
+		in, out := &in.SkippedRemovalClusters, &out.SkippedRemovalClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnreachableGateways != nil {
+		in, out := &in.UnreachableGateways, &out.UnreachableGateways
+		*out = make([]UnreachableGateway, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayDNSStatus.
@@ -100,3 +198,212 @@ func (in *GatewayDNSStatus) DeepCopy() *GatewayDNSStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegisteredCluster) DeepCopyInto(out *RegisteredCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredCluster.
+func (in *RegisteredCluster) DeepCopy() *RegisteredCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(RegisteredCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegisteredCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegisteredClusterList) DeepCopyInto(out *RegisteredClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RegisteredCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterList.
+func (in *RegisteredClusterList) DeepCopy() *RegisteredClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(RegisteredClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegisteredClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegisteredClusterSpec) DeepCopyInto(out *RegisteredClusterSpec) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterSpec.
+func (in *RegisteredClusterSpec) DeepCopy() *RegisteredClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegisteredClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnreachableGateway) DeepCopyInto(out *UnreachableGateway) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnreachableGateway.
+func (in *UnreachableGateway) DeepCopy() *UnreachableGateway {
+	if in == nil {
+		return nil
+	}
+	out := new(UnreachableGateway)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XCCInstallation) DeepCopyInto(out *XCCInstallation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XCCInstallation.
+func (in *XCCInstallation) DeepCopy() *XCCInstallation {
+	if in == nil {
+		return nil
+	}
+	out := new(XCCInstallation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *XCCInstallation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XCCInstallationList) DeepCopyInto(out *XCCInstallationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]XCCInstallation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XCCInstallationList.
+func (in *XCCInstallationList) DeepCopy() *XCCInstallationList {
+	if in == nil {
+		return nil
+	}
+	out := new(XCCInstallationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *XCCInstallationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XCCInstallationSpec) DeepCopyInto(out *XCCInstallationSpec) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XCCInstallationSpec.
+func (in *XCCInstallationSpec) DeepCopy() *XCCInstallationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(XCCInstallationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XCCInstallationStatus) DeepCopyInto(out *XCCInstallationStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ClusterInstallation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XCCInstallationStatus.
+func (in *XCCInstallationStatus) DeepCopy() *XCCInstallationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(XCCInstallationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
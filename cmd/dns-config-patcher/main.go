@@ -5,14 +5,19 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
@@ -37,17 +42,59 @@ func getEnvVarOrDie(name string, description string) string {
 }
 
 func main() {
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
-
-	dnsServiceNamespace := getEnvVarOrDie(
-		"DNS_SERVICE_NAMESPACE",
-		"Must be set to the namespace of the DNS service that will handle DNS lookups for the provided DOMAIN_SUFFIX.",
-	)
+	var mode string
+	var configFile string
+	var strategy string
+	var kubeDNSConfigMapName string
+	var nodeLocalDNSConfigMapName string
+	var continuous bool
+	var metricsAddr string
+	var probeAddr string
+	var dnsServiceWaitTimeout time.Duration
+	var verify bool
+	var verifyReloadWait time.Duration
+	var verifyTimeout time.Duration
+	var clusterDNSService string
+	var clusterDNSServer string
+	flag.StringVar(&mode, "mode", "patch",
+		"patch adds the stub domain block for DOMAIN_SUFFIX to the Corefile, "+
+			"remove strips it again, e.g. from a pre-delete hook.")
+	flag.StringVar(&configFile, "config", "",
+		"A YAML file with additional domain suffixes, upstreams and forward options of the stub domain, "+
+			"e.g. mounted from a ConfigMap.")
+	flag.StringVar(&strategy, "strategy", string(dnsconfig.StrategyAuto),
+		"The cluster DNS to configure: coredns, kube-dns or nodelocaldns. "+
+			"auto detects it from the ConfigMaps in COREFILE_CONFIGMAP_NAMESPACE.")
+	flag.StringVar(&kubeDNSConfigMapName, "kube-dns-configmap", "kube-dns",
+		"The name of the kube-dns ConfigMap holding its stubDomains.")
+	flag.StringVar(&nodeLocalDNSConfigMapName, "node-local-dns-configmap", "node-local-dns",
+		"The name of the ConfigMap holding the Corefile of NodeLocal DNSCache.")
+	flag.BoolVar(&continuous, "continuous", false,
+		"Keep running and re-apply the stub domain block whenever the Corefile ConfigMap "+
+			"or the DNS service changes, instead of patching once and exiting.")
+	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to in continuous mode.")
+	flag.StringVar(&probeAddr, "health-probe-addr", ":8081", "The address the health probe endpoint binds to in continuous mode.")
+	flag.DurationVar(&dnsServiceWaitTimeout, "dns-service-wait-timeout", 5*time.Second,
+		"How long to wait for the DNS service to get a ClusterIP or, when it is headless, ready endpoints "+
+			"before failing when patching once.")
+	flag.BoolVar(&verify, "verify", false,
+		"After patching once, query the canary TXT record the dns-server serves in each stub zone "+
+			"through the cluster DNS, and fail when it is not answered. The dns-server must serve the canary.")
+	flag.DurationVar(&verifyReloadWait, "verify-reload-wait", 45*time.Second,
+		"How long to wait for the cluster DNS to reload the patched configuration before verifying. "+
+			"The CoreDNS reload plugin checks every 30s with up to 15s of jitter.")
+	flag.DurationVar(&verifyTimeout, "verify-timeout", 2*time.Minute,
+		"How long to keep querying the canary after the reload wait before failing, which covers "+
+			"the kubelet syncing the ConfigMap into the cluster DNS pods.")
+	flag.StringVar(&clusterDNSService, "cluster-dns-service", "kube-system/kube-dns",
+		"The namespace/name of the Service of the cluster DNS queried when verifying, "+
+			"unless NodeLocal DNSCache was patched, which is queried on its bind address.")
+	flag.StringVar(&clusterDNSServer, "cluster-dns-server", "",
+		"The host:port queried when verifying, instead of the ClusterIP of --cluster-dns-service "+
+			"or the bind address of NodeLocal DNSCache.")
+	flag.Parse()
 
-	dnsServiceName := getEnvVarOrDie(
-		"DNS_SERVICE_NAME",
-		"Must be set to the name of the DNS service that will handle DNS lookups for the provided DOMAIN_SUFFIX.",
-	)
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
 	corefileConfigMapNamespace := getEnvVarOrDie(
 		"COREFILE_CONFIGMAP_NAMESPACE",
@@ -59,11 +106,76 @@ func main() {
 		"Must be set to the name of the ConfigMap containing the Corefile to be patched.",
 	)
 
-	domainSuffix := getEnvVarOrDie(
-		"DOMAIN_SUFFIX",
-		"Must be set to the domain suffix of the zone that is handled by another DNS service.",
+	patcherOptions := dnsconfig.PatcherOptions{
+		Log:                       log,
+		Namespace:                 corefileConfigMapNamespace,
+		CorefileConfigMapName:     corefileConfigMapName,
+		KubeDNSConfigMapName:      kubeDNSConfigMapName,
+		NodeLocalDNSConfigMapName: nodeLocalDNSConfigMapName,
+	}
+
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			log.Error(err, "unable to read config file")
+			os.Exit(1)
+		}
+		patcherOptions.StubDomain, err = dnsconfig.LoadStubDomainConfig(data)
+		if err != nil {
+			log.Error(err, "unable to load config file")
+			os.Exit(1)
+		}
+	}
+
+	switch mode {
+	case "patch":
+	case "remove":
+		if continuous {
+			log.Error(fmt.Errorf("--continuous is only supported in patch mode"), "invalid flags")
+			os.Exit(1)
+		}
+		// Only the kube-dns stub domains are keyed by the domain suffix. It
+		// is optional, the stub domains added by the patcher are recorded on
+		// the ConfigMap, but removes the ones added by older versions.
+		patcherOptions.DomainSuffix = os.Getenv("DOMAIN_SUFFIX")
+		remove(dnsconfig.Strategy(strategy), patcherOptions)
+		return
+	default:
+		log.Error(fmt.Errorf("unknown mode %q, must be patch or remove", mode), "invalid flags")
+		os.Exit(1)
+	}
+
+	dnsServiceNamespace := getEnvVarOrDie(
+		"DNS_SERVICE_NAMESPACE",
+		"Must be set to the namespace of the DNS service that will handle DNS lookups for the provided DOMAIN_SUFFIX.",
 	)
 
+	dnsServiceName := getEnvVarOrDie(
+		"DNS_SERVICE_NAME",
+		"Must be set to the name of the DNS service that will handle DNS lookups for the provided DOMAIN_SUFFIX.",
+	)
+
+	if len(patcherOptions.StubDomain.DomainSuffixes) > 0 {
+		patcherOptions.DomainSuffix = os.Getenv("DOMAIN_SUFFIX")
+	} else {
+		patcherOptions.DomainSuffix = getEnvVarOrDie(
+			"DOMAIN_SUFFIX",
+			"Must be set to the domain suffix of the zone that is handled by another DNS service, unless the config file sets domainSuffixes.",
+		)
+	}
+
+	if continuous {
+		runContinuously(continuousOptions{
+			metricsAddr:         metricsAddr,
+			probeAddr:           probeAddr,
+			dnsServiceNamespace: dnsServiceNamespace,
+			dnsServiceName:      dnsServiceName,
+			strategy:            dnsconfig.Strategy(strategy),
+			patcherOptions:      patcherOptions,
+		})
+		return
+	}
+
 	client, err := client.New(ctrl.GetConfigOrDie(), client.Options{
 		Scheme: scheme,
 	})
@@ -80,26 +192,207 @@ func main() {
 		PollingInterval: 500 * time.Millisecond,
 	}
 
-	dnsServiceWatcherCtx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	dnsServiceClusterIP, err := dnsServiceWatcher.GetDNSServiceClusterIP(dnsServiceWatcherCtx)
+	dnsServiceWatcherCtx, cancel := context.WithTimeout(context.Background(), dnsServiceWaitTimeout)
+	defer cancel()
+	dnsServiceAddresses, err := dnsServiceWatcher.GetDNSServiceAddresses(dnsServiceWatcherCtx)
 	if err != nil {
-		log.Error(err, "unable to get DNS service ClusterIP")
+		log.Error(err, "unable to get DNS service addresses")
 		os.Exit(1)
 	}
 
-	patcher := dnsconfig.CorefilePatcher{
-		Client:       client,
-		Log:          log.WithName("CorefilePatcher"),
-		DomainSuffix: domainSuffix,
+	patcherOptions.Client = client
+	patcher, detectedStrategy := newPatcherOrDie(client, dnsconfig.Strategy(strategy), patcherOptions, false)
 
-		Namespace:     corefileConfigMapNamespace,
-		ConfigMapName: corefileConfigMapName,
-	}
-
-	if err = patcher.AppendStubDomainBlock(dnsServiceClusterIP); err != nil {
+	if err = patcher.AppendStubDomainBlock(dnsServiceAddresses...); err != nil {
 		log.Error(err, "unable to append stub domain block")
 		os.Exit(1)
 	}
 
 	log.Info("successfully patched Corefile")
+
+	if verify {
+		verifyOrDie(client, detectedStrategy, patcherOptions, verifyOptions{
+			reloadWait:  verifyReloadWait,
+			timeout:     verifyTimeout,
+			waitTimeout: dnsServiceWaitTimeout,
+			service:     clusterDNSService,
+			server:      clusterDNSServer,
+		})
+	}
+}
+
+type verifyOptions struct {
+	reloadWait time.Duration
+	timeout    time.Duration
+
+	// waitTimeout bounds waiting for the ClusterIP of the cluster DNS Service.
+	waitTimeout time.Duration
+
+	// service is the namespace/name of the cluster DNS Service, used when
+	// server is empty.
+	service string
+	server  string
+}
+
+// verifyOrDie waits for the cluster DNS to answer for the stub domains. With
+// the NodeLocal DNSCache strategy it queries NodeLocal DNSCache, whose
+// Corefile was patched, rather than the cluster DNS behind it.
+func verifyOrDie(c client.Client, strategy dnsconfig.Strategy, patcherOptions dnsconfig.PatcherOptions, opts verifyOptions) {
+	server := opts.server
+	if server == "" && strategy == dnsconfig.StrategyNodeLocalDNS {
+		var err error
+		server, err = dnsconfig.NodeLocalDNSServer(context.Background(), c, patcherOptions)
+		if err != nil {
+			log.Error(err, "unable to get NodeLocal DNSCache address, pass --cluster-dns-server")
+			os.Exit(1)
+		}
+	}
+	if server == "" {
+		namespace, name, ok := strings.Cut(opts.service, "/")
+		if !ok {
+			log.Error(fmt.Errorf("--cluster-dns-service %q must be namespace/name", opts.service), "invalid flags")
+			os.Exit(1)
+		}
+		dnsServiceWatcher := dnsconfig.DNSServiceWatcher{
+			Client:      c,
+			Namespace:   namespace,
+			ServiceName: name,
+
+			PollingInterval: 500 * time.Millisecond,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), opts.waitTimeout)
+		defer cancel()
+		clusterIP, err := dnsServiceWatcher.GetDNSServiceClusterIP(ctx)
+		if err != nil {
+			log.Error(err, "unable to get cluster DNS service ClusterIP")
+			os.Exit(1)
+		}
+		server = net.JoinHostPort(clusterIP, "53")
+	}
+
+	verifier := dnsconfig.Verifier{
+		Log:             log.WithName("Verifier"),
+		Server:          server,
+		ReloadWait:      opts.reloadWait,
+		PollingInterval: 2 * time.Second,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.reloadWait+opts.timeout)
+	defer cancel()
+	if err := verifier.Verify(ctx, patcherOptions.DomainSuffixes()); err != nil {
+		log.Error(err, "stub domain is not resolvable through the cluster DNS")
+		os.Exit(1)
+	}
+
+	log.Info("successfully verified stub domain")
+}
+
+// newPatcherOrDie returns the patcher for the strategy, detecting it with the
+// reader when it is auto, and the strategy it was built for. Adding a kube-dns
+// stub domain requires a domain suffix, removing it does not as the patcher
+// records the stub domains it added.
+func newPatcherOrDie(reader client.Reader, strategy dnsconfig.Strategy, opts dnsconfig.PatcherOptions, removing bool) (dnsconfig.Patcher, dnsconfig.Strategy) {
+	if strategy == dnsconfig.StrategyAuto {
+		var err error
+		strategy, err = dnsconfig.DetectStrategy(context.Background(), reader, opts)
+		if err != nil {
+			log.Error(err, "unable to detect the cluster DNS")
+			os.Exit(1)
+		}
+		log.Info("detected cluster DNS", "strategy", strategy)
+	}
+	if strategy == dnsconfig.StrategyKubeDNS && !removing && opts.DomainSuffix == "" && len(opts.StubDomain.DomainSuffixes) == 0 {
+		log.Error(fmt.Errorf("DOMAIN_SUFFIX environment variable unset. Must be set to the domain suffix of the kube-dns stub domain."), "unable to get DOMAIN_SUFFIX environment variable")
+		os.Exit(1)
+	}
+
+	patcher, err := dnsconfig.NewPatcher(strategy, opts)
+	if err != nil {
+		log.Error(err, "invalid flags")
+		os.Exit(1)
+	}
+	return patcher, strategy
+}
+
+type continuousOptions struct {
+	metricsAddr string
+	probeAddr   string
+
+	dnsServiceNamespace string
+	dnsServiceName      string
+
+	strategy       dnsconfig.Strategy
+	patcherOptions dnsconfig.PatcherOptions
+}
+
+// runContinuously keeps the Corefile patched until the process is signalled
+// to stop.
+func runContinuously(opts continuousOptions) {
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		MetricsBindAddress:     opts.metricsAddr,
+		HealthProbeBindAddress: opts.probeAddr,
+		NewCache: cache.MultiNamespacedCacheBuilder([]string{
+			opts.patcherOptions.Namespace,
+			opts.dnsServiceNamespace,
+		}),
+	})
+	if err != nil {
+		log.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	// The cache of the manager only starts with it, so the cluster DNS is
+	// detected by reading from the API server directly.
+	opts.patcherOptions.Client = mgr.GetClient()
+	patcher, _ := newPatcherOrDie(mgr.GetAPIReader(), opts.strategy, opts.patcherOptions, false)
+
+	corefileReconciler := &dnsconfig.CorefileReconciler{
+		Client:              mgr.GetClient(),
+		Log:                 log.WithName("CorefileReconciler"),
+		Patcher:             patcher,
+		DNSServiceNamespace: opts.dnsServiceNamespace,
+		DNSServiceName:      opts.dnsServiceName,
+	}
+	if err = corefileReconciler.SetupWithManager(mgr); err != nil {
+		log.Error(err, "unable to create controller", "controller", "Corefile")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		log.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	// Not ready until the Corefile first forwards the stub domain.
+	if err := mgr.AddReadyzCheck("readyz", corefileReconciler.ReadyzCheck); err != nil {
+		log.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	log.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		log.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// remove strips the stub domain block from the Corefile. It succeeds when
+// there is nothing to remove so that it can run as a pre-delete hook.
+func remove(strategy dnsconfig.Strategy, opts dnsconfig.PatcherOptions) {
+	client, err := client.New(ctrl.GetConfigOrDie(), client.Options{
+		Scheme: scheme,
+	})
+	if err != nil {
+		log.Error(err, "unable to get client")
+		os.Exit(1)
+	}
+
+	opts.Client = client
+	patcher, _ := newPatcherOrDie(client, strategy, opts, true)
+
+	if err = patcher.RemoveStubDomainBlock(); err != nil {
+		log.Error(err, "unable to remove stub domain block")
+		os.Exit(1)
+	}
+
+	log.Info("successfully removed stub domain block from Corefile")
 }
@@ -49,6 +49,10 @@ import (
 	"github.com/coredns/coredns/coremain"
 )
 
+// directives orders the plugins, whatever their order in the Corefile. cache
+// and loadbalance come after crosscluster so that they only handle the
+// queries it passes on: its answers carry their own TTLs and order, which
+// views, topology and weights depend on.
 var directives = []string{
 	"metadata",
 	"cancel",
@@ -71,8 +75,6 @@ var directives = []string{
 	"acl",
 	"any",
 	"chaos",
-	"loadbalance",
-	"cache",
 	"rewrite",
 	"dnssec",
 	"autopath",
@@ -81,6 +83,8 @@ var directives = []string{
 	"hosts",
 	"auto",
 	"crosscluster",
+	"loadbalance",
+	"cache",
 	"secondary",
 	"loop",
 	"forward",
@@ -17,7 +17,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/xccinstallation"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
 	discoveryv1 "k8s.io/api/discovery/v1"
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	// +kubebuilder:scaffold:imports
@@ -39,10 +42,39 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var dryRun bool
+	var orphanCollectionInterval time.Duration
+	var orphanGracePeriod time.Duration
+	var bootstrapConsumers bool
+	var dnsServerImage string
+	var dnsConfigPatcherImage string
+	var registeredClusters bool
+	var xccInstallations bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Log the EndpointSlices each GatewayDNS would create, update or delete on the workload clusters, "+
+			"and record their number in the xcc_dns_controller_planned_endpointslice_changes metric, without writing them. "+
+			"The XCCInstallations only log the clusters they would install on.")
+	flag.DurationVar(&orphanCollectionInterval, "orphan-collection-interval", 10*time.Minute,
+		"How often to delete the EndpointSlices whose GatewayDNS or gateway Cluster no longer exists from all workload clusters. 0 disables it.")
+	flag.DurationVar(&orphanGracePeriod, "orphan-grace-period", 10*time.Minute,
+		"How long an EndpointSlice has to be orphaned before it is deleted.")
+	flag.BoolVar(&bootstrapConsumers, "bootstrap-consumers", false,
+		"Install the dns-server and the dns-config-patcher on the workload clusters without the NAMESPACE namespace. "+
+			"Otherwise they are reported as not installed in the status of the GatewayDNS.")
+	flag.StringVar(&dnsServerImage, "dns-server-image", "",
+		"The image of the dns-server installed by --bootstrap-consumers. Defaults to the image of manifests/dns-server.")
+	flag.StringVar(&dnsConfigPatcherImage, "dns-config-patcher-image", "",
+		"The image of the dns-config-patcher installed by --bootstrap-consumers. Defaults to the image of manifests/dns-config-patcher.")
+	flag.BoolVar(&registeredClusters, "registered-clusters", false,
+		"Also handle the clusters registered with a RegisteredCluster and a kubeconfig Secret, next to the Cluster API Clusters. "+
+			"The RegisteredCluster CRD must be installed.")
+	flag.BoolVar(&xccInstallations, "xcc-installations", false,
+		"Install the dns-server and the dns-config-patcher on the clusters matched by each XCCInstallation. "+
+			"The XCCInstallation CRD must be installed.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -92,21 +124,49 @@ func main() {
 	}
 
 	client := mgr.GetClient()
+	var clientProvider inventory.ClusterClientProvider = clusterCacheTracker
+	clusterInventory := inventory.Inventory{inventory.ClusterAPI{Client: client}}
+	if registeredClusters {
+		clientProvider = &inventory.ClientProvider{
+			Client:     client,
+			Scheme:     mgr.GetScheme(),
+			SourceName: "xcc-dns-controller",
+			Fallback:   clusterCacheTracker,
+		}
+		clusterInventory = inventory.Inventory{inventory.Registered{Client: client}, inventory.ClusterAPI{Client: client}}
+	}
+
+	var bootstrap *consumer.Installer
+	if bootstrapConsumers {
+		bootstrap = &consumer.Installer{
+			Options: consumer.Options{
+				Namespace:             namespace,
+				DomainSuffix:          domainSuffix,
+				DNSServerImage:        dnsServerImage,
+				DNSConfigPatcherImage: dnsConfigPatcherImage,
+			},
+			FieldManager: gatewaydns.FieldManager,
+		}
+	}
+
 	if err = (&gatewaydns.GatewayDNSReconciler{
 		Client:          client,
 		Log:             reconcilerLog,
 		PollingInterval: gatewayDNSPollingIntervalDuration,
 		Scheme:          mgr.GetScheme(),
-		ClientProvider:  clusterCacheTracker,
-		ClusterSearcher: &gatewaydns.ClusterSearcher{Client: client},
+		ClientProvider:  clientProvider,
+		ClusterSearcher: &gatewaydns.ClusterSearcher{Client: client, Inventory: clusterInventory},
 		EndpointSliceReconciler: &gatewaydns.EndpointSliceReconciler{
-			ClientProvider: clusterCacheTracker,
+			ClientProvider: clientProvider,
 			Namespace:      namespace,
 			Log:            reconcilerLog.WithName("EndpointSliceReconciler"),
+			DryRun:         dryRun,
+			Bootstrap:      bootstrap,
 		},
+		WatchRegisteredClusters: registeredClusters,
 		ClusterGatewayCollector: &gatewaydns.ClusterGatewayCollector{
 			Log:            reconcilerLog.WithName("EndpointSliceCollector"),
-			ClientProvider: clusterCacheTracker,
+			ClientProvider: clientProvider,
 			Namespace:      namespace,
 			DomainSuffix:   domainSuffix,
 		},
@@ -114,6 +174,37 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "GatewayDNS")
 		os.Exit(1)
 	}
+
+	if xccInstallations {
+		if err = (&xccinstallation.XCCInstallationReconciler{
+			Client:                  client,
+			Log:                     ctrl.Log.WithName("controllers").WithName("XCCInstallation"),
+			ClientProvider:          clientProvider,
+			Inventory:               clusterInventory,
+			WatchRegisteredClusters: registeredClusters,
+			Namespace:               namespace,
+			DryRun:                  dryRun,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "XCCInstallation")
+			os.Exit(1)
+		}
+	}
+
+	if orphanCollectionInterval > 0 {
+		if err = mgr.Add(&gatewaydns.OrphanCollector{
+			Client:         client,
+			Log:            reconcilerLog.WithName("OrphanCollector"),
+			ClientProvider: clientProvider,
+			Namespace:      namespace,
+			Inventory:      clusterInventory,
+			Interval:       orphanCollectionInterval,
+			GracePeriod:    orphanGracePeriod,
+			DryRun:         dryRun,
+		}); err != nil {
+			setupLog.Error(err, "unable to add orphan collector", "controller", "GatewayDNS")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -0,0 +1,295 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
+)
+
+func status(ctx context.Context, out io.Writer, opts options, args []string) error {
+	flags := newFlagSet("status", os.Stderr)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("status takes the GatewayDNS as its only argument")
+	}
+	gatewayDNSName, err := namespacedName(positional[0], opts.namespace)
+	if err != nil {
+		return err
+	}
+
+	inspector, err := newInspector(opts)
+	if err != nil {
+		return err
+	}
+	report, err := inspector.GatewayDNSStatus(ctx, gatewayDNSName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "GatewayDNS:       %s\n", gatewayDNSName)
+	fmt.Fprintf(out, "Service:          %s\n", report.GatewayDNS.Spec.Service)
+	fmt.Fprintf(out, "Cluster selector: %s\n", metav1.FormatLabelSelector(&report.GatewayDNS.Spec.ClusterSelector))
+	var matched []string
+	for _, cluster := range report.MatchedClusters {
+		matched = append(matched, cluster.Name)
+	}
+	fmt.Fprintf(out, "Matched clusters: %s\n", orNone(strings.Join(matched, ", ")))
+	if report.GatewayDNS.DeletionTimestamp != nil {
+		fmt.Fprintf(out, "Deleting:         since %s, waiting for %s\n",
+			report.GatewayDNS.DeletionTimestamp.Format(time.RFC3339),
+			orNone(strings.Join(report.GatewayDNS.Status.PendingRemovalClusters, ", ")))
+		if skipped := report.GatewayDNS.Status.SkippedRemovalClusters; len(skipped) > 0 {
+			fmt.Fprintf(out, "Skipped removal:  %s\n", strings.Join(skipped, ", "))
+		}
+	}
+
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GATEWAY CLUSTER\tHOSTNAME\tADDRESSES\tWEIGHT")
+	for _, clusterGateway := range report.ClusterGateways {
+		if clusterGateway.Unreachable {
+			fmt.Fprintf(w, "%s\t-\tunreachable\t-\n", clusterGateway.ClusterNamespacedName.Name)
+			continue
+		}
+		endpointSlice := clusterGateway.ToEndpointSlice()
+		weight := endpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			clusterGateway.ClusterNamespacedName.Name,
+			endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation],
+			orNone(strings.Join(endpointSlice.Endpoints[0].Addresses, ",")),
+			orDefault(weight),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONSUMER CLUSTER\tSYNC\tMISSING\tCHANGED\tUNDESIRED")
+	for _, state := range report.Clusters {
+		switch {
+		case state.Err != nil:
+			fmt.Fprintf(w, "%s\terror: %s\t-\t-\t-\n", state.Cluster.Name, state.Err)
+		case state.SkipReason != "":
+			fmt.Fprintf(w, "%s\tskipped: %s\t-\t-\t-\n", state.Cluster.Name, state.SkipReason)
+		case state.NamespaceMissing:
+			fmt.Fprintf(w, "%s\tnot installed, namespace %s not found\t-\t-\t-\n", state.Cluster.Name, opts.controllerNamespace)
+		default:
+			sync := "in sync"
+			if !state.InSync() {
+				sync = "out of sync"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", state.Cluster.Name, sync,
+				endpointSliceNames(state.Diff.Missing),
+				endpointSliceNames(state.Diff.Changed),
+				endpointSliceNames(state.Diff.Undesired),
+			)
+		}
+	}
+	return w.Flush()
+}
+
+func plan(ctx context.Context, out io.Writer, opts options, args []string) error {
+	flags := newFlagSet("plan", os.Stderr)
+	file := flags.String("f", "", "A GatewayDNS manifest to plan instead of the GatewayDNS in the cluster, e.g. with a new clusterSelector.")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	inspector, err := newInspector(opts)
+	if err != nil {
+		return err
+	}
+
+	var report gatewaydns.GatewayDNSReport
+	switch {
+	case *file != "" && len(positional) == 0:
+		gatewayDNS, err := readGatewayDNS(*file, opts.namespace)
+		if err != nil {
+			return err
+		}
+		// The status of the GatewayDNS in the cluster tells since when its
+		// gateways are unreachable.
+		var existing connectivityv1alpha1.GatewayDNS
+		err = inspector.Client.Get(ctx, types.NamespacedName{Namespace: gatewayDNS.Namespace, Name: gatewayDNS.Name}, &existing)
+		if err == nil {
+			gatewayDNS.Status = existing.Status
+		} else if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		report, err = inspector.Plan(ctx, gatewayDNS)
+		if err != nil {
+			return err
+		}
+	case *file == "" && len(positional) == 1:
+		gatewayDNSName, err := namespacedName(positional[0], opts.namespace)
+		if err != nil {
+			return err
+		}
+		report, err = inspector.GatewayDNSStatus(ctx, gatewayDNSName)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("plan takes either the GatewayDNS as its only argument or -f")
+	}
+
+	fmt.Fprintf(out, "GatewayDNS %s/%s matches %d clusters.\n", report.GatewayDNS.Namespace, report.GatewayDNS.Name, len(report.MatchedClusters))
+	changed := 0
+	for _, state := range report.Clusters {
+		switch {
+		case state.Err != nil:
+			fmt.Fprintf(out, "\n%s: unknown, %s\n", state.Cluster, state.Err)
+		case state.SkipReason != "":
+			fmt.Fprintf(out, "\n%s: skipped, %s\n", state.Cluster, state.SkipReason)
+		case state.NamespaceMissing:
+			fmt.Fprintf(out, "\n%s: skipped, namespace %s not found\n", state.Cluster, opts.controllerNamespace)
+		case !state.Diff.Empty():
+			changed++
+			fmt.Fprintf(out, "\n%s:\n", state.Cluster)
+			for _, change := range state.Diff.Changes() {
+				fmt.Fprintf(out, "  %s\n", change)
+			}
+		}
+	}
+	fmt.Fprintf(out, "\n%d of %d clusters would change.\n", changed, len(report.Clusters))
+	return nil
+}
+
+// readGatewayDNS reads a GatewayDNS manifest, defaulting its namespace.
+func readGatewayDNS(file string, defaultNamespace string) (connectivityv1alpha1.GatewayDNS, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return connectivityv1alpha1.GatewayDNS{}, err
+	}
+	var gatewayDNS connectivityv1alpha1.GatewayDNS
+	if err := yaml.UnmarshalStrict(data, &gatewayDNS); err != nil {
+		return connectivityv1alpha1.GatewayDNS{}, fmt.Errorf("invalid GatewayDNS in %s: %w", file, err)
+	}
+	if gatewayDNS.Kind != "GatewayDNS" || gatewayDNS.Name == "" {
+		return connectivityv1alpha1.GatewayDNS{}, fmt.Errorf("%s must hold a single named GatewayDNS", file)
+	}
+	if gatewayDNS.Namespace == "" {
+		gatewayDNS.Namespace = defaultNamespace
+	}
+	return gatewayDNS, nil
+}
+
+func records(ctx context.Context, out io.Writer, opts options, args []string) error {
+	flags := newFlagSet("records", os.Stderr)
+	cluster := flags.String("cluster", "", "The Cluster whose dns-server records to list.")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("records takes no arguments")
+	}
+	clusterName, err := namespacedName(*cluster, opts.namespace)
+	if err != nil {
+		return fmt.Errorf("--cluster: %w", err)
+	}
+
+	inspector, err := newInspector(opts)
+	if err != nil {
+		return err
+	}
+	records, err := inspector.Records(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	return writeRecords(out, records)
+}
+
+func resolve(ctx context.Context, out io.Writer, opts options, args []string) error {
+	flags := newFlagSet("resolve", os.Stderr)
+	from := flags.String("from", "", "The Cluster whose dns-server answers the query.")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("resolve takes the fqdn as its only argument")
+	}
+	clusterName, err := namespacedName(*from, opts.namespace)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+
+	inspector, err := newInspector(opts)
+	if err != nil {
+		return err
+	}
+	records, err := inspector.Resolve(ctx, clusterName, positional[0])
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s does not resolve from Cluster %s", positional[0], clusterName)
+	}
+	return writeRecords(out, records)
+}
+
+func writeRecords(out io.Writer, records []gatewaydns.Record) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESSES\tWEIGHT\tREGION\tZONE\tGATEWAYDNS\tENDPOINTSLICE")
+	for _, record := range records {
+		weight := ""
+		if record.Weight != 0 {
+			weight = fmt.Sprint(record.Weight)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.FQDN,
+			orNone(strings.Join(record.Addresses, ",")),
+			orDefault(weight),
+			orNone(record.Region),
+			orNone(record.Zone),
+			orNone(record.GatewayDNS),
+			record.ResourceKey,
+		)
+	}
+	return w.Flush()
+}
+
+func endpointSliceNames(endpointSlices []discoveryv1.EndpointSlice) string {
+	var names []string
+	for _, endpointSlice := range endpointSlices {
+		names = append(names, endpointSlice.Name)
+	}
+	return orNone(strings.Join(names, ","))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func orDefault(s string) string {
+	if s == "" {
+		return "<default>"
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// xccctl inspects the cross-cluster DNS state of the management cluster and
+// its workload clusters, reading them the same way xcc-dns-controller does.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = connectivityv1alpha1.AddToScheme(scheme)
+	_ = clusterv1beta1.AddToScheme(scheme)
+	_ = discoveryv1.AddToScheme(scheme)
+}
+
+const usage = `xccctl inspects cross-cluster DNS from the management cluster.
+
+Usage:
+  xccctl [flags] status <gatewaydns>             matched clusters, gateways and EndpointSlice sync state per cluster
+  xccctl [flags] plan <gatewaydns>|-f <file>     EndpointSlices the controller would create, update or delete per cluster
+  xccctl [flags] records --cluster <cluster>     records the dns-server of the cluster serves
+  xccctl [flags] resolve <fqdn> --from <cluster> records the dns-server of the cluster answers the fqdn with
+
+GatewayDNS and Cluster names are namespace/name, or a name in --namespace.
+
+Flags:
+`
+
+type options struct {
+	namespace           string
+	controllerNamespace string
+	domainSuffix        string
+	timeout             time.Duration
+}
+
+func main() {
+	var opts options
+	var verbose bool
+	flag.StringVar(&opts.namespace, "namespace", "default", "The namespace of GatewayDNS and Cluster names given without one.")
+	flag.StringVar(&opts.controllerNamespace, "controller-namespace", "xcc-dns",
+		"The NAMESPACE of xcc-dns-controller, which the dns-server runs in on the workload clusters.")
+	flag.StringVar(&opts.domainSuffix, "domain-suffix", "xcc.test", "The DOMAIN_SUFFIX of xcc-dns-controller.")
+	flag.DurationVar(&opts.timeout, "timeout", time.Minute, "How long to wait for the clusters to answer.")
+	flag.BoolVar(&verbose, "v", false, "Log what the controller would log while reading the clusters.")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	log := logr.Discard()
+	if verbose {
+		log = zap.New(zap.UseDevMode(true), zap.WriteTo(os.Stderr))
+	}
+	ctrl.SetLogger(log)
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	var err error
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "status":
+		err = status(ctx, os.Stdout, opts, args)
+	case "plan":
+		err = plan(ctx, os.Stdout, opts, args)
+	case "records":
+		err = records(ctx, os.Stdout, opts, args)
+	case "resolve":
+		err = resolve(ctx, os.Stdout, opts, args)
+	default:
+		err = fmt.Errorf("unknown command %q, must be status, plan, records or resolve", command)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// newInspector wires an Inspector up like xcc-dns-controller wires its
+// reconciler, with clients of the workload clusters built from their
+// kubeconfig Secrets instead of a ClusterCacheTracker. Like the controller, it
+// reads both the RegisteredClusters and the Cluster API Clusters.
+func newInspector(opts options) (*gatewaydns.Inspector, error) {
+	managementClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get client: %w", err)
+	}
+
+	log := ctrl.Log.WithName("xccctl")
+	clientProvider := &inventory.ClientProvider{
+		Client:     managementClient,
+		Scheme:     scheme,
+		SourceName: "xccctl",
+		Fallback: &gatewaydns.KubeconfigClientProvider{
+			Client:     managementClient,
+			SourceName: "xccctl",
+		},
+	}
+	clusterInventory := inventory.Inventory{
+		inventory.Registered{Client: managementClient},
+		inventory.ClusterAPI{Client: managementClient},
+	}
+	return &gatewaydns.Inspector{
+		Client:          managementClient,
+		Log:             log,
+		ClientProvider:  clientProvider,
+		ClusterSearcher: &gatewaydns.ClusterSearcher{Client: managementClient, Inventory: clusterInventory},
+		EndpointSliceReconciler: &gatewaydns.EndpointSliceReconciler{
+			ClientProvider: clientProvider,
+			Namespace:      opts.controllerNamespace,
+			Log:            log.WithName("EndpointSliceReconciler"),
+		},
+		ClusterGatewayCollector: &gatewaydns.ClusterGatewayCollector{
+			Log:            log.WithName("EndpointSliceCollector"),
+			ClientProvider: clientProvider,
+			Namespace:      opts.controllerNamespace,
+			DomainSuffix:   opts.domainSuffix,
+		},
+	}, nil
+}
+
+// parseArgs parses the flags of a command, which may come before or after its
+// positional arguments, and returns the positional arguments.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// namespacedName parses namespace/name, defaulting the namespace.
+func namespacedName(s string, defaultNamespace string) (types.NamespacedName, error) {
+	if s == "" {
+		return types.NamespacedName{}, fmt.Errorf("name must not be empty")
+	}
+	namespace, name, ok := strings.Cut(s, "/")
+	if !ok {
+		return types.NamespacedName{Namespace: defaultNamespace, Name: s}, nil
+	}
+	if namespace == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("%q must be namespace/name or name", s)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+func newFlagSet(name string, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	return flags
+}
@@ -94,7 +94,7 @@ function setup_clusters() {
 function install_xcc() {
   echo "Install Cross-cluster-connectivity..."
   kind load docker-image "${XCC_DNS_CONTROLLER_IMAGE}" --name "${MANAGEMENT_CLUSTER}"
-  kubectl --kubeconfig "${MANAGEMENT_CLUSTER}.kubeconfig" apply -f "${ROOT}/manifests/crds/connectivity.tanzu.vmware.com_gatewaydns.yaml"
+  kubectl --kubeconfig "${MANAGEMENT_CLUSTER}.kubeconfig" apply -f "${ROOT}/manifests/crds/"
   kubectl --kubeconfig "${MANAGEMENT_CLUSTER}.kubeconfig" apply -f "${ROOT}/manifests/xcc-dns-controller/deployment.yaml"
 
   kind load docker-image "${DNS_SERVER_IMAGE}" --name "${CLUSTER_A}"
@@ -0,0 +1,20 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package manifests embeds the manifests that xcc-dns-controller installs on
+// the workload clusters, so that they are the same as the ones applied by
+// hand.
+package manifests
+
+import _ "embed"
+
+// DNSServer installs the dns-server in the xcc-dns namespace.
+//
+//go:embed dns-server/deployment.yaml
+var DNSServer []byte
+
+// DNSConfigPatcher runs the Job that forwards the DOMAIN_SUFFIX zone of the
+// cluster DNS to the dns-server.
+//
+//go:embed dns-config-patcher/deployment.yaml
+var DNSConfigPatcher []byte
@@ -0,0 +1,46 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Installer installs the dns-server and the dns-config-patcher on a workload
+// cluster, like a ClusterResourceSet would.
+type Installer struct {
+	Options Options
+
+	// FieldManager the objects are server-side applied as.
+	FieldManager string
+}
+
+// Install server-side applies the rendered manifests to the cluster of the
+// client, in order, so that the namespace exists before the objects in it.
+// The pod template of a Job cannot be changed, so existing Jobs are replaced,
+// which runs them again.
+func (i *Installer) Install(ctx context.Context, c client.Client) error {
+	objects, err := Render(i.Options)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if object.GetKind() == "Job" {
+			err := c.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return fmt.Errorf("replacing %s %s: %w", object.GetKind(), client.ObjectKeyFromObject(object), err)
+			}
+		}
+		err := c.Patch(ctx, object, client.Apply, client.FieldOwner(i.FieldManager), client.ForceOwnership)
+		if err != nil {
+			return fmt.Errorf("applying %s %s: %w", object.GetKind(), client.ObjectKeyFromObject(object), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consumer_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
+	batchv1 "k8s.io/api/batch/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// patchRecorder records the patches sent to the cluster, as the fake client
+// does not support server-side apply.
+type patchRecorder struct {
+	client.Client
+
+	patched []string
+	options []*client.PatchOptions
+	err     error
+}
+
+func (p *patchRecorder) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if p.err != nil {
+		return p.err
+	}
+	Expect(patch.Type()).To(Equal(types.ApplyPatchType))
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+	p.patched = append(p.patched, obj.GetObjectKind().GroupVersionKind().Kind+" "+client.ObjectKeyFromObject(obj).String())
+	p.options = append(p.options, patchOptions)
+	return nil
+}
+
+var _ = Describe("Installer", func() {
+	var (
+		recorder  *patchRecorder
+		installer *consumer.Installer
+	)
+
+	BeforeEach(func() {
+		recorder = &patchRecorder{Client: fake.NewClientBuilder().Build()}
+		installer = &consumer.Installer{
+			Options: consumer.Options{
+				Namespace:    "some-namespace",
+				DomainSuffix: "some.suffix",
+			},
+			FieldManager: "some-field-manager",
+		}
+	})
+
+	It("applies the rendered objects in order", func() {
+		Expect(installer.Install(context.Background(), recorder)).To(Succeed())
+
+		objects, err := consumer.Render(installer.Options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.patched).To(HaveLen(len(objects)))
+		Expect(recorder.patched[0]).To(Equal("Namespace /some-namespace"))
+		Expect(recorder.patched).To(ContainElement("Deployment some-namespace/dns-server"))
+		Expect(recorder.patched).To(ContainElement("Job some-namespace/dns-config-patcher"))
+		for _, options := range recorder.options {
+			Expect(options.FieldManager).To(Equal("some-field-manager"))
+			Expect(*options.Force).To(BeTrue())
+		}
+	})
+
+	Context("when the Job already exists", func() {
+		It("replaces it", func() {
+			Expect(recorder.Create(context.Background(), &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "dns-config-patcher"},
+			})).To(Succeed())
+
+			Expect(installer.Install(context.Background(), recorder)).To(Succeed())
+
+			var job batchv1.Job
+			err := recorder.Get(context.Background(), types.NamespacedName{Namespace: "some-namespace", Name: "dns-config-patcher"}, &job)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			Expect(recorder.patched).To(ContainElement("Job some-namespace/dns-config-patcher"))
+		})
+	})
+
+	Context("when applying fails", func() {
+		It("returns the error with the object", func() {
+			recorder.err = errors.New("forbidden")
+			err := installer.Install(context.Background(), recorder)
+			Expect(err).To(MatchError("applying Namespace /some-namespace: forbidden"))
+		})
+	})
+})
@@ -0,0 +1,235 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package consumer renders and installs the dns-server and the
+// dns-config-patcher on the workload clusters that consume the records
+// published by xcc-dns-controller.
+package consumer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/manifests"
+)
+
+const (
+	// manifestNamespace is the namespace the manifests are written for.
+	manifestNamespace = "xcc-dns"
+
+	dnsServerContainer        = "dns-server"
+	dnsConfigPatcherContainer = "dns-config-patcher"
+)
+
+// Options are the values rendered into the manifests.
+type Options struct {
+	// Namespace the dns-server is installed in. It must be the namespace
+	// xcc-dns-controller publishes EndpointSlices to.
+	Namespace string
+
+	// DomainSuffix the cluster DNS forwards to the dns-server.
+	DomainSuffix string
+
+	// Version replaces the tag of the images of the manifests, and is set as
+	// the app.kubernetes.io/version label of the objects.
+	Version string
+
+	// ImageRepository replaces the repository of the images of the
+	// manifests.
+	ImageRepository string
+
+	// DNSServerImage and DNSConfigPatcherImage replace the images of the
+	// manifests when set, regardless of Version and ImageRepository.
+	DNSServerImage        string
+	DNSConfigPatcherImage string
+}
+
+// VersionLabel is set to the Version the objects were rendered with.
+const VersionLabel = "app.kubernetes.io/version"
+
+// Render returns the objects of the dns-server and dns-config-patcher
+// manifests, in the order they have to be applied, with the namespace,
+// domain suffix and images of the options.
+func Render(options Options) ([]*unstructured.Unstructured, error) {
+	if options.Namespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+	if options.DomainSuffix == "" {
+		return nil, errors.New("domain suffix is required")
+	}
+
+	var objects []*unstructured.Unstructured
+	seen := map[string]bool{}
+	for _, manifest := range [][]byte{manifests.DNSServer, manifests.DNSConfigPatcher} {
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifest)))
+		for {
+			document, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(document)) == 0 {
+				continue
+			}
+
+			json, err := yaml.YAMLToJSON(document)
+			if err != nil {
+				return nil, err
+			}
+			object := &unstructured.Unstructured{}
+			if err := object.UnmarshalJSON(json); err != nil {
+				return nil, err
+			}
+			if err := render(object, options); err != nil {
+				return nil, fmt.Errorf("rendering %s %s: %w", object.GetKind(), object.GetName(), err)
+			}
+
+			// Both manifests create the namespace.
+			key := fmt.Sprintf("%s/%s/%s", object.GroupVersionKind(), object.GetNamespace(), object.GetName())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+func render(object *unstructured.Unstructured, options Options) error {
+	if object.GetKind() == "Namespace" && object.GetName() == manifestNamespace {
+		object.SetName(options.Namespace)
+	}
+	if object.GetNamespace() == manifestNamespace {
+		object.SetNamespace(options.Namespace)
+	}
+	if options.Version != "" {
+		labels := object.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[VersionLabel] = options.Version
+		object.SetLabels(labels)
+	}
+
+	switch object.GetKind() {
+	case "RoleBinding":
+		subjects, _, err := unstructured.NestedSlice(object.Object, "subjects")
+		if err != nil {
+			return err
+		}
+		for _, subject := range subjects {
+			subject, ok := subject.(map[string]interface{})
+			if ok && subject["namespace"] == manifestNamespace {
+				subject["namespace"] = options.Namespace
+			}
+		}
+		return unstructured.SetNestedSlice(object.Object, subjects, "subjects")
+	case "Deployment", "Job":
+		containers, _, err := unstructured.NestedSlice(object.Object, "spec", "template", "spec", "containers")
+		if err != nil {
+			return err
+		}
+		for _, container := range containers {
+			container, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			renderContainer(container, options)
+		}
+		return unstructured.SetNestedSlice(object.Object, containers, "spec", "template", "spec", "containers")
+	}
+	return nil
+}
+
+func renderContainer(container map[string]interface{}, options Options) {
+	if image, ok := container["image"].(string); ok {
+		container["image"] = renderImage(image, options)
+	}
+	switch container["name"] {
+	case dnsServerContainer:
+		if options.DNSServerImage != "" {
+			container["image"] = options.DNSServerImage
+		}
+	case dnsConfigPatcherContainer:
+		if options.DNSConfigPatcherImage != "" {
+			container["image"] = options.DNSConfigPatcherImage
+		}
+		// The canary is only known to be served by the dns-server of the
+		// manifests, or of their Version.
+		if options.DNSServerImage != "" {
+			if args := withoutArg(container["args"], "--verify"); args != nil {
+				container["args"] = args
+			} else {
+				delete(container, "args")
+			}
+		}
+	}
+
+	env, _ := container["env"].([]interface{})
+	for _, variable := range env {
+		variable, ok := variable.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch variable["name"] {
+		case "DNS_SERVICE_NAMESPACE":
+			variable["value"] = options.Namespace
+		case "DOMAIN_SUFFIX":
+			variable["value"] = options.DomainSuffix
+		}
+	}
+}
+
+// withoutArg returns the args without arg, or nil when none is left.
+func withoutArg(args interface{}, arg string) []interface{} {
+	values, _ := args.([]interface{})
+	var kept []interface{}
+	for _, value := range values {
+		if value != arg {
+			kept = append(kept, value)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// renderImage replaces the repository and the tag of the image with the
+// ImageRepository and Version of the options.
+func renderImage(image string, options Options) string {
+	repository, name := "", image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		repository, name = image[:i], image[i+1:]
+	}
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name, tag = name[:i], name[i+1:]
+	}
+
+	if options.ImageRepository != "" {
+		repository = strings.TrimSuffix(options.ImageRepository, "/")
+	}
+	if options.Version != "" {
+		tag = options.Version
+	}
+
+	if repository != "" {
+		name = repository + "/" + name
+	}
+	if tag != "" {
+		name = name + ":" + tag
+	}
+	return name
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consumer_test
+
+import (
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Render", func() {
+	var options consumer.Options
+
+	BeforeEach(func() {
+		options = consumer.Options{
+			Namespace:    "some-namespace",
+			DomainSuffix: "some.suffix",
+		}
+	})
+
+	find := func(objects []*unstructured.Unstructured, kind, namespace, name string) *unstructured.Unstructured {
+		for _, object := range objects {
+			if object.GetKind() == kind && object.GetNamespace() == namespace && object.GetName() == name {
+				return object
+			}
+		}
+		return nil
+	}
+
+	containers := func(object *unstructured.Unstructured) []interface{} {
+		containers, found, err := unstructured.NestedSlice(object.Object, "spec", "template", "spec", "containers")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		return containers
+	}
+
+	env := func(object *unstructured.Unstructured) map[string]interface{} {
+		values := map[string]interface{}{}
+		for _, variable := range containers(object)[0].(map[string]interface{})["env"].([]interface{}) {
+			variable := variable.(map[string]interface{})
+			values[variable["name"].(string)] = variable["value"]
+		}
+		return values
+	}
+
+	It("renders the namespace once, before the objects in it", func() {
+		objects, err := consumer.Render(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		var namespaces []string
+		for _, object := range objects {
+			if object.GetKind() == "Namespace" {
+				namespaces = append(namespaces, object.GetName())
+			}
+			Expect(object.GetNamespace()).To(BeElementOf("", "some-namespace", "kube-system"))
+		}
+		Expect(namespaces).To(Equal([]string{"some-namespace"}))
+		Expect(objects[0].GetKind()).To(Equal("Namespace"))
+
+		Expect(find(objects, "Deployment", "some-namespace", "dns-server")).NotTo(BeNil())
+		Expect(find(objects, "ConfigMap", "some-namespace", "dns-server-corefile")).NotTo(BeNil())
+		Expect(find(objects, "Service", "some-namespace", "dns-server")).NotTo(BeNil())
+		Expect(find(objects, "Role", "kube-system", "system-corefile-updater")).NotTo(BeNil())
+	})
+
+	It("binds the roles to the service accounts of the namespace", func() {
+		objects, err := consumer.Render(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		roleBinding := find(objects, "RoleBinding", "kube-system", "dns-config-patcher")
+		Expect(roleBinding).NotTo(BeNil())
+		subjects, _, err := unstructured.NestedSlice(roleBinding.Object, "subjects")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subjects).To(ConsistOf(map[string]interface{}{
+			"kind":      "ServiceAccount",
+			"name":      "dns-config-patcher",
+			"namespace": "some-namespace",
+		}))
+	})
+
+	It("points the dns-config-patcher to the dns-server of the namespace and the domain suffix", func() {
+		objects, err := consumer.Render(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		job := find(objects, "Job", "some-namespace", "dns-config-patcher")
+		Expect(job).NotTo(BeNil())
+		Expect(env(job)).To(HaveKeyWithValue("DNS_SERVICE_NAMESPACE", "some-namespace"))
+		Expect(env(job)).To(HaveKeyWithValue("DOMAIN_SUFFIX", "some.suffix"))
+	})
+
+	It("verifies the stub domain against the canary of the dns-server", func() {
+		objects, err := consumer.Render(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		job := find(objects, "Job", "some-namespace", "dns-config-patcher")
+		Expect(containers(job)[0]).To(HaveKeyWithValue("args", ConsistOf("--verify")))
+	})
+
+	It("keeps the images of the manifests by default", func() {
+		objects, err := consumer.Render(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		deployment := find(objects, "Deployment", "some-namespace", "dns-server")
+		Expect(containers(deployment)[0]).To(HaveKeyWithValue("image", "gcr.io/tanzu-xcc/dns-server:dev"))
+	})
+
+	Context("when images are set", func() {
+		It("replaces the images of the manifests", func() {
+			options.DNSServerImage = "some-registry/dns-server:v1"
+			options.DNSConfigPatcherImage = "some-registry/dns-config-patcher:v1"
+			objects, err := consumer.Render(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := find(objects, "Deployment", "some-namespace", "dns-server")
+			Expect(containers(deployment)[0]).To(HaveKeyWithValue("image", "some-registry/dns-server:v1"))
+			job := find(objects, "Job", "some-namespace", "dns-config-patcher")
+			Expect(containers(job)[0]).To(HaveKeyWithValue("image", "some-registry/dns-config-patcher:v1"))
+		})
+
+		It("does not verify against a dns-server that may not serve the canary", func() {
+			options.DNSServerImage = "some-registry/dns-server:v1"
+			objects, err := consumer.Render(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			job := find(objects, "Job", "some-namespace", "dns-config-patcher")
+			Expect(containers(job)[0]).NotTo(HaveKey("args"))
+		})
+	})
+
+	Context("when a version and an image repository are set", func() {
+		BeforeEach(func() {
+			options.Version = "v1.2.3"
+			options.ImageRepository = "some-registry.example.com/xcc/"
+		})
+
+		It("replaces the repository and the tag of the images", func() {
+			objects, err := consumer.Render(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := find(objects, "Deployment", "some-namespace", "dns-server")
+			Expect(containers(deployment)[0]).To(HaveKeyWithValue("image", "some-registry.example.com/xcc/dns-server:v1.2.3"))
+			job := find(objects, "Job", "some-namespace", "dns-config-patcher")
+			Expect(containers(job)[0]).To(HaveKeyWithValue("image", "some-registry.example.com/xcc/dns-config-patcher:v1.2.3"))
+		})
+
+		It("labels the objects with the version", func() {
+			objects, err := consumer.Render(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, object := range objects {
+				Expect(object.GetLabels()).To(HaveKeyWithValue(consumer.VersionLabel, "v1.2.3"))
+			}
+		})
+
+		It("prefers the images that are set", func() {
+			options.DNSServerImage = "some-registry/dns-server@sha256:abc"
+			objects, err := consumer.Render(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := find(objects, "Deployment", "some-namespace", "dns-server")
+			Expect(containers(deployment)[0]).To(HaveKeyWithValue("image", "some-registry/dns-server@sha256:abc"))
+		})
+	})
+
+	Context("when the domain suffix is not set", func() {
+		It("returns an error", func() {
+			options.DomainSuffix = ""
+			_, err := consumer.Render(options)
+			Expect(err).To(MatchError("domain suffix is required"))
+		})
+	})
+})
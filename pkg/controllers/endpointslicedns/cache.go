@@ -6,8 +6,11 @@ package endpointslicedns
 import (
 	"fmt"
 	"net"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/miekg/dns"
 )
@@ -17,118 +20,217 @@ type DNSCacheEntry struct {
 	ResourceKey string
 	FQDN        string
 	Addresses   []string
+
+	// Weight is the relative weight of the addresses in answers, or 0 for the
+	// default weight. Region and Zone are the topology of the gateway.
+	Weight int32
+	Region string
+	Zone   string
+
+	// ViewAddresses are alternative addresses served to clients in the named
+	// view instead of Addresses.
+	ViewAddresses map[string][]string
 }
 
 // DNSCache maps Domain Name -> DNSCacheEntry
+//
+// Reads are served from an immutable snapshot that is swapped atomically on
+// every write, so lookups never take a lock and never observe a partially
+// applied update. Slices returned by Lookup are shared with the snapshot and
+// must not be modified by callers.
 type DNSCache struct {
-	mutex             sync.RWMutex
+	// writeMutex serializes writers; readers only load the snapshot.
+	writeMutex  sync.Mutex
+	snapshot    atomic.Value // *dnsCacheSnapshot
+	isPopulated int32
+}
+
+// dnsCacheSnapshot is never mutated once it has been stored in a DNSCache.
+type dnsCacheSnapshot struct {
 	entries           map[string][]DNSCacheEntry
 	resourceKeyToFQDN map[string]string
-	isPopulated       bool
+	serial            uint32
 }
 
-// Upsert updates or inserts the DNSCacheEntry in the cache
-func (d *DNSCache) Upsert(entry DNSCacheEntry) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+var emptySnapshot = &dnsCacheSnapshot{}
 
-	if d.entries == nil || d.resourceKeyToFQDN == nil {
-		d.entries = make(map[string][]DNSCacheEntry)
-		d.resourceKeyToFQDN = make(map[string]string)
+func (d *DNSCache) load() *dnsCacheSnapshot {
+	if s, ok := d.snapshot.Load().(*dnsCacheSnapshot); ok {
+		return s
 	}
-	fqdn := dns.CanonicalName(entry.FQDN)
+	return emptySnapshot
+}
 
-	if oldFQDN, ok := d.resourceKeyToFQDN[entry.ResourceKey]; ok {
-		if oldFQDN != fqdn {
-			for i, oldEntry := range d.entries[oldFQDN] {
-				if oldEntry.ResourceKey == entry.ResourceKey {
-					d.entries[oldFQDN] = append(d.entries[oldFQDN][:i], d.entries[oldFQDN][i+1:]...)
-					break
-				}
-			}
-			if len(d.entries[oldFQDN]) == 0 {
-				delete(d.entries, oldFQDN)
-			}
+// clone returns a copy of the snapshot whose maps may be modified. The
+// entry slices are still shared and must be replaced, not written to. The
+// copy carries the next serial number.
+func (s *dnsCacheSnapshot) clone() *dnsCacheSnapshot {
+	next := &dnsCacheSnapshot{
+		entries:           make(map[string][]DNSCacheEntry, len(s.entries)+1),
+		resourceKeyToFQDN: make(map[string]string, len(s.resourceKeyToFQDN)+1),
+		serial:            nextSerial(s.serial),
+	}
+	for fqdn, entries := range s.entries {
+		next.entries[fqdn] = entries
+	}
+	for resourceKey, fqdn := range s.resourceKeyToFQDN {
+		next.resourceKeyToFQDN[resourceKey] = fqdn
+	}
+	return next
+}
+
+// nextSerial returns a serial that is greater than the previous one and, where
+// possible, the current unix time so that serials keep increasing across
+// restarts of the dns-server.
+func nextSerial(previous uint32) uint32 {
+	now := uint32(time.Now().Unix())
+	if now > previous {
+		return now
+	}
+	return previous + 1
+}
+
+// withoutResourceKey returns a new slice containing every entry except the
+// one with the given resource key.
+func withoutResourceKey(entries []DNSCacheEntry, resourceKey string) []DNSCacheEntry {
+	remaining := make([]DNSCacheEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ResourceKey != resourceKey {
+			remaining = append(remaining, entry)
 		}
 	}
+	return remaining
+}
+
+// Upsert updates or inserts the DNSCacheEntry in the cache
+func (d *DNSCache) Upsert(entry DNSCacheEntry) {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
 
+	current := d.load()
+	fqdn := dns.CanonicalName(entry.FQDN)
+
+	// The caller keeps ownership of entry.Addresses, so canonicalize into a
+	// copy rather than rewriting it in place.
+	addresses := make([]string, len(entry.Addresses))
 	for i, address := range entry.Addresses {
 		if net.ParseIP(address) == nil {
-			entry.Addresses[i] = dns.CanonicalName(address)
+			address = dns.CanonicalName(address)
+		}
+		addresses[i] = address
+	}
+	entry.Addresses = addresses
+	entry.FQDN = fqdn
+	if entry.ViewAddresses != nil {
+		viewAddresses := make(map[string][]string, len(entry.ViewAddresses))
+		for view, addresses := range entry.ViewAddresses {
+			viewAddresses[view] = append([]string(nil), addresses...)
 		}
-		entry.FQDN = dns.CanonicalName(entry.FQDN)
+		entry.ViewAddresses = viewAddresses
 	}
 
-	updated := false
-	for i, e := range d.entries[fqdn] {
+	// Resyncs upsert unchanged entries; skipping them keeps the serial stable.
+	if existing := current.lookupByResourceKey(entry.ResourceKey); existing != nil && reflect.DeepEqual(*existing, entry) {
+		return
+	}
+
+	next := current.clone()
+	if oldFQDN, ok := next.resourceKeyToFQDN[entry.ResourceKey]; ok && oldFQDN != fqdn {
+		next.entries[oldFQDN] = withoutResourceKey(next.entries[oldFQDN], entry.ResourceKey)
+		if len(next.entries[oldFQDN]) == 0 {
+			delete(next.entries, oldFQDN)
+		}
+	}
+
+	existing := next.entries[fqdn]
+	updated := make([]DNSCacheEntry, 0, len(existing)+1)
+	replaced := false
+	for _, e := range existing {
 		if e.ResourceKey == entry.ResourceKey {
-			d.entries[fqdn][i] = entry
-			updated = true
-			break
+			e = entry
+			replaced = true
 		}
+		updated = append(updated, e)
 	}
-	if !updated {
-		d.entries[fqdn] = append(d.entries[fqdn], entry)
+	if !replaced {
+		updated = append(updated, entry)
 	}
-	d.resourceKeyToFQDN[entry.ResourceKey] = fqdn
+	next.entries[fqdn] = updated
+	next.resourceKeyToFQDN[entry.ResourceKey] = fqdn
+
+	d.snapshot.Store(next)
 }
 
 // Delete removes the DNSCacheEntries associated with the provided FQDN
 func (d *DNSCache) Delete(fqdn string) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
 
-	if d.entries == nil || d.resourceKeyToFQDN == nil {
+	fqdn = dns.CanonicalName(fqdn)
+	current := d.load()
+	if _, ok := current.entries[fqdn]; !ok {
 		return
 	}
-	fqdn = dns.CanonicalName(fqdn)
-	for _, entry := range d.entries[fqdn] {
-		delete(d.resourceKeyToFQDN, entry.ResourceKey)
+
+	next := current.clone()
+	for _, entry := range next.entries[fqdn] {
+		delete(next.resourceKeyToFQDN, entry.ResourceKey)
 	}
-	delete(d.entries, fqdn)
+	delete(next.entries, fqdn)
+
+	d.snapshot.Store(next)
 }
 
 // DeleteByResourceKey removes the DNSCacheEntry associated with the resource key
 func (d *DNSCache) DeleteByResourceKey(resourceKey string) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
 
-	if d.entries == nil || d.resourceKeyToFQDN == nil {
+	current := d.load()
+	fqdnToUpdate, ok := current.resourceKeyToFQDN[resourceKey]
+	if !ok {
 		return
 	}
-	if fqdnToUpdate, ok := d.resourceKeyToFQDN[resourceKey]; ok {
-		for i, entry := range d.entries[fqdnToUpdate] {
-			if entry.ResourceKey == resourceKey {
-				d.entries[fqdnToUpdate] = append(d.entries[fqdnToUpdate][:i], d.entries[fqdnToUpdate][i+1:]...)
-				break
-			}
 
-			if len(d.entries[fqdnToUpdate]) == 0 {
-				delete(d.entries, fqdnToUpdate)
-			}
-		}
-		delete(d.resourceKeyToFQDN, resourceKey)
+	next := current.clone()
+	next.entries[fqdnToUpdate] = withoutResourceKey(next.entries[fqdnToUpdate], resourceKey)
+	if len(next.entries[fqdnToUpdate]) == 0 {
+		delete(next.entries, fqdnToUpdate)
+	}
+	delete(next.resourceKeyToFQDN, resourceKey)
+
+	d.snapshot.Store(next)
+}
+
+// AddressesForView returns the addresses of the entry in the view, or its
+// default addresses when the view is empty or the entry has none for it.
+func (e DNSCacheEntry) AddressesForView(view string) []string {
+	if addresses, ok := e.ViewAddresses[view]; ok && view != "" && len(addresses) > 0 {
+		return addresses
 	}
+	return e.Addresses
 }
 
-// Lookup retrieves the DNSCacheEntries associated with the provided FQDN
+// Lookup retrieves the DNSCacheEntries associated with the provided FQDN. The
+// returned slice belongs to an immutable snapshot and must not be modified.
 func (d *DNSCache) Lookup(fqdn string) []DNSCacheEntry {
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
+	return d.load().lookup(fqdn)
+}
 
-	if d.entries == nil || d.resourceKeyToFQDN == nil {
+func (s *dnsCacheSnapshot) lookup(fqdn string) []DNSCacheEntry {
+	if len(s.entries) == 0 {
 		return nil
 	}
 
 	fqdn = dns.CanonicalName(fqdn)
-	if e, ok := d.entries[fqdn]; ok {
+	if e, ok := s.entries[fqdn]; ok {
 		return e
 	}
 
 	labels := dns.SplitDomainName(fqdn)
 	for len(labels) > 0 {
 		nextLookup := fmt.Sprintf("*.%s.", strings.Join(labels[1:], "."))
-		if e, ok := d.entries[nextLookup]; ok {
+		if e, ok := s.entries[nextLookup]; ok {
 			return e
 		}
 		labels = labels[1:]
@@ -139,15 +241,12 @@ func (d *DNSCache) Lookup(fqdn string) []DNSCacheEntry {
 
 // LookupByResourceKey retrieves the DNSCacheEntry associated with the resource key
 func (d *DNSCache) LookupByResourceKey(resourceKey string) *DNSCacheEntry {
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
-
-	if d.entries == nil || d.resourceKeyToFQDN == nil {
-		return nil
-	}
+	return d.load().lookupByResourceKey(resourceKey)
+}
 
-	if fqdn, ok := d.resourceKeyToFQDN[resourceKey]; ok {
-		for _, e := range d.entries[fqdn] {
+func (s *dnsCacheSnapshot) lookupByResourceKey(resourceKey string) *DNSCacheEntry {
+	if fqdn, ok := s.resourceKeyToFQDN[resourceKey]; ok {
+		for _, e := range s.entries[fqdn] {
 			if e.ResourceKey == resourceKey {
 				return &e
 			}
@@ -156,19 +255,48 @@ func (d *DNSCache) LookupByResourceKey(resourceKey string) *DNSCacheEntry {
 	return nil
 }
 
+// NameExists returns true when the FQDN resolves to any entry, either directly
+// or through a wildcard, or when it is an empty non-terminal: a name that owns
+// no records itself but has entries below it (RFC 8020).
+func (d *DNSCache) NameExists(fqdn string) bool {
+	s := d.load()
+	fqdn = dns.CanonicalName(fqdn)
+	if len(s.lookup(fqdn)) > 0 {
+		return true
+	}
+	for name := range s.entries {
+		if dns.IsSubDomain(fqdn, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns every FQDN that has entries in the cache, including wildcard
+// names such as "*.gateway.example.com.".
+func (d *DNSCache) Names() []string {
+	s := d.load()
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Serial returns a number that increases every time the contents of the cache
+// change and stays the same otherwise. It is suitable as a SOA serial.
+func (d *DNSCache) Serial() uint32 {
+	return d.load().serial
+}
+
 // IsPopulated returns true when the cache is fully populated
 func (d *DNSCache) IsPopulated() bool {
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
-
-	return d.isPopulated
+	return atomic.LoadInt32(&d.isPopulated) == 1
 }
 
 // SetPopulated marks the cache as populated
 func (d *DNSCache) SetPopulated() {
-	d.mutex.Lock()
-	d.isPopulated = true
-	d.mutex.Unlock()
+	atomic.StoreInt32(&d.isPopulated, 1)
 }
 
 // IsValid returns true if and only if the DNS entry associated with FQDN is
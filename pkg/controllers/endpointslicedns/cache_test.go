@@ -4,6 +4,10 @@
 package endpointslicedns_test
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
 
 	. "github.com/onsi/ginkgo"
@@ -347,4 +351,239 @@ var _ = Describe("DNSCache", func() {
 			Expect(cache.IsValid("a.b.d")).To(BeFalse())
 		})
 	})
+	Describe("NameExists", func() {
+		var cache *endpointslicedns.DNSCache
+
+		BeforeEach(func() {
+			cache = new(endpointslicedns.DNSCache)
+			cache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-abc",
+				FQDN:        "a.b.c.",
+				Addresses:   []string{"1.2.3.4"},
+			})
+			cache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-wildcard",
+				FQDN:        "*.gateway.d.e.",
+				Addresses:   []string{"2.3.4.5"},
+			})
+		})
+
+		It("returns true for names with entries", func() {
+			Expect(cache.NameExists("a.b.c")).To(BeTrue())
+		})
+
+		It("returns true for names matching a wildcard", func() {
+			Expect(cache.NameExists("foo.gateway.d.e")).To(BeTrue())
+		})
+
+		It("returns true for empty non-terminals", func() {
+			Expect(cache.NameExists("b.c")).To(BeTrue())
+			Expect(cache.NameExists("gateway.d.e")).To(BeTrue())
+		})
+
+		It("returns false for names without entries", func() {
+			Expect(cache.NameExists("x.b.c")).To(BeFalse())
+			Expect(cache.NameExists("a.b.c.d")).To(BeFalse())
+		})
+	})
+
+	Describe("Names", func() {
+		It("returns the canonical names of all entries", func() {
+			cache := new(endpointslicedns.DNSCache)
+			Expect(cache.Names()).To(BeEmpty())
+
+			cache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-abc",
+				FQDN:        "A.b.c",
+				Addresses:   []string{"1.2.3.4"},
+			})
+			cache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-wildcard",
+				FQDN:        "*.gateway.d.e",
+				Addresses:   []string{"2.3.4.5"},
+			})
+
+			Expect(cache.Names()).To(ConsistOf("a.b.c.", "*.gateway.d.e."))
+		})
+	})
+
+	Describe("Serial", func() {
+		var (
+			cache *endpointslicedns.DNSCache
+			entry endpointslicedns.DNSCacheEntry
+		)
+
+		BeforeEach(func() {
+			cache = new(endpointslicedns.DNSCache)
+			entry = endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-abc",
+				FQDN:        "A.b.c",
+				Addresses:   []string{"1.2.3.4", "FOO.com"},
+			}
+			cache.Upsert(entry)
+		})
+
+		It("does not change when an identical entry is upserted", func() {
+			serial := cache.Serial()
+			cache.Upsert(entry)
+			Expect(cache.Serial()).To(Equal(serial))
+		})
+
+		It("does not change when deleting entries that do not exist", func() {
+			serial := cache.Serial()
+			cache.Delete("x.y.z")
+			cache.DeleteByResourceKey("12345-missing")
+			Expect(cache.Serial()).To(Equal(serial))
+		})
+
+		It("increases on every change", func() {
+			serial := cache.Serial()
+
+			entry.Addresses = []string{"2.3.4.5"}
+			cache.Upsert(entry)
+			Expect(cache.Serial()).To(BeNumerically(">", serial))
+			serial = cache.Serial()
+
+			cache.DeleteByResourceKey("12345-abc")
+			Expect(cache.Serial()).To(BeNumerically(">", serial))
+			serial = cache.Serial()
+
+			cache.Upsert(entry)
+			cache.Delete("a.b.c")
+			Expect(cache.Serial()).To(BeNumerically(">", serial+1))
+		})
+	})
+
+	Describe("snapshot isolation", func() {
+		It("does not modify the addresses passed to Upsert", func() {
+			cache := new(endpointslicedns.DNSCache)
+			addresses := []string{"FOO.com"}
+			cache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-abc",
+				FQDN:        "a.b.c",
+				Addresses:   addresses,
+			})
+
+			Expect(addresses).To(Equal([]string{"FOO.com"}))
+			Expect(cache.Lookup("a.b.c")[0].Addresses).To(Equal([]string{"foo.com."}))
+		})
+
+		It("does not change previously returned lookups when the cache is written to", func() {
+			cache := new(endpointslicedns.DNSCache)
+			firstEntry := endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-first",
+				FQDN:        "a.b.c.",
+				Addresses:   []string{"1.2.3.4"},
+			}
+			secondEntry := endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-second",
+				FQDN:        "a.b.c.",
+				Addresses:   []string{"2.3.4.5"},
+			}
+			cache.Upsert(firstEntry)
+			cache.Upsert(secondEntry)
+
+			lookup := cache.Lookup("a.b.c")
+			Expect(lookup).To(Equal([]endpointslicedns.DNSCacheEntry{firstEntry, secondEntry}))
+
+			cache.DeleteByResourceKey("12345-first")
+			cache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-second",
+				FQDN:        "a.b.c.",
+				Addresses:   []string{"3.4.5.6"},
+			})
+
+			Expect(lookup).To(Equal([]endpointslicedns.DNSCacheEntry{firstEntry, secondEntry}))
+			Expect(cache.Lookup("a.b.c")).To(ConsistOf(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "12345-second",
+				FQDN:        "a.b.c.",
+				Addresses:   []string{"3.4.5.6"},
+			}))
+		})
+
+		It("never exposes torn entries to concurrent readers", func() {
+			const (
+				writers    = 4
+				readers    = 8
+				iterations = 500
+			)
+			cache := new(endpointslicedns.DNSCache)
+
+			// Every address written for a resource key is prefixed with that
+			// key's writer index, so a reader can tell if it observed an entry
+			// that was half rewritten.
+			entryFor := func(writer, iteration int) endpointslicedns.DNSCacheEntry {
+				fqdn := "a.b.c."
+				if iteration%3 == 0 {
+					fqdn = "x.b.c."
+				}
+				return endpointslicedns.DNSCacheEntry{
+					ResourceKey: fmt.Sprintf("writer-%d", writer),
+					FQDN:        fqdn,
+					Addresses: []string{
+						fmt.Sprintf("10.%d.%d.1", writer, iteration%250),
+						fmt.Sprintf("10.%d.%d.2", writer, iteration%250),
+					},
+				}
+			}
+
+			var wg sync.WaitGroup
+			done := make(chan struct{})
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(writer int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					for i := 0; i < iterations; i++ {
+						cache.Upsert(entryFor(writer, i))
+						if i%7 == 0 {
+							cache.DeleteByResourceKey(fmt.Sprintf("writer-%d", writer))
+						}
+						if i%50 == 0 {
+							cache.Delete("x.b.c")
+						}
+					}
+				}(w)
+			}
+
+			var readerWG sync.WaitGroup
+			for r := 0; r < readers; r++ {
+				readerWG.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer readerWG.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						for _, fqdn := range []string{"a.b.c", "x.b.c"} {
+							for _, entry := range cache.Lookup(fqdn) {
+								Expect(entry.FQDN).To(Equal(fqdn + "."))
+								Expect(entry.Addresses).To(HaveLen(2))
+								prefix := "10." + strings.TrimPrefix(entry.ResourceKey, "writer-") + "."
+								for _, address := range entry.Addresses {
+									Expect(address).To(HavePrefix(prefix))
+								}
+							}
+						}
+						if entry := cache.LookupByResourceKey("writer-0"); entry != nil {
+							Expect(entry.Addresses).To(HaveLen(2))
+						}
+						cache.IsValid("a.b.c")
+					}
+				}()
+			}
+
+			wg.Wait()
+			close(done)
+			readerWG.Wait()
+
+			for w := 0; w < writers; w++ {
+				final := entryFor(w, iterations-1)
+				Expect(cache.LookupByResourceKey(final.ResourceKey)).To(Equal(&final))
+			}
+		})
+	})
 })
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -46,10 +47,41 @@ func (r *EndpointSliceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 	}
 
-	fqdn, ok := endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]
+	entry, ok := DNSCacheEntryForEndpointSlice(log, endpointSlice)
 	if !ok {
 		return ctrl.Result{}, nil
 	}
+	fqdn := entry.FQDN
+
+	r.RecordsCache.Upsert(entry)
+	log.WithValues("dns-hostname", fqdn).Info("Successfully synced")
+
+	if !r.RecordsCache.IsValid(fqdn) {
+		errLines := []string{
+			fmt.Sprintf(`DNS entry for "%s" is in an invalid state and will`, fqdn),
+			`lead to undefined behavior on DNS lookup.`,
+		}
+		msgLines := []string{
+			"If this FQDN is to resolve to a CNAME record, check to ensure any",
+			"FQDN EndpointSlice associated with this FQDN is the only",
+			"EndpointSlice annotated with this FQDN. Otherwise, if the FQDN is to",
+			"resolve to an A record, then ensure there are no FQDN EndpointSlices",
+			"annotated with this FQDN.",
+		}
+		log.Error(errors.New(strings.Join(errLines, " ")), strings.Join(msgLines, " "))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// DNSCacheEntryForEndpointSlice returns the entry the dns-server serves for
+// the EndpointSlice, or false when the EndpointSlice has no DNS hostname or an
+// unhandled address type.
+func DNSCacheEntryForEndpointSlice(log logr.Logger, endpointSlice discoveryv1.EndpointSlice) (DNSCacheEntry, bool) {
+	fqdn, ok := endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]
+	if !ok {
+		return DNSCacheEntry{}, false
+	}
 
 	addresses := []string{}
 
@@ -70,32 +102,47 @@ func (r *EndpointSliceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 	} else {
 		log.Info("Skipping EndpointSlice with unhandled AddressType")
-		return ctrl.Result{}, nil
+		return DNSCacheEntry{}, false
 	}
 
-	r.RecordsCache.Upsert(DNSCacheEntry{
-		ResourceKey: req.String(),
-		FQDN:        fqdn,
-		Addresses:   addresses,
-	})
-	log.WithValues("dns-hostname", fqdn).Info("Successfully synced")
+	var weight int32
+	if annotation, ok := endpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey]; ok {
+		parsed, err := strconv.ParseInt(annotation, 10, 32)
+		if err != nil || parsed < 1 {
+			log.Error(fmt.Errorf("Invalid gateway weight: %s", annotation), "Using the default weight")
+		} else {
+			weight = int32(parsed)
+		}
+	}
 
-	if !r.RecordsCache.IsValid(fqdn) {
-		errLines := []string{
-			fmt.Sprintf(`DNS entry for "%s" is in an invalid state and will`, fqdn),
-			`lead to undefined behavior on DNS lookup.`,
+	viewAddresses := map[string][]string{}
+	for key, value := range endpointSlice.Annotations {
+		if !strings.HasPrefix(key, connectivityv1alpha1.ViewAddressesAnnotationPrefix) {
+			continue
 		}
-		msgLines := []string{
-			"If this FQDN is to resolve to a CNAME record, check to ensure any",
-			"FQDN EndpointSlice associated with this FQDN is the only",
-			"EndpointSlice annotated with this FQDN. Otherwise, if the FQDN is to",
-			"resolve to an A record, then ensure there are no FQDN EndpointSlices",
-			"annotated with this FQDN.",
+		view := strings.TrimPrefix(key, connectivityv1alpha1.ViewAddressesAnnotationPrefix)
+		for _, address := range strings.Split(value, ",") {
+			ip := net.ParseIP(strings.TrimSpace(address))
+			if ip == nil {
+				log.Error(errors.New("invalid IP"), "Skipping view address", "View", view, "Address", address)
+				continue
+			}
+			viewAddresses[view] = append(viewAddresses[view], ip.String())
 		}
-		log.Error(errors.New(strings.Join(errLines, " ")), strings.Join(msgLines, " "))
+	}
+	if len(viewAddresses) == 0 {
+		viewAddresses = nil
 	}
 
-	return ctrl.Result{}, nil
+	return DNSCacheEntry{
+		ResourceKey:   fmt.Sprintf("%s/%s", endpointSlice.Namespace, endpointSlice.Name),
+		FQDN:          fqdn,
+		Addresses:     addresses,
+		Weight:        weight,
+		Region:        endpointSlice.Annotations[connectivityv1alpha1.GatewayRegionAnnotation],
+		Zone:          endpointSlice.Annotations[connectivityv1alpha1.GatewayZoneAnnotation],
+		ViewAddresses: viewAddresses,
+	}, true
 }
 
 func (r *EndpointSliceReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -289,6 +289,66 @@ var _ = Describe("Reconcile", func() {
 			})
 		})
 
+		When("the EndpointSlice carries the weight and topology of a gateway", func() {
+			BeforeEach(func() {
+				endpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey] = "3"
+				endpointSlice.Annotations[connectivityv1alpha1.GatewayRegionAnnotation] = "us-east-1"
+				endpointSlice.Annotations[connectivityv1alpha1.GatewayZoneAnnotation] = "us-east-1a"
+				err := kubeClient.Update(context.Background(), endpointSlice)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("stores them in the dns cache entry", func() {
+				_, err := endpointSliceReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				cacheEntries := dnsCache.Lookup("foo.xcc.test")
+				Expect(cacheEntries).To(HaveLen(1))
+				Expect(cacheEntries[0].Weight).To(Equal(int32(3)))
+				Expect(cacheEntries[0].Region).To(Equal("us-east-1"))
+				Expect(cacheEntries[0].Zone).To(Equal("us-east-1a"))
+			})
+
+			When("the weight is invalid", func() {
+				BeforeEach(func() {
+					endpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey] = "-1"
+					err := kubeClient.Update(context.Background(), endpointSlice)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("uses the default weight", func() {
+					_, err := endpointSliceReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					cacheEntries := dnsCache.Lookup("foo.xcc.test")
+					Expect(cacheEntries).To(HaveLen(1))
+					Expect(cacheEntries[0].Weight).To(BeZero())
+					Expect(cacheEntriesToAddresses(cacheEntries)).To(ConsistOf(expectedIPs))
+				})
+			})
+		})
+
+		When("the EndpointSlice carries addresses for views", func() {
+			BeforeEach(func() {
+				endpointSlice.Annotations[connectivityv1alpha1.ViewAddressesAnnotationPrefix+"private"] = "10.0.0.1, 10.0.0.2,not-an-ip"
+				err := kubeClient.Update(context.Background(), endpointSlice)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("stores the valid addresses of each view in the dns cache entry", func() {
+				_, err := endpointSliceReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				cacheEntries := dnsCache.Lookup("foo.xcc.test")
+				Expect(cacheEntries).To(HaveLen(1))
+				Expect(cacheEntries[0].ViewAddresses).To(Equal(map[string][]string{
+					"private": {"10.0.0.1", "10.0.0.2"},
+				}))
+				Expect(cacheEntries[0].AddressesForView("private")).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+				Expect(cacheEntries[0].AddressesForView("public")).To(ConsistOf(expectedIPs))
+			})
+		})
+
 		When("an invalid IP is provided as part of an IPv4 EndpointSlice", func() {
 			BeforeEach(func() {
 				endpointSlice.Endpoints = append(endpointSlice.Endpoints, discoveryv1.Endpoint{
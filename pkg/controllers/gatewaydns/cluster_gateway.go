@@ -5,6 +5,8 @@ package gatewaydns
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
@@ -21,6 +23,13 @@ type ClusterGateway struct {
 	DomainSuffix             string
 	ControllerNamespace      string // xcc-test by default, where xcc-dns-controller and dns-server are deployed
 	GatewayDNSNamespacedName types.NamespacedName
+
+	// Weight is the relative weight of the gateway in DNS answers, or nil
+	// for the default weight. Region and Zone are the topology of the
+	// cluster of the gateway.
+	Weight *int32
+	Region string
+	Zone   string
 }
 
 func (cg ClusterGateway) ToEndpointSlice() discoveryv1.EndpointSlice {
@@ -34,16 +43,34 @@ func (cg ClusterGateway) ToEndpointSlice() discoveryv1.EndpointSlice {
 		addresses = append(addresses, ingress.IP)
 	}
 
+	annotations := map[string]string{
+		connectivityv1alpha1.DNSHostnameAnnotation:   hostname,
+		connectivityv1alpha1.GatewayDNSRefAnnotation: cg.GatewayDNSNamespacedName.String(),
+		connectivityv1alpha1.ClusterRefAnnotation:    cg.ClusterNamespacedName.String(),
+	}
+	if cg.Weight != nil {
+		annotations[connectivityv1alpha1.GatewayWeightKey] = strconv.Itoa(int(*cg.Weight))
+	}
+	if cg.Region != "" {
+		annotations[connectivityv1alpha1.GatewayRegionAnnotation] = cg.Region
+	}
+	if cg.Zone != "" {
+		annotations[connectivityv1alpha1.GatewayZoneAnnotation] = cg.Zone
+	}
+	for key, value := range cg.Gateway.Annotations {
+		if strings.HasPrefix(key, connectivityv1alpha1.ViewAddressesAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+
 	return discoveryv1.EndpointSlice{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      cg.endpointSliceName(),
-			Namespace: cg.ControllerNamespace,
-			Annotations: map[string]string{
-				connectivityv1alpha1.DNSHostnameAnnotation:   hostname,
-				connectivityv1alpha1.GatewayDNSRefAnnotation: cg.GatewayDNSNamespacedName.String(),
-			},
+			Name:        cg.endpointSliceName(),
+			Namespace:   cg.ControllerNamespace,
+			Annotations: annotations,
 			Labels: map[string]string{
-				"kubernetes.io/service-name": cg.endpointSliceName(),
+				discoveryv1.LabelServiceName: cg.endpointSliceName(),
+				discoveryv1.LabelManagedBy:   connectivityv1alpha1.EndpointSliceManagedBy,
 			},
 		},
 		AddressType: discoveryv1.AddressTypeIPv4,
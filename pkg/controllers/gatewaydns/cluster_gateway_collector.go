@@ -6,11 +6,14 @@ package gatewaydns
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 
 	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
@@ -30,9 +33,18 @@ func (e *ClusterGatewayCollector) GetGatewaysForClusters(ctx context.Context,
 
 	gatewayDNSSpecService := newNamespacedNameFromString(gatewayDNS.Spec.Service)
 
+	policy := clusterLifecyclePolicy(gatewayDNS)
+
 	var clusterGateways []ClusterGateway
 	for _, cluster := range clusters {
-		service, err := e.getLoadBalancerServiceForCluster(ctx, gatewayDNSSpecService, cluster)
+		var service *corev1.Service
+		var err error
+		if conditionType, notReady := notReadyCondition(cluster); notReady && policy.NotReady == connectivityv1alpha1.NotReadyClusterPolicyUnreachable {
+			e.Log.Info("Treating the gateway of a Cluster that is not ready as unreachable", "Cluster", fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name), "Condition", conditionType)
+			err = fmt.Errorf("cluster condition %s is not True", conditionType)
+		} else {
+			service, err = e.getLoadBalancerServiceForCluster(ctx, gatewayDNSSpecService, cluster)
+		}
 		clusterGateway := ClusterGateway{
 			ClusterNamespacedName: types.NamespacedName{
 				Namespace: cluster.Namespace,
@@ -45,6 +57,9 @@ func (e *ClusterGatewayCollector) GetGatewaysForClusters(ctx context.Context,
 				Namespace: gatewayDNS.Namespace,
 				Name:      gatewayDNS.Name,
 			},
+			Weight: e.gatewayWeight(gatewayDNS, cluster),
+			Region: cluster.Labels[corev1.LabelTopologyRegion],
+			Zone:   cluster.Labels[corev1.LabelTopologyZone],
 		}
 		if service != nil {
 			clusterGateway.Gateway = service
@@ -92,6 +107,37 @@ func (e *ClusterGatewayCollector) getLoadBalancerServiceForCluster(ctx context.C
 	return nil, nil
 }
 
+// gatewayWeight returns the weight of the first clusterWeights entry of the
+// GatewayDNS that matches the cluster, else the weight from the gateway-weight
+// label of the cluster. It returns nil when neither sets a weight.
+func (e *ClusterGatewayCollector) gatewayWeight(gatewayDNS connectivityv1alpha1.GatewayDNS, cluster clusterv1beta1.Cluster) *int32 {
+	log := e.Log.WithValues("Cluster", fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name))
+
+	for _, clusterWeight := range gatewayDNS.Spec.ClusterWeights {
+		selector, err := metav1.LabelSelectorAsSelector(&clusterWeight.ClusterSelector)
+		if err != nil {
+			log.Error(err, "Invalid clusterWeights selector", "GatewayDNS", fmt.Sprintf("%s/%s", gatewayDNS.Namespace, gatewayDNS.Name))
+			continue
+		}
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			weight := clusterWeight.Weight
+			return &weight
+		}
+	}
+
+	label, ok := cluster.Labels[connectivityv1alpha1.GatewayWeightKey]
+	if !ok {
+		return nil
+	}
+	weight, err := strconv.ParseInt(label, 10, 32)
+	if err != nil || weight < 1 {
+		log.Error(fmt.Errorf("invalid gateway weight %q", label), "Ignoring gateway weight label")
+		return nil
+	}
+	weight32 := int32(weight)
+	return &weight32
+}
+
 func newNamespacedNameFromString(s string) types.NamespacedName {
 	namespacedName := types.NamespacedName{}
 	result := strings.Split(s, string(types.Separator))
@@ -24,6 +24,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 )
 
 var _ = Describe("Cluster Gateway Collector", func() {
@@ -180,6 +181,63 @@ var _ = Describe("Cluster Gateway Collector", func() {
 			})
 		})
 
+		Context("when the clusters have weight and topology labels", func() {
+			BeforeEach(func() {
+				err := clusterClient0.Create(context.Background(), gatewayService0)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = clusterClient1.Create(context.Background(), gatewayService1)
+				Expect(err).NotTo(HaveOccurred())
+
+				clusters[0].Labels[connectivityv1alpha1.GatewayWeightKey] = "3"
+				clusters[0].Labels[corev1.LabelTopologyRegion] = "us-east-1"
+				clusters[0].Labels[corev1.LabelTopologyZone] = "us-east-1a"
+				clusters[1].Labels[connectivityv1alpha1.GatewayWeightKey] = "not-a-number"
+			})
+
+			It("returns the weight and topology of each gateway", func() {
+				gateways := clusterGatewayCollector.GetGatewaysForClusters(
+					context.Background(),
+					*gatewayDNS,
+					clusters,
+				)
+				Expect(gateways).To(HaveLen(2))
+				Expect(gateways[0].Weight).To(PointTo(Equal(int32(3))))
+				Expect(gateways[0].Region).To(Equal("us-east-1"))
+				Expect(gateways[0].Zone).To(Equal("us-east-1a"))
+
+				Expect(gateways[1].Weight).To(BeNil())
+				Expect(gateways[1].Region).To(BeEmpty())
+				Expect(gateways[1].Zone).To(BeEmpty())
+			})
+
+			It("prefers the first matching clusterWeights entry of the GatewayDNS", func() {
+				gatewayDNS.Spec.ClusterWeights = []connectivityv1alpha1.ClusterWeight{
+					{
+						ClusterSelector: metav1.LabelSelector{
+							MatchLabels: map[string]string{corev1.LabelTopologyRegion: "us-east-1"},
+						},
+						Weight: 7,
+					},
+					{
+						ClusterSelector: metav1.LabelSelector{
+							MatchLabels: map[string]string{"some-label": "true"},
+						},
+						Weight: 2,
+					},
+				}
+
+				gateways := clusterGatewayCollector.GetGatewaysForClusters(
+					context.Background(),
+					*gatewayDNS,
+					clusters,
+				)
+				Expect(gateways).To(HaveLen(2))
+				Expect(gateways[0].Weight).To(PointTo(Equal(int32(7))))
+				Expect(gateways[1].Weight).To(PointTo(Equal(int32(2))))
+			})
+		})
+
 		Context("when searching a client for services fails", func() {
 			var fakeClusterClient *gatewaydnsfakes.FakeClient
 			BeforeEach(func() {
@@ -97,6 +97,7 @@ var _ = Describe("ClusterGateway", func() {
 		Expect(endpointSlice.Endpoints).To(HaveLen(1))
 		Expect(endpointSlice.Endpoints[0].Addresses).To(ConsistOf("1.1.0.1"))
 		Expect(endpointSlice.Labels["kubernetes.io/service-name"]).To(Equal("cluster-namespace-foo-cluster-name-foo-gateway"))
+		Expect(endpointSlice.Labels[discoveryv1.LabelManagedBy]).To(Equal(connectivityv1alpha1.EndpointSliceManagedBy))
 
 		endpointSlice = clusterGateways[1].ToEndpointSlice()
 		Expect(endpointSlice.Name).To(Equal("cluster-namespace-bar-cluster-name-bar-gateway"))
@@ -118,4 +119,34 @@ var _ = Describe("ClusterGateway", func() {
 		Expect(endpointSlice.Endpoints[0].Addresses).To(ConsistOf("1.1.0.3"))
 		Expect(endpointSlice.Labels["kubernetes.io/service-name"]).To(Equal("cluster-namespace-baz-cluster-name-baz-gateway"))
 	})
+
+	It("does not annotate the weight and topology when they are not set", func() {
+		endpointSlice := clusterGateways[0].ToEndpointSlice()
+		Expect(endpointSlice.Annotations).NotTo(HaveKey(connectivityv1alpha1.GatewayWeightKey))
+		Expect(endpointSlice.Annotations).NotTo(HaveKey(connectivityv1alpha1.GatewayRegionAnnotation))
+		Expect(endpointSlice.Annotations).NotTo(HaveKey(connectivityv1alpha1.GatewayZoneAnnotation))
+	})
+
+	It("annotates the Endpoint Slice with the weight and topology of the gateway", func() {
+		weight := int32(5)
+		clusterGateways[0].Weight = &weight
+		clusterGateways[0].Region = "us-east-1"
+		clusterGateways[0].Zone = "us-east-1a"
+
+		endpointSlice := clusterGateways[0].ToEndpointSlice()
+		Expect(endpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey]).To(Equal("5"))
+		Expect(endpointSlice.Annotations[connectivityv1alpha1.GatewayRegionAnnotation]).To(Equal("us-east-1"))
+		Expect(endpointSlice.Annotations[connectivityv1alpha1.GatewayZoneAnnotation]).To(Equal("us-east-1a"))
+	})
+
+	It("copies the view addresses of the gateway Service to the Endpoint Slice", func() {
+		clusterGateways[0].Gateway.Annotations = map[string]string{
+			connectivityv1alpha1.ViewAddressesAnnotationPrefix + "private": "10.0.0.1,10.0.0.2",
+			"some-other-annotation": "value",
+		}
+
+		endpointSlice := clusterGateways[0].ToEndpointSlice()
+		Expect(endpointSlice.Annotations[connectivityv1alpha1.ViewAddressesAnnotationPrefix+"private"]).To(Equal("10.0.0.1,10.0.0.2"))
+		Expect(endpointSlice.Annotations).NotTo(HaveKey("some-other-annotation"))
+	})
 })
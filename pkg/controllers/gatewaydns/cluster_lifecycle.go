@@ -0,0 +1,75 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+)
+
+// clusterLifecyclePolicy returns the clusterLifecycle of the GatewayDNS with
+// the defaults of its unset fields.
+func clusterLifecyclePolicy(gatewayDNS connectivityv1alpha1.GatewayDNS) connectivityv1alpha1.ClusterLifecyclePolicy {
+	policy := gatewayDNS.Spec.ClusterLifecycle
+	if policy.Deleting == "" {
+		policy.Deleting = connectivityv1alpha1.DeletingClusterPolicyWithdraw
+	}
+	if policy.Paused == "" {
+		policy.Paused = connectivityv1alpha1.PausedClusterPolicySkip
+	}
+	if policy.NotReady == "" {
+		policy.NotReady = connectivityv1alpha1.NotReadyClusterPolicyUnreachable
+	}
+	return policy
+}
+
+// IsClusterDeleting returns whether the cluster is being deleted.
+func IsClusterDeleting(cluster clusterv1beta1.Cluster) bool {
+	return !cluster.DeletionTimestamp.IsZero()
+}
+
+// IsClusterPaused returns whether the cluster is paused, with its spec or
+// the paused annotation of Cluster API.
+func IsClusterPaused(cluster clusterv1beta1.Cluster) bool {
+	if cluster.Spec.Paused {
+		return true
+	}
+	_, ok := cluster.Annotations[clusterv1beta1.PausedAnnotation]
+	return ok
+}
+
+// notReadyCondition returns the ControlPlaneReady or InfrastructureReady
+// condition of the cluster that is not True. Clusters without those
+// conditions, such as those without a control plane reference, are ready.
+func notReadyCondition(cluster clusterv1beta1.Cluster) (clusterv1beta1.ConditionType, bool) {
+	for _, conditionType := range []clusterv1beta1.ConditionType{
+		clusterv1beta1.ControlPlaneReadyCondition,
+		clusterv1beta1.InfrastructureReadyCondition,
+	} {
+		for _, condition := range cluster.Status.Conditions {
+			if condition.Type == conditionType && condition.Status != corev1.ConditionTrue {
+				return conditionType, true
+			}
+		}
+	}
+	return "", false
+}
+
+// skipWritesReason returns why the policy does not let the EndpointSlices of
+// the cluster be written, or an empty string when it does.
+func skipWritesReason(policy connectivityv1alpha1.ClusterLifecyclePolicy, cluster clusterv1beta1.Cluster) string {
+	if policy.Paused == connectivityv1alpha1.PausedClusterPolicySkip && IsClusterPaused(cluster) {
+		return "Cluster is paused"
+	}
+	if policy.NotReady == connectivityv1alpha1.NotReadyClusterPolicyUnreachable {
+		if conditionType, ok := notReadyCondition(cluster); ok {
+			return fmt.Sprintf("Cluster condition %s is not True", conditionType)
+		}
+	}
+	return ""
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 
 	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,6 +16,14 @@ import (
 
 type ClusterSearcher struct {
 	client.Client
+
+	// Inventory lists the clusters, defaults to the Cluster API Clusters.
+	Inventory inventory.Lister
+}
+
+// ListClusters lists the clusters of the inventory.
+func (cs *ClusterSearcher) ListClusters(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+	return inventory.WithDefault(cs.Inventory, cs.Client).ListClusters(ctx, opts...)
 }
 
 func (cs *ClusterSearcher) ListMatchingClusters(ctx context.Context,
@@ -25,14 +34,22 @@ func (cs *ClusterSearcher) ListMatchingClusters(ctx context.Context,
 		return nil, err
 	}
 
-	var matchingClusters clusterv1beta1.ClusterList
-	err = cs.Client.List(ctx,
-		&matchingClusters,
+	matchingClusters, err := cs.ListClusters(ctx,
 		client.MatchingLabelsSelector{Selector: selector},
 		client.InNamespace(gatewayDNS.Namespace),
 	)
 	if err != nil {
 		return nil, err
 	}
-	return matchingClusters.Items, nil
+
+	if clusterLifecyclePolicy(gatewayDNS).Deleting == connectivityv1alpha1.DeletingClusterPolicyPublish {
+		return matchingClusters, nil
+	}
+	var clusters []clusterv1beta1.Cluster
+	for _, cluster := range matchingClusters {
+		if cluster.DeletionTimestamp.IsZero() {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
 }
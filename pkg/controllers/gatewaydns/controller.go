@@ -7,10 +7,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -19,6 +22,7 @@ import (
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -37,10 +41,19 @@ type GatewayDNSReconciler struct {
 	EndpointSliceReconciler *EndpointSliceReconciler
 	ClusterGatewayCollector *ClusterGatewayCollector
 
+	// WatchRegisteredClusters reconciles the GatewayDNS matching a
+	// RegisteredCluster when it changes. The RegisteredCluster CRD must be
+	// installed.
+	WatchRegisteredClusters bool
+
 	// PollingInterval defaults to 30 seconds if not provided
 	PollingInterval time.Duration
 }
 
+// defaultDeletionTimeout is how long the deletion of a GatewayDNS without a
+// deletionTimeout waits for its records to be removed.
+const defaultDeletionTimeout = 10 * time.Minute
+
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . clientProvider
 type clientProvider interface {
 	GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error)
@@ -48,6 +61,8 @@ type clientProvider interface {
 
 // +kubebuilder:rbac:groups=connectivity.tanzu.vmware.com,resources=gatewaydns,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=connectivity.tanzu.vmware.com,resources=gatewaydns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=connectivity.tanzu.vmware.com,resources=gatewaydns/finalizers,verbs=update
+// +kubebuilder:rbac:groups=connectivity.tanzu.vmware.com,resources=registeredclusters,verbs=get;list;watch
 
 func (r *GatewayDNSReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("GatewayDNS", req.NamespacedName)
@@ -56,7 +71,8 @@ func (r *GatewayDNSReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	var gatewayDNS connectivityv1alpha1.GatewayDNS
 	if err := r.Client.Get(ctx, req.NamespacedName, &gatewayDNS); err != nil {
 		if k8serrors.IsNotFound(err) {
-			err := r.convergeOnClustersForGatewayDNS(ctx, log, req.NamespacedName, nil)
+			gatewayDNS.Namespace, gatewayDNS.Name = req.Namespace, req.Name
+			_, err := r.convergeOnClustersForGatewayDNS(ctx, log, gatewayDNS, nil)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
@@ -67,6 +83,18 @@ func (r *GatewayDNSReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	if !gatewayDNS.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, log, gatewayDNS)
+	}
+
+	if !controllerutil.ContainsFinalizer(&gatewayDNS, connectivityv1alpha1.GatewayDNSFinalizer) && !r.dryRun() {
+		controllerutil.AddFinalizer(&gatewayDNS, connectivityv1alpha1.GatewayDNSFinalizer)
+		if err := r.Client.Update(ctx, &gatewayDNS); err != nil {
+			log.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
 	log.Info("Searching for Clusters", "ClusterSelector", gatewayDNS.Spec.ClusterSelector, "Service", gatewayDNS.Spec.Service)
 	clustersWithEndpoints, err := r.ClusterSearcher.ListMatchingClusters(ctx, gatewayDNS)
 	if err != nil {
@@ -77,34 +105,177 @@ func (r *GatewayDNSReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	clusterGateways := r.ClusterGatewayCollector.GetGatewaysForClusters(ctx, gatewayDNS, clustersWithEndpoints)
 
-	err = r.convergeOnClustersForGatewayDNS(ctx, log, req.NamespacedName, clusterGateways)
+	clusterGateways, statusChanged := withdrawStaleGateways(log, &gatewayDNS, clusterGateways, time.Now())
+
+	notInstalled, convergeErr := r.convergeOnClustersForGatewayDNS(ctx, log, gatewayDNS, clusterGateways)
+	if setConsumersNotInstalled(&gatewayDNS, notInstalled) {
+		statusChanged = true
+	}
+	if statusChanged && r.dryRun() {
+		log.Info("Would update status", "Status", gatewayDNS.Status)
+	} else if statusChanged {
+		if err := r.Client.Status().Update(ctx, &gatewayDNS); err != nil {
+			log.Error(err, "Failed to update status")
+			return ctrl.Result{}, err
+		}
+	}
+	if convergeErr != nil {
+		return ctrl.Result{}, convergeErr
+	}
+	log.Info("Finished Reconciling")
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileDelete removes the records of the GatewayDNS from every cluster in
+// its namespace. The finalizer is removed once all of them confirmed the
+// removal, or once the deletionTimeout of the GatewayDNS expired; until then
+// the clusters that did not are reported in its status. Clusters being
+// deleted are not waited for, nor are the clusters whose writes the
+// lifecycle policy skips, which are reported on their own; their records are
+// left to the orphan collection.
+func (r *GatewayDNSReconciler) reconcileDelete(ctx context.Context, log logr.Logger, gatewayDNS connectivityv1alpha1.GatewayDNS) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(&gatewayDNS, connectivityv1alpha1.GatewayDNSFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	namespacedName := types.NamespacedName{Namespace: gatewayDNS.Namespace, Name: gatewayDNS.Name}
+	clustersInGatewayDNSNamespace, err := r.ClusterSearcher.ListClusters(ctx, client.InNamespace(gatewayDNS.Namespace))
 	if err != nil {
+		log.Error(err, "Failed to list clusters in gateway dns namespace")
 		return ctrl.Result{}, err
 	}
-	log.Info("Finished Reconciling")
 
+	policy := clusterLifecyclePolicy(gatewayDNS)
+	var pendingClusters, skippedClusters []string
+	removingClusters := 0
+	for _, cluster := range clustersInGatewayDNSNamespace {
+		clusterName := fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name)
+		if IsClusterDeleting(cluster) {
+			log.Info("Not removing the records from Cluster", "Cluster", clusterName, "Reason", "Cluster is being deleted")
+			continue
+		}
+		if reason := skipWritesReason(policy, cluster); reason != "" {
+			log.Info("Not removing the records from Cluster", "Cluster", clusterName, "Reason", reason)
+			skippedClusters = append(skippedClusters, clusterName)
+			continue
+		}
+		removingClusters++
+		// A cluster without the dns-server has no records to remove.
+		_, errs := r.EndpointSliceReconciler.ConvergeToClusters(ctx, []clusterv1beta1.Cluster{cluster}, namespacedName, nil)
+		if len(errs) > 0 {
+			pendingClusters = append(pendingClusters, clusterName)
+		}
+	}
+
+	if r.dryRun() {
+		// The records were only planned, the finalizer stays so that the
+		// controller removes them once it writes.
+		log.Info("Would remove the finalizer once the records are removed", "PendingClusters", pendingClusters, "SkippedClusters", skippedClusters)
+		return ctrl.Result{}, nil
+	}
+
+	if len(pendingClusters) > 0 {
+		deletionTimeout := defaultDeletionTimeout
+		if gatewayDNS.Spec.DeletionTimeout != nil {
+			deletionTimeout = gatewayDNS.Spec.DeletionTimeout.Duration
+		}
+		remaining := deletionTimeout - time.Since(gatewayDNS.DeletionTimestamp.Time)
+		if remaining > 0 {
+			log.Info("Waiting for clusters to confirm the removal of the records", "Clusters", pendingClusters)
+			gatewayDNS.Status.PendingRemovalClusters = pendingClusters
+			gatewayDNS.Status.SkippedRemovalClusters = skippedClusters
+			meta.SetStatusCondition(&gatewayDNS.Status.Conditions, metav1.Condition{
+				Type:               connectivityv1alpha1.RecordsRemovedCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             "WaitingForClusters",
+				Message:            fmt.Sprintf("%d of %d clusters have not confirmed the removal of the records", len(pendingClusters), removingClusters),
+				ObservedGeneration: gatewayDNS.Generation,
+			})
+			if err := r.Client.Status().Update(ctx, &gatewayDNS); err != nil {
+				log.Error(err, "Failed to update status")
+				return ctrl.Result{}, err
+			}
+			requeueAfter := r.pollingInterval()
+			if remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+		log.Error(errors.New("deletion timeout expired"), "Giving up on removing the records from clusters", "Clusters", pendingClusters, "DeletionTimeout", deletionTimeout)
+	}
+	if len(skippedClusters) > 0 {
+		log.Info("Leaving the records on skipped clusters to the orphan collection", "Clusters", skippedClusters)
+	}
+
+	controllerutil.RemoveFinalizer(&gatewayDNS, connectivityv1alpha1.GatewayDNSFinalizer)
+	if err := r.Client.Update(ctx, &gatewayDNS); err != nil {
+		log.Error(err, "Failed to remove finalizer")
+		return ctrl.Result{}, err
+	}
+	log.Info("Finished Reconciling")
 	return ctrl.Result{}, nil
 }
 
-func (r *GatewayDNSReconciler) convergeOnClustersForGatewayDNS(ctx context.Context, log logr.Logger, namespacedName types.NamespacedName, clusterGateways []ClusterGateway) error {
-	var clustersInGatewayDNSNamespace clusterv1beta1.ClusterList
-	err := r.Client.List(ctx, &clustersInGatewayDNSNamespace, client.InNamespace(namespacedName.Namespace))
+// convergeOnClustersForGatewayDNS converges the records of the GatewayDNS on
+// every cluster in its namespace, and returns the namespace/name of those
+// without the dns-server installed.
+func (r *GatewayDNSReconciler) convergeOnClustersForGatewayDNS(ctx context.Context, log logr.Logger, gatewayDNS connectivityv1alpha1.GatewayDNS, clusterGateways []ClusterGateway) ([]string, error) {
+	namespacedName := types.NamespacedName{Namespace: gatewayDNS.Namespace, Name: gatewayDNS.Name}
+	clustersInGatewayDNSNamespace, err := r.ClusterSearcher.ListClusters(ctx, client.InNamespace(namespacedName.Namespace))
 	if err != nil {
 		log.Error(err, "Failed to list clusters in gateway dns namespace")
-		return err
+		return nil, err
+	}
+
+	policy := clusterLifecyclePolicy(gatewayDNS)
+	var clusters []clusterv1beta1.Cluster
+	for _, cluster := range clustersInGatewayDNSNamespace {
+		if reason := skipWritesReason(policy, cluster); reason != "" {
+			log.Info("Skipping Cluster", "Cluster", fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name), "Reason", reason)
+			continue
+		}
+		clusters = append(clusters, cluster)
 	}
 
-	errs := r.EndpointSliceReconciler.ConvergeToClusters(ctx, clustersInGatewayDNSNamespace.Items, namespacedName, clusterGateways)
+	notInstalledClusters, errs := r.EndpointSliceReconciler.ConvergeToClusters(ctx, clusters, namespacedName, clusterGateways)
+	var notInstalled []string
+	for _, cluster := range notInstalledClusters {
+		notInstalled = append(notInstalled, cluster.String())
+	}
 	if len(errs) > 0 {
-		return errors.New("Failed to converge EndpointSlices")
+		return notInstalled, errors.New("Failed to converge EndpointSlices")
 	}
 
-	return nil
+	return notInstalled, nil
+}
+
+// setConsumersNotInstalled reports in the status of the GatewayDNS the
+// clusters its records are not published to because the dns-server is not
+// installed on them. It returns whether the status changed.
+func setConsumersNotInstalled(gatewayDNS *connectivityv1alpha1.GatewayDNS, notInstalled []string) bool {
+	previous := gatewayDNS.Status.DeepCopy()
+
+	gatewayDNS.Status.ConsumersNotInstalled = notInstalled
+	condition := metav1.Condition{
+		Type:               connectivityv1alpha1.ConsumersInstalledCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Installed",
+		ObservedGeneration: gatewayDNS.Generation,
+	}
+	if len(notInstalled) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ConsumerNotInstalled"
+		condition.Message = fmt.Sprintf("The dns-server is not installed on %d clusters: %s", len(notInstalled), strings.Join(notInstalled, ", "))
+	}
+	meta.SetStatusCondition(&gatewayDNS.Status.Conditions, condition)
+
+	return !reflect.DeepEqual(previous, &gatewayDNS.Status)
 }
 
 func (r *GatewayDNSReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	pollEventsCh := r.PollGatewayDNS()
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&connectivityv1alpha1.GatewayDNS{}).
 		Watches(
 			&source.Channel{
@@ -122,18 +293,21 @@ func (r *GatewayDNSReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(
 			&source.Kind{Type: &clusterv1beta1.Cluster{}},
 			handler.EnqueueRequestsFromMapFunc(r.ClusterToGatewayDNS),
-		).
-		Complete(r)
+		)
+	if r.WatchRegisteredClusters {
+		b = b.Watches(
+			&source.Kind{Type: &connectivityv1alpha1.RegisteredCluster{}},
+			handler.EnqueueRequestsFromMapFunc(r.ClusterToGatewayDNS),
+		)
+	}
+	return b.Complete(r)
 }
 
 func (r *GatewayDNSReconciler) PollGatewayDNS() <-chan event.GenericEvent {
 	log := r.Log.WithName("Poll")
 	pollEventsCh := make(chan event.GenericEvent)
 
-	pollingInterval := r.PollingInterval
-	if pollingInterval == 0 {
-		pollingInterval = 30 * time.Second
-	}
+	pollingInterval := r.pollingInterval()
 	log.Info("Start", "PollingInterval", pollingInterval)
 
 	go func() {
@@ -158,6 +332,19 @@ func (r *GatewayDNSReconciler) PollGatewayDNS() <-chan event.GenericEvent {
 	return pollEventsCh
 }
 
+// dryRun returns whether the EndpointSliceReconciler only logs its writes, in
+// which case nothing is written to the GatewayDNS either.
+func (r *GatewayDNSReconciler) dryRun() bool {
+	return r.EndpointSliceReconciler != nil && r.EndpointSliceReconciler.DryRun
+}
+
+func (r *GatewayDNSReconciler) pollingInterval() time.Duration {
+	if r.PollingInterval == 0 {
+		return 30 * time.Second
+	}
+	return r.PollingInterval
+}
+
 func (r *GatewayDNSReconciler) ClusterToGatewayDNS(o client.Object) []reconcile.Request {
 	log := r.Log.WithName("ClusterToGatewayDNS")
 	var gatewayDNSList connectivityv1alpha1.GatewayDNSList
@@ -10,10 +10,15 @@ import (
 	"time"
 
 	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns/gatewaydnsfakes"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/internal/applytest"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -60,9 +65,9 @@ var _ = Describe("Controller", func() {
 		_ = discoveryv1.AddToScheme(scheme)
 
 		managementClient = fake.NewClientBuilder().WithScheme(scheme).Build()
-		gatewayClusterClient = fake.NewClientBuilder().WithScheme(scheme).Build()
-		workloadClusterClient = fake.NewClientBuilder().WithScheme(scheme).Build()
-		otherNamespaceClusterClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		gatewayClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+		workloadClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+		otherNamespaceClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
 
 		clusterClients = make(map[string]client.Client)
 		clusterClients["some-namespace/some-gateway-cluster"] = gatewayClusterClient
@@ -273,6 +278,128 @@ var _ = Describe("Controller", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(endpointSliceList.Items).To(HaveLen(0))
 			})
+
+			It("adds a finalizer to the gateway dns", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+				Expect(gatewayDNS.Finalizers).To(ConsistOf(connectivityv1alpha1.GatewayDNSFinalizer))
+			})
+
+			Context("during a dry run", func() {
+				BeforeEach(func() {
+					gatewayDNSReconciler.EndpointSliceReconciler.DryRun = true
+				})
+
+				It("does not write to the gateway dns", func() {
+					var original connectivityv1alpha1.GatewayDNS
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, &original)).To(Succeed())
+
+					_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+					Expect(gatewayDNS.ResourceVersion).To(Equal(original.ResourceVersion))
+					Expect(gatewayDNS.Finalizers).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when the dns-server is not installed on a cluster", func() {
+			BeforeEach(func() {
+				Expect(workloadClusterClient.Delete(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+			})
+
+			It("reports the cluster as not installed in the status", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+				Expect(gatewayDNS.Status.ConsumersNotInstalled).To(ConsistOf("some-namespace/some-workload-cluster"))
+				condition := meta.FindStatusCondition(gatewayDNS.Status.Conditions, connectivityv1alpha1.ConsumersInstalledCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("ConsumerNotInstalled"))
+			})
+
+			It("clears the status once the dns-server is installed", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(workloadClusterClient.Create(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+				_, err = gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+				Expect(gatewayDNS.Status.ConsumersNotInstalled).To(BeEmpty())
+				Expect(meta.IsStatusConditionTrue(gatewayDNS.Status.Conditions, connectivityv1alpha1.ConsumersInstalledCondition)).To(BeTrue())
+			})
+		})
+
+		Context("when a cluster is registered with a RegisteredCluster", func() {
+			var registeredClusterClient client.Client
+
+			BeforeEach(func() {
+				Expect(managementClient.Create(context.Background(), &connectivityv1alpha1.RegisteredCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "some-registered-cluster",
+						Namespace: "some-namespace",
+						Labels: map[string]string{
+							"cluster-with-gateway": "true",
+						},
+					},
+					Spec: connectivityv1alpha1.RegisteredClusterSpec{
+						KubeconfigSecretRef: connectivityv1alpha1.SecretKeyReference{Name: "some-registered-cluster-kubeconfig"},
+					},
+				})).To(Succeed())
+
+				registeredClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+				clusterClients["some-namespace/some-registered-cluster"] = registeredClusterClient
+				Expect(registeredClusterClient.Create(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+				Expect(registeredClusterClient.Create(context.Background(), &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "some-gateway-service",
+						Namespace: "some-service-namespace",
+					},
+					Spec: corev1.ServiceSpec{
+						Type: corev1.ServiceTypeLoadBalancer,
+					},
+					Status: corev1.ServiceStatus{
+						LoadBalancer: corev1.LoadBalancerStatus{
+							Ingress: []corev1.LoadBalancerIngress{{IP: "1.2.3.6"}},
+						},
+					},
+				})).To(Succeed())
+
+				gatewayDNSReconciler.ClusterSearcher.Inventory = inventory.Inventory{
+					inventory.Registered{Client: managementClient},
+					inventory.ClusterAPI{Client: managementClient},
+				}
+			})
+
+			It("publishes its gateway and writes its endpoint slices like those of a Cluster API Cluster", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpointSlice discoveryv1.EndpointSlice
+				Expect(workloadClusterClient.Get(context.Background(), types.NamespacedName{
+					Namespace: namespace,
+					Name:      "some-namespace-some-registered-cluster-gateway",
+				}, &endpointSlice)).To(Succeed())
+				Expect(endpointSlice.ObjectMeta.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]).To(Equal("*.gateway.some-registered-cluster.some-namespace.clusters.xcc.test"))
+				Expect(endpointSlice.Endpoints[0].Addresses).To(Equal([]string{"1.2.3.6"}))
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(registeredClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(2))
+			})
 		})
 
 		Context("when a gateway dns is deleted", func() {
@@ -292,6 +419,384 @@ var _ = Describe("Controller", func() {
 				err = workloadClusterClient.List(context.Background(), &endpointSliceList)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(endpointSliceList.Items).To(HaveLen(0))
+
+				err = managementClient.Get(context.Background(), req.NamespacedName, &connectivityv1alpha1.GatewayDNS{})
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			})
+
+			Context("when a cluster cannot be reached", func() {
+				BeforeEach(func() {
+					delete(clusterClients, "some-namespace/some-workload-cluster")
+				})
+
+				It("keeps the gateway dns and reports the cluster in its status", func() {
+					result, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+					var deletingGatewayDNS connectivityv1alpha1.GatewayDNS
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, &deletingGatewayDNS)).To(Succeed())
+					Expect(deletingGatewayDNS.Finalizers).To(ContainElement(connectivityv1alpha1.GatewayDNSFinalizer))
+					Expect(deletingGatewayDNS.Status.PendingRemovalClusters).To(Equal([]string{"some-namespace/some-workload-cluster"}))
+					condition := meta.FindStatusCondition(deletingGatewayDNS.Status.Conditions, connectivityv1alpha1.RecordsRemovedCondition)
+					Expect(condition).NotTo(BeNil())
+					Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+					Expect(condition.Message).To(Equal("1 of 2 clusters have not confirmed the removal of the records"))
+
+					var endpointSliceList discoveryv1.EndpointSliceList
+					Expect(gatewayClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+					Expect(endpointSliceList.Items).To(HaveLen(0))
+				})
+
+				Context("when another cluster is paused", func() {
+					BeforeEach(func() {
+						Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: gatewayCluster.Namespace, Name: gatewayCluster.Name}, gatewayCluster)).To(Succeed())
+						gatewayCluster.Spec.Paused = true
+						Expect(managementClient.Update(context.Background(), gatewayCluster)).To(Succeed())
+					})
+
+					It("reports it as skipped instead of waiting for it", func() {
+						_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+						Expect(err).NotTo(HaveOccurred())
+
+						var deletingGatewayDNS connectivityv1alpha1.GatewayDNS
+						Expect(managementClient.Get(context.Background(), req.NamespacedName, &deletingGatewayDNS)).To(Succeed())
+						Expect(deletingGatewayDNS.Status.PendingRemovalClusters).To(Equal([]string{"some-namespace/some-workload-cluster"}))
+						Expect(deletingGatewayDNS.Status.SkippedRemovalClusters).To(Equal([]string{"some-namespace/some-gateway-cluster"}))
+						condition := meta.FindStatusCondition(deletingGatewayDNS.Status.Conditions, connectivityv1alpha1.RecordsRemovedCondition)
+						Expect(condition).NotTo(BeNil())
+						Expect(condition.Message).To(Equal("1 of 1 clusters have not confirmed the removal of the records"))
+					})
+				})
+
+				It("finishes the deletion once the cluster confirmed the removal", func() {
+					_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					clusterClients["some-namespace/some-workload-cluster"] = workloadClusterClient
+					_, err = gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					var endpointSliceList discoveryv1.EndpointSliceList
+					Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+					Expect(endpointSliceList.Items).To(HaveLen(0))
+
+					err = managementClient.Get(context.Background(), req.NamespacedName, &connectivityv1alpha1.GatewayDNS{})
+					Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+				})
+			})
+		})
+
+		Context("when a gateway dns is deleted while bootstrap is enabled", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(workloadClusterClient.Delete(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+				gatewayDNSReconciler.EndpointSliceReconciler.Bootstrap = &consumer.Installer{
+					Options: consumer.Options{
+						Namespace:    namespace,
+						DomainSuffix: "xcc.test",
+					},
+					FieldManager: gatewaydns.FieldManager,
+				}
+				Expect(managementClient.Delete(context.Background(), gatewayDNS)).To(Succeed())
+			})
+
+			It("does not install the dns-server on the clusters without it", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = managementClient.Get(context.Background(), req.NamespacedName, &connectivityv1alpha1.GatewayDNS{})
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+
+				var namespaceObject corev1.Namespace
+				err = workloadClusterClient.Get(context.Background(), types.NamespacedName{Name: namespace}, &namespaceObject)
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+				Expect(workloadClusterClient.(*applytest.Client).Applies).To(BeEmpty())
+
+				_, err = gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(workloadClusterClient.(*applytest.Client).Applies).To(BeEmpty())
+			})
+		})
+
+		Context("when a gateway dns is deleted during a dry run", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				gatewayDNSReconciler.EndpointSliceReconciler.DryRun = true
+				Expect(managementClient.Delete(context.Background(), gatewayDNS)).To(Succeed())
+			})
+
+			It("keeps the records, the finalizer and the status", func() {
+				var deletingGatewayDNS connectivityv1alpha1.GatewayDNS
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, &deletingGatewayDNS)).To(Succeed())
+
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(1))
+
+				var dryRunGatewayDNS connectivityv1alpha1.GatewayDNS
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, &dryRunGatewayDNS)).To(Succeed())
+				Expect(dryRunGatewayDNS.Finalizers).To(ContainElement(connectivityv1alpha1.GatewayDNSFinalizer))
+				Expect(dryRunGatewayDNS.ResourceVersion).To(Equal(deletingGatewayDNS.ResourceVersion))
+			})
+		})
+
+		Context("when a gateway dns is deleted while a cluster is paused", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: workloadCluster.Namespace, Name: workloadCluster.Name}, workloadCluster)).To(Succeed())
+				workloadCluster.Spec.Paused = true
+				Expect(managementClient.Update(context.Background(), workloadCluster)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), gatewayDNS)).To(Succeed())
+			})
+
+			It("does not wait for it and leaves its records", func() {
+				result, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeZero())
+
+				err = managementClient.Get(context.Background(), req.NamespacedName, &connectivityv1alpha1.GatewayDNS{})
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(1))
+			})
+		})
+
+		Context("when a gateway dns is deleted while a cluster is being deleted", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: workloadCluster.Namespace, Name: workloadCluster.Name}, workloadCluster)).To(Succeed())
+				workloadCluster.Finalizers = []string{clusterv1beta1.ClusterFinalizer}
+				Expect(managementClient.Update(context.Background(), workloadCluster)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), workloadCluster)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), gatewayDNS)).To(Succeed())
+
+				delete(clusterClients, "some-namespace/some-workload-cluster")
+			})
+
+			It("does not wait for it", func() {
+				result, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeZero())
+
+				err = managementClient.Get(context.Background(), req.NamespacedName, &connectivityv1alpha1.GatewayDNS{})
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("when a gateway dns is deleted and its deletion timeout expired", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+				gatewayDNS.Spec.DeletionTimeout = &metav1.Duration{Duration: 0}
+				Expect(managementClient.Update(context.Background(), gatewayDNS)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), gatewayDNS)).To(Succeed())
+
+				delete(clusterClients, "some-namespace/some-workload-cluster")
+			})
+
+			It("gives up on the clusters that cannot be reached", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = managementClient.Get(context.Background(), req.NamespacedName, &connectivityv1alpha1.GatewayDNS{})
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(1))
+			})
+		})
+
+		Context("when a gateway cluster is being deleted", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: gatewayCluster.Namespace, Name: gatewayCluster.Name}, gatewayCluster)).To(Succeed())
+				gatewayCluster.Finalizers = []string{clusterv1beta1.ClusterFinalizer}
+				Expect(managementClient.Update(context.Background(), gatewayCluster)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), gatewayCluster)).To(Succeed())
+			})
+
+			It("stops publishing its gateway", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(0))
+			})
+
+			Context("when the gateway dns publishes the gateways of deleting clusters", func() {
+				BeforeEach(func() {
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+					gatewayDNS.Spec.ClusterLifecycle.Deleting = connectivityv1alpha1.DeletingClusterPolicyPublish
+					Expect(managementClient.Update(context.Background(), gatewayDNS)).To(Succeed())
+				})
+
+				It("keeps publishing its gateway", func() {
+					_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					var endpointSliceList discoveryv1.EndpointSliceList
+					Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+					Expect(endpointSliceList.Items).To(HaveLen(1))
+				})
+			})
+		})
+
+		Context("when a cluster is paused", func() {
+			BeforeEach(func() {
+				workloadCluster.Spec.Paused = true
+				Expect(managementClient.Update(context.Background(), workloadCluster)).To(Succeed())
+			})
+
+			It("does not write its endpoint slices", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(0))
+				Expect(gatewayClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(1))
+			})
+
+			Context("when the gateway dns converges paused clusters", func() {
+				BeforeEach(func() {
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+					gatewayDNS.Spec.ClusterLifecycle.Paused = connectivityv1alpha1.PausedClusterPolicyConverge
+					Expect(managementClient.Update(context.Background(), gatewayDNS)).To(Succeed())
+				})
+
+				It("writes its endpoint slices", func() {
+					_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					var endpointSliceList discoveryv1.EndpointSliceList
+					Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+					Expect(endpointSliceList.Items).To(HaveLen(1))
+				})
+			})
+		})
+
+		Context("when a gateway cluster is not ready", func() {
+			BeforeEach(func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: gatewayCluster.Namespace, Name: gatewayCluster.Name}, gatewayCluster)).To(Succeed())
+				gatewayCluster.Status.Conditions = clusterv1beta1.Conditions{
+					{Type: clusterv1beta1.InfrastructureReadyCondition, Status: corev1.ConditionTrue},
+					{Type: clusterv1beta1.ControlPlaneReadyCondition, Status: corev1.ConditionFalse},
+				}
+				Expect(managementClient.Update(context.Background(), gatewayCluster)).To(Succeed())
+
+				clientProvider.GetClientCalls(func(ctx context.Context, namespacedName types.NamespacedName) (client.Client, error) {
+					if namespacedName.Name == gatewayCluster.Name {
+						return nil, errors.New("not expected to query a cluster that is not ready")
+					}
+					return clusterClients[namespacedName.String()], nil
+				})
+			})
+
+			It("treats its gateway as unreachable and keeps its records without querying it", func() {
+				callsBefore := clientProvider.GetClientCallCount()
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(clientProvider.GetClientCallCount()).To(BeNumerically(">", callsBefore))
+				for i := callsBefore; i < clientProvider.GetClientCallCount(); i++ {
+					_, namespacedName := clientProvider.GetClientArgsForCall(i)
+					Expect(namespacedName.Name).NotTo(Equal(gatewayCluster.Name))
+				}
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(HaveLen(1))
+				Expect(endpointSliceList.Items[0].Endpoints[0].Addresses).To(Equal([]string{"1.2.3.4"}))
+			})
+
+			It("records since when the gateway is unreachable in the status", func() {
+				_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+				Expect(gatewayDNS.Status.UnreachableGateways).To(HaveLen(1))
+				unreachableGateway := gatewayDNS.Status.UnreachableGateways[0]
+				Expect(unreachableGateway.Cluster).To(Equal("some-namespace/some-gateway-cluster"))
+				Expect(unreachableGateway.Since.Time).To(BeTemporally("~", time.Now(), 2*time.Second))
+				Expect(unreachableGateway.Withdrawn).To(BeFalse())
+
+				_, err = gatewayDNSReconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+				Expect(gatewayDNS.Status.UnreachableGateways).To(Equal([]connectivityv1alpha1.UnreachableGateway{unreachableGateway}))
+			})
+
+			Context("when the gateway has been unreachable for longer than the stale record ttl", func() {
+				BeforeEach(func() {
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+					gatewayDNS.Spec.StaleRecordTTL = &metav1.Duration{Duration: time.Hour}
+					gatewayDNS.Status.UnreachableGateways = []connectivityv1alpha1.UnreachableGateway{{
+						Cluster: "some-namespace/some-gateway-cluster",
+						Since:   metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+					}}
+					Expect(managementClient.Update(context.Background(), gatewayDNS)).To(Succeed())
+				})
+
+				It("withdraws its records", func() {
+					_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					var endpointSliceList discoveryv1.EndpointSliceList
+					Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+					Expect(endpointSliceList.Items).To(HaveLen(0))
+
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+					Expect(gatewayDNS.Status.UnreachableGateways).To(HaveLen(1))
+					Expect(gatewayDNS.Status.UnreachableGateways[0].Withdrawn).To(BeTrue())
+				})
+
+				It("publishes them again once the gateway is reachable", func() {
+					_, err := gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: gatewayCluster.Namespace, Name: gatewayCluster.Name}, gatewayCluster)).To(Succeed())
+					gatewayCluster.Status.Conditions = nil
+					Expect(managementClient.Update(context.Background(), gatewayCluster)).To(Succeed())
+					clientProvider.GetClientCalls(func(ctx context.Context, namespacedName types.NamespacedName) (client.Client, error) {
+						return clusterClients[namespacedName.String()], nil
+					})
+
+					_, err = gatewayDNSReconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+
+					var endpointSliceList discoveryv1.EndpointSliceList
+					Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+					Expect(endpointSliceList.Items).To(HaveLen(1))
+
+					Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
+					Expect(gatewayDNS.Status.UnreachableGateways).To(BeEmpty())
+				})
 			})
 		})
 
@@ -315,7 +820,7 @@ var _ = Describe("Controller", func() {
 				err := managementClient.Create(context.Background(), anotherGatewayCluster)
 				Expect(err).NotTo(HaveOccurred())
 
-				anotherGatewayClusterClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+				anotherGatewayClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
 				clusterClients["some-namespace/another-gateway-cluster"] = anotherGatewayClusterClient
 
 				corev1Namespace := corev1.Namespace{
@@ -351,6 +856,7 @@ var _ = Describe("Controller", func() {
 				_, err = gatewayDNSReconciler.Reconcile(context.Background(), req)
 				Expect(err).NotTo(HaveOccurred())
 
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
 				gatewayDNS.Spec.ClusterSelector.MatchLabels = map[string]string{
 					"a-different-gateway-label": "true",
 				}
@@ -426,6 +932,7 @@ var _ = Describe("Controller", func() {
 				err = gatewayClusterClient.Create(context.Background(), service)
 				Expect(err).NotTo(HaveOccurred())
 
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, gatewayDNS)).To(Succeed())
 				gatewayDNS.Spec.Service = "some-service-namespace/a-different-gateway-service"
 
 				err = managementClient.Update(context.Background(), gatewayDNS)
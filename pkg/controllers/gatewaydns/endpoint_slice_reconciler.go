@@ -7,27 +7,53 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/go-logr/logr"
 	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// FieldManager is the field manager of the objects that xcc-dns-controller
+// applies to workload clusters: the EndpointSlices and the installations of
+// the dns-server.
+const FieldManager = "xcc-dns-controller"
+
 type EndpointSliceReconciler struct {
 	ClientProvider clientProvider
 	Namespace      string
 	Log            logr.Logger
+
+	// DryRun logs the EndpointSlices that would be created, updated and
+	// deleted, and records their number in the
+	// xcc_dns_controller_planned_endpointslice_changes metric, instead of
+	// writing them.
+	DryRun bool
+
+	// Bootstrap installs the dns-server on the clusters without Namespace
+	// before converging them, when there are records to publish. Without it,
+	// or when the records are removed, those clusters are skipped and
+	// reported as not installed.
+	Bootstrap *consumer.Installer
 }
 
+// ConvergeToClusters converges the EndpointSlices of the GatewayDNS on each
+// cluster to the desired gateways. It returns the clusters without the
+// dns-server, whose EndpointSlices are not written, and the errors of the
+// clusters that failed to converge.
 func (e *EndpointSliceReconciler) ConvergeToClusters(ctx context.Context,
-	clusters []clusterv1beta1.Cluster, gatewayDNSNamespacedName types.NamespacedName, desiredClusterGateways []ClusterGateway) []error {
+	clusters []clusterv1beta1.Cluster, gatewayDNSNamespacedName types.NamespacedName, desiredClusterGateways []ClusterGateway) ([]types.NamespacedName, []error) {
+	var notInstalled []types.NamespacedName
 	var errors []error
 
 	for _, cluster := range clusters {
@@ -42,19 +68,20 @@ func (e *EndpointSliceReconciler) ConvergeToClusters(ctx context.Context,
 			continue
 		}
 
-		var namespace corev1.Namespace
-		err = clusterClient.Get(ctx, client.ObjectKey{Name: e.Namespace}, &namespace)
+		installed, err := e.ensureConsumerInstalled(ctx, log, clusterClient, len(desiredClusterGateways) > 0)
 		if err != nil {
-			if k8serrors.IsNotFound(err) {
-				continue
-			} else {
-				log.Error(err, "Failed to get namespace")
-				errors = append(errors, err)
-				continue
-			}
+			errors = append(errors, err)
+			continue
+		}
+		if !installed {
+			notInstalled = append(notInstalled, types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name})
+			continue
 		}
 
-		err = e.convergeCluster(ctx, log, gatewayDNSNamespacedName, clusterClient, desiredClusterGateways)
+		err = e.convergeCluster(ctx, log, gatewayDNSNamespacedName, types.NamespacedName{
+			Namespace: cluster.Namespace,
+			Name:      cluster.Name,
+		}, clusterClient, desiredClusterGateways)
 		if err != nil {
 			log.Error(err, "Failed to converge EndpointSlices")
 			errors = append(errors, err)
@@ -62,50 +89,80 @@ func (e *EndpointSliceReconciler) ConvergeToClusters(ctx context.Context,
 		}
 	}
 
-	return errors
+	return notInstalled, errors
+}
+
+// ensureConsumerInstalled returns whether the dns-server is installed on the
+// cluster, that is whether Namespace exists. When Bootstrap is set and there
+// are records to publish, it is installed first: a cluster without it has no
+// records to remove.
+func (e *EndpointSliceReconciler) ensureConsumerInstalled(ctx context.Context, log logr.Logger, clusterClient client.Client, publishing bool) (bool, error) {
+	var namespace corev1.Namespace
+	err := clusterClient.Get(ctx, client.ObjectKey{Name: e.Namespace}, &namespace)
+	if err == nil {
+		return true, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		log.Error(err, "Failed to get namespace")
+		return false, err
+	}
+
+	if e.Bootstrap == nil || !publishing {
+		log.Info("Skipping Cluster without the dns-server installed", "Namespace", e.Namespace)
+		return false, nil
+	}
+	if e.DryRun {
+		log.Info("Would install the dns-server", "Namespace", e.Namespace)
+		return false, nil
+	}
+	if err := e.Bootstrap.Install(ctx, clusterClient); err != nil {
+		log.Error(err, "Failed to install the dns-server")
+		return false, err
+	}
+	log.Info("Installed the dns-server", "Namespace", e.Namespace)
+	return true, nil
 }
 
-func (e *EndpointSliceReconciler) convergeCluster(ctx context.Context, log logr.Logger, gatewayDNSNamespacedName types.NamespacedName, clusterClient client.Client, desiredClusterGateways []ClusterGateway) error {
+func (e *EndpointSliceReconciler) convergeCluster(ctx context.Context, log logr.Logger, gatewayDNSNamespacedName types.NamespacedName, clusterNamespacedName types.NamespacedName, clusterClient client.Client, desiredClusterGateways []ClusterGateway) error {
 	clusterDiff, err := e.diffCluster(ctx, log, gatewayDNSNamespacedName, clusterClient, desiredClusterGateways)
 	if err != nil {
 		return err
 	}
 
-	for _, endpointSlice := range clusterDiff.missing {
-		err = clusterClient.Create(ctx, &endpointSlice)
+	if e.DryRun {
+		recordPlan(log, gatewayDNSNamespacedName, clusterNamespacedName, clusterDiff)
+		return nil
+	}
+
+	for _, endpointSlice := range clusterDiff.Missing {
+		// An EndpointSlice with the same name that xcc-dns-controller does
+		// not manage belongs to someone else: apply without forcing so that
+		// the conflict is reported rather than taking it over.
+		err = apply(ctx, clusterClient, endpointSlice, false)
 		if err != nil {
-			if k8serrors.IsAlreadyExists(err) {
-				var existingEndpointSlice discoveryv1.EndpointSlice
-				namespacedName := types.NamespacedName{
-					Namespace: endpointSlice.Namespace,
-					Name:      endpointSlice.Name,
-				}
-				err = clusterClient.Get(ctx, namespacedName, &existingEndpointSlice)
-				if err != nil {
-					return err
-				}
-				existingEndpointSlice = merge(endpointSlice, existingEndpointSlice)
-				err = clusterClient.Update(ctx, &existingEndpointSlice)
-				if err != nil {
-					return err
-				}
-				log.Info("Updated EndpointSlice", "EndpointSlice", fmt.Sprintf("%s/%s", endpointSlice.Namespace, endpointSlice.Name), "Hostname", endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation], "Addresses", flattenEndpoints(endpointSlice.Endpoints))
-				continue
+			if k8serrors.IsConflict(err) {
+				return fmt.Errorf("EndpointSlice %s is not managed by %s: %w", EndpointSliceKey(endpointSlice), FieldManager, err)
 			}
 			return err
 		}
-		log.Info("Created EndpointSlice", "EndpointSlice", fmt.Sprintf("%s/%s", endpointSlice.Namespace, endpointSlice.Name), "Hostname", endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation], "Addresses", flattenEndpoints(endpointSlice.Endpoints))
+		log.Info("Applied EndpointSlice", "EndpointSlice", fmt.Sprintf("%s/%s", endpointSlice.Namespace, endpointSlice.Name), "Hostname", endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation], "Addresses", flattenEndpoints(endpointSlice.Endpoints))
 	}
 
-	for _, endpointSlice := range clusterDiff.changed {
-		err = clusterClient.Update(ctx, &endpointSlice)
+	for i, endpointSlice := range clusterDiff.Changed {
+		err = removeStaleGatewayAttributes(ctx, clusterClient, clusterDiff.ChangedFrom[i], endpointSlice)
+		if err != nil {
+			return err
+		}
+		// The EndpointSlice is managed by xcc-dns-controller, the fields it
+		// applies are its own even if another writer changed them.
+		err = apply(ctx, clusterClient, endpointSlice, true)
 		if err != nil {
 			return err
 		}
 		log.Info("Updated EndpointSlice", "EndpointSlice", fmt.Sprintf("%s/%s", endpointSlice.Namespace, endpointSlice.Name), "Hostname", endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation], "Addresses", flattenEndpoints(endpointSlice.Endpoints))
 	}
 
-	for _, endpointSlice := range clusterDiff.undesired {
+	for _, endpointSlice := range clusterDiff.Undesired {
 		err = clusterClient.Delete(ctx, &endpointSlice)
 		if err != nil {
 			return err
@@ -116,10 +173,140 @@ func (e *EndpointSliceReconciler) convergeCluster(ctx context.Context, log logr.
 	return nil
 }
 
+// apply server-side applies the fields of the EndpointSlice that
+// xcc-dns-controller owns, as FieldManager. Labels and annotations written by
+// other controllers are left alone.
+func apply(ctx context.Context, clusterClient client.Client, endpointSlice discoveryv1.EndpointSlice, force bool) error {
+	options := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if force {
+		options = append(options, client.ForceOwnership)
+	}
+	return clusterClient.Patch(ctx, applyConfiguration(endpointSlice), client.Apply, options...)
+}
+
+// applyConfiguration returns the fields of the EndpointSlice that
+// xcc-dns-controller owns.
+func applyConfiguration(endpointSlice discoveryv1.EndpointSlice) *discoveryv1.EndpointSlice {
+	annotations := gatewayAttributes(endpointSlice)
+	annotations[connectivityv1alpha1.DNSHostnameAnnotation] = endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]
+	annotations[connectivityv1alpha1.GatewayDNSRefAnnotation] = endpointSlice.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation]
+	annotations[connectivityv1alpha1.ClusterRefAnnotation] = endpointSlice.Annotations[connectivityv1alpha1.ClusterRefAnnotation]
+
+	return &discoveryv1.EndpointSlice{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: discoveryv1.SchemeGroupVersion.String(),
+			Kind:       "EndpointSlice",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      endpointSlice.Name,
+			Namespace: endpointSlice.Namespace,
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: endpointSlice.Labels[discoveryv1.LabelServiceName],
+				discoveryv1.LabelManagedBy:   connectivityv1alpha1.EndpointSliceManagedBy,
+			},
+			Annotations: annotations,
+		},
+		AddressType: endpointSlice.AddressType,
+		Endpoints:   endpointSlice.Endpoints,
+		Ports:       endpointSlice.Ports,
+	}
+}
+
+// removeStaleGatewayAttributes deletes the gateway attribute annotations of
+// the existing EndpointSlice that are no longer desired. Server-side apply
+// only removes the fields the field manager applied before, which does not
+// cover EndpointSlices written with Create and Update by earlier versions.
+func removeStaleGatewayAttributes(ctx context.Context, clusterClient client.Client, existing, desired discoveryv1.EndpointSlice) error {
+	desiredAttributes := gatewayAttributes(desired)
+	updated := existing.DeepCopy()
+	for key := range gatewayAttributes(existing) {
+		if _, ok := desiredAttributes[key]; !ok {
+			delete(updated.Annotations, key)
+		}
+	}
+	if len(updated.Annotations) == len(existing.Annotations) {
+		return nil
+	}
+	return clusterClient.Patch(ctx, updated, client.MergeFrom(&existing))
+}
+
+// isManagedEndpointSlice returns true for the EndpointSlices published by
+// xcc-dns-controller. Those published before the managed-by label was set are
+// recognized by their annotations.
+func isManagedEndpointSlice(endpointSlice discoveryv1.EndpointSlice) bool {
+	if managedBy, ok := endpointSlice.Labels[discoveryv1.LabelManagedBy]; ok {
+		return managedBy == connectivityv1alpha1.EndpointSliceManagedBy
+	}
+	_, hasHostname := endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]
+	_, hasGatewayDNSRef := endpointSlice.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation]
+	return hasHostname && hasGatewayDNSRef
+}
+
+// recordPlan logs the changes of the diff and sets their number in the
+// plannedEndpointSliceChanges metric.
+func recordPlan(log logr.Logger, gatewayDNSNamespacedName, clusterNamespacedName types.NamespacedName, clusterDiff ClusterDiff) {
+	for _, change := range clusterDiff.Changes() {
+		log.Info("Dry run, not applying planned EndpointSlice change", "Change", change)
+	}
+	for action, endpointSlices := range map[string][]discoveryv1.EndpointSlice{
+		"create": clusterDiff.Missing,
+		"update": clusterDiff.Changed,
+		"delete": clusterDiff.Undesired,
+	} {
+		plannedEndpointSliceChanges.WithLabelValues(gatewayDNSNamespacedName.String(), clusterNamespacedName.String(), action).Set(float64(len(endpointSlices)))
+	}
+}
+
+// ClusterDiff holds the EndpointSlices a workload cluster has to converge to
+// the desired ClusterGateways of a GatewayDNS.
 type ClusterDiff struct {
-	undesired []discoveryv1.EndpointSlice
-	missing   []discoveryv1.EndpointSlice
-	changed   []discoveryv1.EndpointSlice
+	// Undesired EndpointSlices are deleted, Missing ones created and Changed
+	// ones updated to the content they hold here.
+	Undesired []discoveryv1.EndpointSlice
+	Missing   []discoveryv1.EndpointSlice
+	Changed   []discoveryv1.EndpointSlice
+
+	// ChangedFrom holds the existing content of the Changed EndpointSlices,
+	// in the same order.
+	ChangedFrom []discoveryv1.EndpointSlice
+}
+
+// Changes describes the EndpointSlices that would be created, updated and
+// deleted, one per line, in that order.
+func (c ClusterDiff) Changes() []string {
+	var changes []string
+	for _, endpointSlice := range sortedEndpointSlices(c.Missing) {
+		changes = append(changes, fmt.Sprintf("create %s: %s", EndpointSliceKey(endpointSlice), describeEndpointSlice(endpointSlice)))
+	}
+	changed := make([]int, len(c.Changed))
+	for i := range changed {
+		changed[i] = i
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return EndpointSliceKey(c.Changed[changed[i]]) < EndpointSliceKey(c.Changed[changed[j]])
+	})
+	for _, i := range changed {
+		previous := ""
+		if i < len(c.ChangedFrom) {
+			previous = describeEndpointSlice(c.ChangedFrom[i]) + " => "
+		}
+		changes = append(changes, fmt.Sprintf("update %s: %s%s", EndpointSliceKey(c.Changed[i]), previous, describeEndpointSlice(c.Changed[i])))
+	}
+	for _, endpointSlice := range sortedEndpointSlices(c.Undesired) {
+		changes = append(changes, fmt.Sprintf("delete %s: %s", EndpointSliceKey(endpointSlice), describeEndpointSlice(endpointSlice)))
+	}
+	return changes
+}
+
+// Empty returns true when the cluster is converged.
+func (c ClusterDiff) Empty() bool {
+	return len(c.Undesired) == 0 && len(c.Missing) == 0 && len(c.Changed) == 0
+}
+
+// DiffCluster returns what convergeCluster would write to the cluster for the
+// GatewayDNS, without writing it.
+func (e *EndpointSliceReconciler) DiffCluster(ctx context.Context, gatewayDNSNamespacedName types.NamespacedName, clusterClient client.Client, desiredClusterGateways []ClusterGateway) (ClusterDiff, error) {
+	return e.diffCluster(ctx, e.Log.WithValues("GatewayDNS", gatewayDNSNamespacedName), gatewayDNSNamespacedName, clusterClient, desiredClusterGateways)
 }
 
 func (e *EndpointSliceReconciler) diffCluster(ctx context.Context,
@@ -136,12 +323,11 @@ func (e *EndpointSliceReconciler) diffCluster(ctx context.Context,
 
 	existingEndpointSliceMap := make(map[string]discoveryv1.EndpointSlice, len(existingEndpointSliceList.Items))
 	for _, existingEndpointSlice := range existingEndpointSliceList.Items {
-		if _, ok := existingEndpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]; !ok {
+		if !isManagedEndpointSlice(existingEndpointSlice) {
 			continue
 		}
 
-		existingGatewayDNSNamespacedName, ok := existingEndpointSlice.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation]
-		if !ok || existingGatewayDNSNamespacedName != gatewayDNSNamespacedName.String() {
+		if existingEndpointSlice.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation] != gatewayDNSNamespacedName.String() {
 			continue
 		}
 
@@ -161,11 +347,12 @@ func (e *EndpointSliceReconciler) diffCluster(ctx context.Context,
 		desiredEndpointSlice := desiredClusterGateway.ToEndpointSlice()
 		if existingItem, ok := existingEndpointSliceMap[desiredClusterGateway.EndpointSliceKey()]; ok {
 			if !compareEndpointSlices(desiredEndpointSlice, existingItem) {
+				clusterDiff.ChangedFrom = append(clusterDiff.ChangedFrom, *existingItem.DeepCopy())
 				existingItem = merge(desiredEndpointSlice, existingItem)
-				clusterDiff.changed = append(clusterDiff.changed, existingItem)
+				clusterDiff.Changed = append(clusterDiff.Changed, existingItem)
 			}
 		} else {
-			clusterDiff.missing = append(clusterDiff.missing, desiredEndpointSlice)
+			clusterDiff.Missing = append(clusterDiff.Missing, desiredEndpointSlice)
 		}
 	}
 
@@ -177,31 +364,97 @@ func (e *EndpointSliceReconciler) diffCluster(ctx context.Context,
 			}
 			continue
 		}
-		clusterDiff.undesired = append(clusterDiff.undesired, existingEndpointSlice)
+		clusterDiff.Undesired = append(clusterDiff.Undesired, existingEndpointSlice)
 	}
 
 	return clusterDiff, nil
 }
 
 func merge(source, dest discoveryv1.EndpointSlice) discoveryv1.EndpointSlice {
+	if dest.Labels == nil {
+		dest.Labels = map[string]string{}
+	}
+	dest.Labels[discoveryv1.LabelServiceName] = source.Labels[discoveryv1.LabelServiceName]
+	dest.Labels[discoveryv1.LabelManagedBy] = source.Labels[discoveryv1.LabelManagedBy]
 	if dest.Annotations == nil {
 		dest.Annotations = map[string]string{}
 	}
 	dest.Annotations[connectivityv1alpha1.DNSHostnameAnnotation] = source.Annotations[connectivityv1alpha1.DNSHostnameAnnotation]
 	dest.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation] = source.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation]
+	dest.Annotations[connectivityv1alpha1.ClusterRefAnnotation] = source.Annotations[connectivityv1alpha1.ClusterRefAnnotation]
+	for key := range dest.Annotations {
+		if isGatewayAttributeAnnotation(key) {
+			delete(dest.Annotations, key)
+		}
+	}
+	for key, value := range gatewayAttributes(source) {
+		dest.Annotations[key] = value
+	}
 	dest.AddressType = source.AddressType
 	dest.Endpoints = source.Endpoints
 	dest.Ports = source.Ports
 	return dest
 }
 
+// isGatewayAttributeAnnotation returns true for the optional annotations that
+// describe a gateway to the dns-server.
+func isGatewayAttributeAnnotation(key string) bool {
+	switch key {
+	case connectivityv1alpha1.GatewayWeightKey,
+		connectivityv1alpha1.GatewayRegionAnnotation,
+		connectivityv1alpha1.GatewayZoneAnnotation:
+		return true
+	}
+	return strings.HasPrefix(key, connectivityv1alpha1.ViewAddressesAnnotationPrefix)
+}
+
+func gatewayAttributes(endpointSlice discoveryv1.EndpointSlice) map[string]string {
+	attributes := map[string]string{}
+	for key, value := range endpointSlice.Annotations {
+		if isGatewayAttributeAnnotation(key) {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
+
 func compareEndpointSlices(a, b discoveryv1.EndpointSlice) bool {
-	return a.Annotations[connectivityv1alpha1.DNSHostnameAnnotation] == b.Annotations[connectivityv1alpha1.DNSHostnameAnnotation] &&
+	return reflect.DeepEqual(gatewayAttributes(a), gatewayAttributes(b)) &&
+		a.Labels[discoveryv1.LabelManagedBy] == b.Labels[discoveryv1.LabelManagedBy] &&
+		a.Annotations[connectivityv1alpha1.DNSHostnameAnnotation] == b.Annotations[connectivityv1alpha1.DNSHostnameAnnotation] &&
+		a.Annotations[connectivityv1alpha1.ClusterRefAnnotation] == b.Annotations[connectivityv1alpha1.ClusterRefAnnotation] &&
 		a.AddressType == b.AddressType &&
 		reflect.DeepEqual(a.Endpoints, b.Endpoints) &&
 		reflect.DeepEqual(a.Ports, b.Ports)
 }
 
+// describeEndpointSlice summarizes what the dns-server serves for the
+// EndpointSlice, e.g. "*.gateway.c.ns.clusters.xcc.test -> 1.2.3.4 weight=2".
+func describeEndpointSlice(endpointSlice discoveryv1.EndpointSlice) string {
+	description := fmt.Sprintf("%s -> %s",
+		endpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation],
+		strings.Join(flattenEndpoints(endpointSlice.Endpoints), ","))
+
+	attributes := gatewayAttributes(endpointSlice)
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		description += fmt.Sprintf(" %s=%s", strings.TrimPrefix(key, "connectivity.tanzu.vmware.com/"), attributes[key])
+	}
+	return description
+}
+
+func sortedEndpointSlices(endpointSlices []discoveryv1.EndpointSlice) []discoveryv1.EndpointSlice {
+	sorted := append([]discoveryv1.EndpointSlice(nil), endpointSlices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return EndpointSliceKey(sorted[i]) < EndpointSliceKey(sorted[j])
+	})
+	return sorted
+}
+
 func flattenEndpoints(endpoints []discoveryv1.Endpoint) []string {
 	var addresses []string
 	for _, endpoint := range endpoints {
@@ -8,10 +8,15 @@ import (
 	"errors"
 
 	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns/gatewaydnsfakes"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/internal/applytest"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -47,8 +52,8 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 		_ = clusterv1beta1.AddToScheme(scheme)
 		_ = discoveryv1.AddToScheme(scheme)
 
-		clusterClient0 = fake.NewClientBuilder().WithScheme(scheme).Build()
-		clusterClient1 = fake.NewClientBuilder().WithScheme(scheme).Build()
+		clusterClient0 = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+		clusterClient1 = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
 
 		clusterClients = make(map[string]client.Client)
 		clusterClients["cluster-namespace-0/cluster-name-0"] = clusterClient0
@@ -183,7 +188,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 
 	Context("when the cluster contains no previous endpoint slices", func() {
 		BeforeEach(func() {
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -218,7 +223,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient0.Create(context.Background(), &existingEndpointSlices[0])).ToNot(HaveOccurred())
 			Expect(clusterClient1.Create(context.Background(), &existingEndpointSlices[1])).ToNot(HaveOccurred())
 
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -244,7 +249,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient1.Create(context.Background(), &existingEndpointSlices[1])).ToNot(HaveOccurred())
 
 			onlyTheAnnotatedEndpointSlices := clusterGateways[0:1]
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheAnnotatedEndpointSlices)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheAnnotatedEndpointSlices)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -271,7 +276,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 
 			clusterGateways[0].Unreachable = true
 			clusterGateways[0].Gateway = nil
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -297,7 +302,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient1.Create(context.Background(), &existingEndpointSlices[1])).ToNot(HaveOccurred())
 
 			onlyTheFirstClusterGateway := clusterGateways[0:1]
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -330,7 +335,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient1.Create(context.Background(), &existingEndpointSlices[0])).ToNot(HaveOccurred())
 
 			onlyTheFirstClusterGateway := clusterGateways[0:1]
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -350,6 +355,36 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 		})
 	})
 
+	Context("when the weight, topology or views of a gateway have changed", func() {
+		BeforeEach(func() {
+			existingEndpointSlice := clusterGateways[0].ToEndpointSlice()
+			existingEndpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey] = "2"
+			existingEndpointSlice.Annotations[connectivityv1alpha1.GatewayRegionAnnotation] = "us-west-2"
+			existingEndpointSlice.Annotations[connectivityv1alpha1.ViewAddressesAnnotationPrefix+"stale"] = "10.0.0.9"
+			Expect(clusterClient0.Create(context.Background(), &existingEndpointSlice)).ToNot(HaveOccurred())
+
+			weight := int32(4)
+			clusterGateways[0].Weight = &weight
+			clusterGateways[0].Gateway.Annotations = map[string]string{
+				connectivityv1alpha1.ViewAddressesAnnotationPrefix + "private": "10.0.0.1",
+			}
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters[0:1], gatewayDNSNamespacedName, clusterGateways[0:1])
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("updates the annotations of the endpoint slice", func() {
+			var endpointSlice discoveryv1.EndpointSlice
+			Expect(clusterClient0.Get(context.Background(), types.NamespacedName{
+				Namespace: namespace,
+				Name:      "cluster-namespace-0-cluster-name-0-gateway",
+			}, &endpointSlice)).To(Succeed())
+			Expect(endpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey]).To(Equal("4"))
+			Expect(endpointSlice.Annotations).NotTo(HaveKey(connectivityv1alpha1.GatewayRegionAnnotation))
+			Expect(endpointSlice.Annotations).NotTo(HaveKey(connectivityv1alpha1.ViewAddressesAnnotationPrefix + "stale"))
+			Expect(endpointSlice.Annotations[connectivityv1alpha1.ViewAddressesAnnotationPrefix+"private"]).To(Equal("10.0.0.1"))
+		})
+	})
+
 	Context("when there are endpoint slices in other namespaces", func() {
 		BeforeEach(func() {
 			existingEndpointSlices := make([]discoveryv1.EndpointSlice, 2)
@@ -364,7 +399,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient1.Create(context.Background(), &existingEndpointSlices[1])).ToNot(HaveOccurred())
 
 			onlyTheFirstClusterGateway := clusterGateways[0:1]
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -392,7 +427,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient1.Create(context.Background(), &existingEndpointSlices[1])).ToNot(HaveOccurred())
 
 			onlyTheFirstClusterGateway := clusterGateways[0:1]
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, onlyTheFirstClusterGateway)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -423,7 +458,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			}
 			Expect(clusterClient0.Create(context.Background(), &endpointSlice)).ToNot(HaveOccurred())
 
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(BeEmpty())
 		})
 
@@ -438,18 +473,126 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 		})
 	})
 
+	Context("when an endpoint slice not managed by xcc-dns-controller has the name of a desired one", func() {
+		BeforeEach(func() {
+			endpointSlice := discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cluster-namespace-0-cluster-name-0-gateway",
+					Namespace: namespace,
+					Labels: map[string]string{
+						discoveryv1.LabelManagedBy: "endpointslice-controller.k8s.io",
+					},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"2.2.0.2"}}},
+			}
+			Expect(clusterClient0.Create(context.Background(), &endpointSlice)).To(Succeed())
+		})
+
+		It("reports the conflict and leaves it alone", func() {
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters[0:1], gatewayDNSNamespacedName, clusterGateways)
+			Expect(errs).To(HaveLen(1))
+			Expect(k8serrors.IsConflict(errs[0])).To(BeTrue())
+			Expect(errs[0]).To(MatchError(ContainSubstring("EndpointSlice xcc-dns/cluster-namespace-0-cluster-name-0-gateway is not managed by xcc-dns-controller")))
+
+			var endpointSlice discoveryv1.EndpointSlice
+			Expect(clusterClient0.Get(context.Background(), types.NamespacedName{
+				Namespace: namespace,
+				Name:      "cluster-namespace-0-cluster-name-0-gateway",
+			}, &endpointSlice)).To(Succeed())
+			Expect(endpointSlice.Labels[discoveryv1.LabelManagedBy]).To(Equal("endpointslice-controller.k8s.io"))
+			Expect(endpointSlice.Annotations).NotTo(HaveKey(connectivityv1alpha1.DNSHostnameAnnotation))
+			Expect(endpointSlice.Endpoints[0].Addresses).To(Equal([]string{"2.2.0.2"}))
+		})
+	})
+
+	Context("when an endpoint slice has the annotations but is managed by another controller", func() {
+		BeforeEach(func() {
+			existingEndpointSlice := endpointSlices[1]
+			existingEndpointSlice.Labels = map[string]string{
+				discoveryv1.LabelManagedBy: "some-other-controller",
+			}
+			Expect(clusterClient0.Create(context.Background(), &existingEndpointSlice)).To(Succeed())
+
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters[0:1], gatewayDNSNamespacedName, clusterGateways[0:1])
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("does not delete it", func() {
+			var endpointSliceList discoveryv1.EndpointSliceList
+			Expect(clusterClient0.List(context.Background(), &endpointSliceList)).To(Succeed())
+			Expect(endpointSliceList.Items).To(WithTransform(endpointSliceItemsToName, ConsistOf("cluster-namespace-0-cluster-name-0-gateway", "cluster-namespace-1-cluster-name-1-gateway")))
+		})
+	})
+
+	Context("when an endpoint slice was published before the managed-by label", func() {
+		BeforeEach(func() {
+			existingEndpointSlice := clusterGateways[0].ToEndpointSlice()
+			delete(existingEndpointSlice.Labels, discoveryv1.LabelManagedBy)
+			Expect(clusterClient0.Create(context.Background(), &existingEndpointSlice)).To(Succeed())
+
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters[0:1], gatewayDNSNamespacedName, clusterGateways[0:1])
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("adopts it by applying the managed-by label", func() {
+			var endpointSlice discoveryv1.EndpointSlice
+			Expect(clusterClient0.Get(context.Background(), types.NamespacedName{
+				Namespace: namespace,
+				Name:      "cluster-namespace-0-cluster-name-0-gateway",
+			}, &endpointSlice)).To(Succeed())
+			Expect(endpointSlice.Labels[discoveryv1.LabelManagedBy]).To(Equal(connectivityv1alpha1.EndpointSliceManagedBy))
+		})
+	})
+
+	Context("when other controllers added labels and annotations to a managed endpoint slice", func() {
+		BeforeEach(func() {
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters[0:1], gatewayDNSNamespacedName, clusterGateways[0:1])
+			Expect(errs).To(BeEmpty())
+
+			var endpointSlice discoveryv1.EndpointSlice
+			Expect(clusterClient0.Get(context.Background(), types.NamespacedName{
+				Namespace: namespace,
+				Name:      "cluster-namespace-0-cluster-name-0-gateway",
+			}, &endpointSlice)).To(Succeed())
+			endpointSlice.Labels["some-label"] = "some-value"
+			endpointSlice.Annotations["some-annotation"] = "some-value"
+			Expect(clusterClient0.Update(context.Background(), &endpointSlice)).To(Succeed())
+
+			clusterGateways[0].Gateway.Status.LoadBalancer.Ingress[0].IP = "1.1.0.9"
+			_, errs = endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters[0:1], gatewayDNSNamespacedName, clusterGateways[0:1])
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("applies the changes and keeps them", func() {
+			var endpointSlice discoveryv1.EndpointSlice
+			Expect(clusterClient0.Get(context.Background(), types.NamespacedName{
+				Namespace: namespace,
+				Name:      "cluster-namespace-0-cluster-name-0-gateway",
+			}, &endpointSlice)).To(Succeed())
+			Expect(endpointSlice.Endpoints[0].Addresses).To(Equal([]string{"1.1.0.9"}))
+			Expect(endpointSlice.Labels["some-label"]).To(Equal("some-value"))
+			Expect(endpointSlice.Annotations["some-annotation"]).To(Equal("some-value"))
+		})
+	})
+
 	Context("when updating a cluster's endpoint slices errors", func() {
 		var fakeClusterClient *gatewaydnsfakes.FakeClient
 		BeforeEach(func() {
 			fakeClusterClient = &gatewaydnsfakes.FakeClient{}
-			fakeClusterClient.CreateReturns(errors.New("something bad happened"))
+			fakeClusterClient.PatchReturns(errors.New("something bad happened"))
 
 			clusterClients["cluster-namespace-0/cluster-name-0"] = fakeClusterClient
 		})
 		It("continues onto the next cluster", func() {
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(ConsistOf(errors.New("something bad happened")))
 
+			_, obj, patch, options := fakeClusterClient.PatchArgsForCall(0)
+			Expect(patch).To(Equal(client.Apply))
+			Expect(options).To(ContainElement(client.FieldOwner(gatewaydns.FieldManager)))
+			Expect(obj.GetObjectKind().GroupVersionKind().Kind).To(Equal("EndpointSlice"))
+
 			var endpointSliceList discoveryv1.EndpointSliceList
 			Expect(clusterClient1.List(context.Background(), &endpointSliceList)).NotTo(HaveOccurred())
 			Expect(endpointSliceList.Items[0].Annotations[connectivityv1alpha1.DNSHostnameAnnotation]).To(Equal("*.gateway.cluster-name-0.cluster-namespace-0.clusters.xcc.test"))
@@ -467,7 +610,7 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			}
 		})
 		It("continues onto the next cluster", func() {
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(ConsistOf(errors.New("oopa"), errors.New("oopa")))
 			Expect(clientProvider.GetClientCallCount()).To(Equal(2))
 		})
@@ -485,8 +628,9 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 		})
 
 		It("skips the cluster without the namespace and without erroring, converges the other cluster", func() {
-			errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			notInstalled, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
 			Expect(errs).To(HaveLen(0))
+			Expect(notInstalled).To(ConsistOf(types.NamespacedName{Namespace: "cluster-namespace-0", Name: "cluster-name-0"}))
 
 			var endpointSliceList discoveryv1.EndpointSliceList
 			Expect(clusterClient0.List(context.Background(), &endpointSliceList)).NotTo(HaveOccurred())
@@ -495,6 +639,119 @@ var _ = Describe("Endpoint Slice Reconciler", func() {
 			Expect(clusterClient1.List(context.Background(), &endpointSliceList)).NotTo(HaveOccurred())
 			Expect(endpointSliceList.Items).To(WithTransform(endpointSliceItemsToName, ConsistOf("cluster-namespace-0-cluster-name-0-gateway", "cluster-namespace-1-cluster-name-1-gateway")))
 		})
+		Context("when bootstrap is enabled", func() {
+			BeforeEach(func() {
+				endpointSliceReconciler.Bootstrap = &consumer.Installer{
+					Options: consumer.Options{
+						Namespace:    namespace,
+						DomainSuffix: "xcc.test",
+					},
+					FieldManager: gatewaydns.FieldManager,
+				}
+			})
+
+			It("installs the dns-server on the cluster and converges it", func() {
+				notInstalled, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+				Expect(errs).To(BeEmpty())
+				Expect(notInstalled).To(BeEmpty())
+
+				var deployment appsv1.Deployment
+				Expect(clusterClient0.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "dns-server"}, &deployment)).To(Succeed())
+				var job batchv1.Job
+				Expect(clusterClient0.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "dns-config-patcher"}, &job)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "DOMAIN_SUFFIX", Value: "xcc.test"}))
+
+				var endpointSliceList discoveryv1.EndpointSliceList
+				Expect(clusterClient0.List(context.Background(), &endpointSliceList)).To(Succeed())
+				Expect(endpointSliceList.Items).To(WithTransform(endpointSliceItemsToName, ConsistOf("cluster-namespace-0-cluster-name-0-gateway", "cluster-namespace-1-cluster-name-1-gateway")))
+			})
+
+			It("does not install the dns-server without records to publish", func() {
+				notInstalled, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, nil)
+				Expect(errs).To(BeEmpty())
+				Expect(notInstalled).To(ConsistOf(types.NamespacedName{Namespace: "cluster-namespace-0", Name: "cluster-name-0"}))
+
+				var namespaceObject corev1.Namespace
+				err := clusterClient0.Get(context.Background(), types.NamespacedName{Name: namespace}, &namespaceObject)
+				Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			})
+
+			Context("when dry run is enabled", func() {
+				It("does not install the dns-server", func() {
+					endpointSliceReconciler.DryRun = true
+					notInstalled, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+					Expect(errs).To(BeEmpty())
+					Expect(notInstalled).To(ConsistOf(types.NamespacedName{Namespace: "cluster-namespace-0", Name: "cluster-name-0"}))
+
+					var namespaceObject corev1.Namespace
+					err := clusterClient0.Get(context.Background(), types.NamespacedName{Name: namespace}, &namespaceObject)
+					Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+				})
+			})
+		})
+	})
+
+	Context("when dry run is enabled", func() {
+		BeforeEach(func() {
+			endpointSliceReconciler.DryRun = true
+
+			existingEndpointSlice := *endpointSlices[0].DeepCopy()
+			existingEndpointSlice.Endpoints = []discoveryv1.Endpoint{{Addresses: []string{"1.1.0.3"}}}
+			Expect(clusterClient0.Create(context.Background(), &existingEndpointSlice)).To(Succeed())
+
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("does not write to the clusters", func() {
+			var endpointSliceList discoveryv1.EndpointSliceList
+			Expect(clusterClient0.List(context.Background(), &endpointSliceList)).To(Succeed())
+			Expect(endpointSliceList.Items).To(WithTransform(endpointSliceItemsToName, ConsistOf("cluster-namespace-0-cluster-name-0-gateway")))
+			Expect(endpointSliceList.Items[0].Endpoints[0].Addresses).To(Equal([]string{"1.1.0.3"}))
+
+			Expect(clusterClient1.List(context.Background(), &endpointSliceList)).To(Succeed())
+			Expect(endpointSliceList.Items).To(BeEmpty())
+		})
+	})
+
+	Describe("DiffCluster", func() {
+		BeforeEach(func() {
+			changedEndpointSlice := *endpointSlices[1].DeepCopy()
+			changedEndpointSlice.Endpoints = []discoveryv1.Endpoint{{Addresses: []string{"1.1.0.9"}}}
+			Expect(clusterClient0.Create(context.Background(), &changedEndpointSlice)).To(Succeed())
+
+			undesiredEndpointSlice := *endpointSlices[1].DeepCopy()
+			undesiredEndpointSlice.Name = "cluster-namespace-2-cluster-name-2-gateway"
+			undesiredEndpointSlice.Annotations[connectivityv1alpha1.DNSHostnameAnnotation] = "*.gateway.cluster-name-2.cluster-namespace-2.clusters.xcc.test"
+			undesiredEndpointSlice.Annotations[connectivityv1alpha1.GatewayWeightKey] = "2"
+			Expect(clusterClient0.Create(context.Background(), &undesiredEndpointSlice)).To(Succeed())
+		})
+
+		It("describes the EndpointSlices that would be created, updated and deleted without writing them", func() {
+			clusterDiff, err := endpointSliceReconciler.DiffCluster(context.Background(), gatewayDNSNamespacedName, clusterClient0, clusterGateways)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(clusterDiff.Empty()).To(BeFalse())
+			Expect(clusterDiff.Changes()).To(Equal([]string{
+				"create xcc-dns/cluster-namespace-0-cluster-name-0-gateway: *.gateway.cluster-name-0.cluster-namespace-0.clusters.xcc.test -> 1.1.0.1",
+				"update xcc-dns/cluster-namespace-1-cluster-name-1-gateway: *.gateway.cluster-name-1.cluster-namespace-1.clusters.xcc.test -> 1.1.0.9 => *.gateway.cluster-name-1.cluster-namespace-1.clusters.xcc.test -> 1.1.0.2",
+				"delete xcc-dns/cluster-namespace-2-cluster-name-2-gateway: *.gateway.cluster-name-2.cluster-namespace-2.clusters.xcc.test -> 1.1.0.2 gateway-weight=2",
+			}))
+
+			var endpointSliceList discoveryv1.EndpointSliceList
+			Expect(clusterClient0.List(context.Background(), &endpointSliceList)).To(Succeed())
+			Expect(endpointSliceList.Items).To(HaveLen(2))
+		})
+
+		It("is empty when the cluster is converged", func() {
+			_, errs := endpointSliceReconciler.ConvergeToClusters(context.Background(), clusters, gatewayDNSNamespacedName, clusterGateways)
+			Expect(errs).To(BeEmpty())
+
+			clusterDiff, err := endpointSliceReconciler.DiffCluster(context.Background(), gatewayDNSNamespacedName, clusterClient0, clusterGateways)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterDiff.Empty()).To(BeTrue())
+			Expect(clusterDiff.Changes()).To(BeEmpty())
+		})
 	})
 })
 
@@ -0,0 +1,222 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+)
+
+// Inspector reads the state the GatewayDNSReconciler converges the clusters
+// to, the same way it does, without writing to any cluster.
+type Inspector struct {
+	Client                  client.Client
+	Log                     logr.Logger
+	ClientProvider          clientProvider
+	ClusterSearcher         *ClusterSearcher
+	EndpointSliceReconciler *EndpointSliceReconciler
+	ClusterGatewayCollector *ClusterGatewayCollector
+}
+
+// GatewayDNSReport is the state of a GatewayDNS across the clusters.
+type GatewayDNSReport struct {
+	GatewayDNS connectivityv1alpha1.GatewayDNS
+
+	// MatchedClusters are the clusters selected by the clusterSelector, and
+	// ClusterGateways the gateways found on them.
+	MatchedClusters []clusterv1beta1.Cluster
+	ClusterGateways []ClusterGateway
+
+	// Clusters holds the sync state of every cluster in the namespace of the
+	// GatewayDNS, which all get its EndpointSlices.
+	Clusters []ClusterSyncState
+}
+
+// ClusterSyncState is how far the EndpointSlices of a cluster are from the
+// desired ClusterGateways.
+type ClusterSyncState struct {
+	Cluster types.NamespacedName
+
+	// Err is set when the cluster could not be read.
+	Err error
+
+	// NamespaceMissing is set when the controller namespace does not exist
+	// on the cluster, which the reconciler skips.
+	NamespaceMissing bool
+
+	// SkipReason is set when the reconciler does not write to the cluster,
+	// e.g. because the lifecycle policy of the GatewayDNS skips paused
+	// clusters. The cluster is not read.
+	SkipReason string
+
+	Diff ClusterDiff
+}
+
+// InSync returns true when the cluster was read and has nothing to converge.
+func (c ClusterSyncState) InSync() bool {
+	return c.Err == nil && !c.NamespaceMissing && c.SkipReason == "" && c.Diff.Empty()
+}
+
+// Record is an EndpointSlice of a cluster as the dns-server serves it.
+type Record struct {
+	endpointslicedns.DNSCacheEntry
+
+	// GatewayDNS is the namespace/name of the GatewayDNS that published the
+	// EndpointSlice, or empty when it was not published by one.
+	GatewayDNS string
+}
+
+// GatewayDNSStatus reports the matched clusters, their gateways and the sync
+// state of each cluster for the GatewayDNS.
+func (i *Inspector) GatewayDNSStatus(ctx context.Context, gatewayDNSNamespacedName types.NamespacedName) (GatewayDNSReport, error) {
+	var gatewayDNS connectivityv1alpha1.GatewayDNS
+	if err := i.Client.Get(ctx, gatewayDNSNamespacedName, &gatewayDNS); err != nil {
+		return GatewayDNSReport{}, err
+	}
+	return i.Plan(ctx, gatewayDNS)
+}
+
+// Plan reports what the reconciler would change on each cluster to converge
+// to the GatewayDNS, which need not exist yet or may have a new spec. Like the
+// reconciler, it skips the clusters the lifecycle policy does not let it
+// write to, and plans the removal of the records of a GatewayDNS being
+// deleted. The records of the gateways unreachable for longer than the
+// staleRecordTTL, according to the status of the GatewayDNS, are withdrawn.
+func (i *Inspector) Plan(ctx context.Context, gatewayDNS connectivityv1alpha1.GatewayDNS) (GatewayDNSReport, error) {
+	report := GatewayDNSReport{GatewayDNS: gatewayDNS}
+	gatewayDNSNamespacedName := types.NamespacedName{Namespace: gatewayDNS.Namespace, Name: gatewayDNS.Name}
+
+	var err error
+	report.MatchedClusters, err = i.ClusterSearcher.ListMatchingClusters(ctx, report.GatewayDNS)
+	if err != nil {
+		return GatewayDNSReport{}, fmt.Errorf("failed to list matching Clusters: %w", err)
+	}
+	report.ClusterGateways = i.ClusterGatewayCollector.GetGatewaysForClusters(ctx, report.GatewayDNS, report.MatchedClusters)
+
+	clusters, err := i.ClusterSearcher.ListClusters(ctx, client.InNamespace(gatewayDNSNamespacedName.Namespace))
+	if err != nil {
+		return GatewayDNSReport{}, fmt.Errorf("failed to list Clusters: %w", err)
+	}
+	policy := clusterLifecyclePolicy(gatewayDNS)
+	deleting := !gatewayDNS.DeletionTimestamp.IsZero()
+	// The status is only read, the reconciler records in it when the
+	// gateways became unreachable.
+	desired, _ := withdrawStaleGateways(logr.Discard(), gatewayDNS.DeepCopy(), report.ClusterGateways, time.Now())
+	if deleting {
+		desired = nil
+	}
+	for _, cluster := range clusters {
+		state := ClusterSyncState{Cluster: types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}}
+		if deleting && IsClusterDeleting(cluster) {
+			state.SkipReason = "Cluster is being deleted"
+		} else {
+			state.SkipReason = skipWritesReason(policy, cluster)
+		}
+		if state.SkipReason != "" {
+			report.Clusters = append(report.Clusters, state)
+			continue
+		}
+
+		clusterClient, namespaceExists, err := i.clusterClient(ctx, state.Cluster)
+		switch {
+		case err != nil:
+			state.Err = err
+		case !namespaceExists:
+			state.NamespaceMissing = true
+		default:
+			state.Diff, state.Err = i.EndpointSliceReconciler.DiffCluster(ctx, gatewayDNSNamespacedName, clusterClient, desired)
+		}
+		report.Clusters = append(report.Clusters, state)
+	}
+	return report, nil
+}
+
+// Records returns the records the dns-server of the cluster serves, sorted by
+// name.
+func (i *Inspector) Records(ctx context.Context, cluster types.NamespacedName) ([]Record, error) {
+	clusterClient, namespaceExists, err := i.clusterClient(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if !namespaceExists {
+		return nil, fmt.Errorf("namespace %s does not exist on Cluster %s, the dns-server is not installed", i.EndpointSliceReconciler.Namespace, cluster)
+	}
+
+	var endpointSlices discoveryv1.EndpointSliceList
+	if err := clusterClient.List(ctx, &endpointSlices, client.InNamespace(i.EndpointSliceReconciler.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, endpointSlice := range endpointSlices.Items {
+		entry, ok := endpointslicedns.DNSCacheEntryForEndpointSlice(i.Log, endpointSlice)
+		if !ok {
+			continue
+		}
+		records = append(records, Record{
+			DNSCacheEntry: entry,
+			GatewayDNS:    endpointSlice.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation],
+		})
+	}
+	sort.Slice(records, func(a, b int) bool {
+		if records[a].FQDN != records[b].FQDN {
+			return records[a].FQDN < records[b].FQDN
+		}
+		return records[a].ResourceKey < records[b].ResourceKey
+	})
+	return records, nil
+}
+
+// Resolve returns the records the dns-server of the cluster answers a query
+// for the fqdn with, including those of matching wildcards.
+func (i *Inspector) Resolve(ctx context.Context, cluster types.NamespacedName, fqdn string) ([]Record, error) {
+	records, err := i.Records(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache endpointslicedns.DNSCache
+	byResourceKey := map[string]Record{}
+	for _, record := range records {
+		cache.Upsert(record.DNSCacheEntry)
+		byResourceKey[record.ResourceKey] = record
+	}
+
+	var answers []Record
+	for _, entry := range cache.Lookup(fqdn) {
+		answers = append(answers, byResourceKey[entry.ResourceKey])
+	}
+	return answers, nil
+}
+
+// clusterClient returns the client of the cluster and whether the controller
+// namespace exists on it.
+func (i *Inspector) clusterClient(ctx context.Context, cluster types.NamespacedName) (client.Client, bool, error) {
+	clusterClient, err := i.ClientProvider.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get client of Cluster %s: %w", cluster, err)
+	}
+
+	var namespace corev1.Namespace
+	if err := clusterClient.Get(ctx, client.ObjectKey{Name: i.EndpointSliceReconciler.Namespace}, &namespace); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return clusterClient, false, nil
+		}
+		return nil, false, err
+	}
+	return clusterClient, true, nil
+}
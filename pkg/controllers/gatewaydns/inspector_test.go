@@ -0,0 +1,357 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns/gatewaydnsfakes"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/internal/applytest"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspector", func() {
+	var (
+		managementClient      client.Client
+		gatewayClusterClient  client.Client
+		workloadClusterClient client.Client
+		clusterClients        map[string]client.Client
+
+		inspector *gatewaydns.Inspector
+
+		gatewayDNSName  types.NamespacedName
+		gatewayCluster  types.NamespacedName
+		workloadCluster types.NamespacedName
+		namespace       string
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = connectivityv1alpha1.AddToScheme(scheme)
+		_ = clusterv1beta1.AddToScheme(scheme)
+		_ = discoveryv1.AddToScheme(scheme)
+
+		managementClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		gatewayClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+		workloadClusterClient = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+
+		gatewayCluster = types.NamespacedName{Namespace: "some-namespace", Name: "some-gateway-cluster"}
+		workloadCluster = types.NamespacedName{Namespace: "some-namespace", Name: "some-workload-cluster"}
+		clusterClients = map[string]client.Client{
+			gatewayCluster.String():  gatewayClusterClient,
+			workloadCluster.String(): workloadClusterClient,
+		}
+		clientProvider := &gatewaydnsfakes.FakeClientProvider{}
+		clientProvider.GetClientStub = func(ctx context.Context, namespacedName types.NamespacedName) (client.Client, error) {
+			clusterClient, ok := clusterClients[namespacedName.String()]
+			if !ok {
+				return nil, errors.New("cluster unreachable")
+			}
+			return clusterClient, nil
+		}
+
+		ctrl.SetLogger(zap.New(
+			zap.UseDevMode(true),
+			zap.WriteTo(GinkgoWriter),
+		))
+		log := ctrl.Log.WithName("xccctl")
+
+		namespace = "xcc-dns"
+		inspector = &gatewaydns.Inspector{
+			Client:          managementClient,
+			Log:             log,
+			ClientProvider:  clientProvider,
+			ClusterSearcher: &gatewaydns.ClusterSearcher{Client: managementClient},
+			EndpointSliceReconciler: &gatewaydns.EndpointSliceReconciler{
+				Log:            log,
+				ClientProvider: clientProvider,
+				Namespace:      namespace,
+			},
+			ClusterGatewayCollector: &gatewaydns.ClusterGatewayCollector{
+				Log:            log,
+				ClientProvider: clientProvider,
+				Namespace:      namespace,
+				DomainSuffix:   "xcc.test",
+			},
+		}
+
+		gatewayDNSName = types.NamespacedName{Namespace: "some-namespace", Name: "some-gateway-dns"}
+		Expect(managementClient.Create(context.Background(), &connectivityv1alpha1.GatewayDNS{
+			ObjectMeta: metav1.ObjectMeta{Namespace: gatewayDNSName.Namespace, Name: gatewayDNSName.Name},
+			Spec: connectivityv1alpha1.GatewayDNSSpec{
+				ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"hasContourGateway": "true"}},
+				Service:         "projectcontour/envoy",
+				ResolutionType:  connectivityv1alpha1.ResolutionTypeLoadBalancer,
+			},
+		})).To(Succeed())
+
+		for _, cluster := range []struct {
+			name   types.NamespacedName
+			labels map[string]string
+		}{
+			{gatewayCluster, map[string]string{"hasContourGateway": "true"}},
+			{workloadCluster, nil},
+			{types.NamespacedName{Namespace: "some-namespace", Name: "some-unreachable-cluster"}, nil},
+		} {
+			Expect(managementClient.Create(context.Background(), &clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: cluster.name.Namespace, Name: cluster.name.Name, Labels: cluster.labels},
+			})).To(Succeed())
+		}
+
+		Expect(gatewayClusterClient.Create(context.Background(), &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		})).To(Succeed())
+		Expect(gatewayClusterClient.Create(context.Background(), &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "projectcontour", Name: "envoy"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+			}},
+		})).To(Succeed())
+
+		Expect(gatewayClusterClient.Create(context.Background(), &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      "some-namespace-some-gateway-cluster-gateway",
+				Labels: map[string]string{
+					discoveryv1.LabelManagedBy: connectivityv1alpha1.EndpointSliceManagedBy,
+				},
+				Annotations: map[string]string{
+					connectivityv1alpha1.DNSHostnameAnnotation:   "*.gateway.some-gateway-cluster.some-namespace.clusters.xcc.test",
+					connectivityv1alpha1.GatewayDNSRefAnnotation: gatewayDNSName.String(),
+					connectivityv1alpha1.ClusterRefAnnotation:    "some-namespace/some-gateway-cluster",
+				},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"1.2.3.4"}}},
+		})).To(Succeed())
+		Expect(gatewayClusterClient.Create(context.Background(), &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      "some-service",
+				Annotations: map[string]string{
+					connectivityv1alpha1.DNSHostnameAnnotation: "some-service.xcc.test",
+				},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"5.6.7.8"}}},
+		})).To(Succeed())
+	})
+
+	Describe("GatewayDNSStatus", func() {
+		It("reports the matched clusters, their gateways and the sync state of each cluster", func() {
+			report, err := inspector.GatewayDNSStatus(context.Background(), gatewayDNSName)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.MatchedClusters).To(HaveLen(1))
+			Expect(report.MatchedClusters[0].Name).To(Equal(gatewayCluster.Name))
+			Expect(report.ClusterGateways).To(HaveLen(1))
+			Expect(report.ClusterGateways[0].Unreachable).To(BeFalse())
+			Expect(report.ClusterGateways[0].Gateway.Status.LoadBalancer.Ingress[0].IP).To(Equal("1.2.3.4"))
+
+			states := map[string]gatewaydns.ClusterSyncState{}
+			for _, state := range report.Clusters {
+				states[state.Cluster.Name] = state
+			}
+			Expect(states).To(HaveLen(3))
+
+			Expect(states["some-gateway-cluster"].InSync()).To(BeTrue())
+
+			Expect(states["some-workload-cluster"].NamespaceMissing).To(BeTrue())
+			Expect(states["some-workload-cluster"].InSync()).To(BeFalse())
+
+			Expect(states["some-unreachable-cluster"].Err).To(MatchError(ContainSubstring("cluster unreachable")))
+			Expect(states["some-unreachable-cluster"].InSync()).To(BeFalse())
+		})
+
+		It("reports the EndpointSlices a cluster is missing", func() {
+			Expect(workloadClusterClient.Create(context.Background(), &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			})).To(Succeed())
+
+			report, err := inspector.GatewayDNSStatus(context.Background(), gatewayDNSName)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, state := range report.Clusters {
+				if state.Cluster == workloadCluster {
+					Expect(state.InSync()).To(BeFalse())
+					Expect(state.Diff.Missing).To(HaveLen(1))
+					Expect(state.Diff.Missing[0].Name).To(Equal("some-namespace-some-gateway-cluster-gateway"))
+					Expect(state.Diff.Changed).To(BeEmpty())
+					Expect(state.Diff.Undesired).To(BeEmpty())
+				}
+			}
+		})
+
+		It("does not write to the clusters", func() {
+			Expect(workloadClusterClient.Create(context.Background(), &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			})).To(Succeed())
+
+			_, err := inspector.GatewayDNSStatus(context.Background(), gatewayDNSName)
+			Expect(err).NotTo(HaveOccurred())
+
+			var endpointSlices discoveryv1.EndpointSliceList
+			Expect(workloadClusterClient.List(context.Background(), &endpointSlices)).To(Succeed())
+			Expect(endpointSlices.Items).To(BeEmpty())
+		})
+
+		Context("when a gateway has been unreachable for longer than the stale record ttl", func() {
+			var since metav1.Time
+
+			BeforeEach(func() {
+				Expect(workloadClusterClient.Create(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+				var endpointSlice discoveryv1.EndpointSlice
+				Expect(gatewayClusterClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "some-namespace-some-gateway-cluster-gateway"}, &endpointSlice)).To(Succeed())
+				endpointSlice.ResourceVersion = ""
+				Expect(workloadClusterClient.Create(context.Background(), &endpointSlice)).To(Succeed())
+
+				var gatewayDNS connectivityv1alpha1.GatewayDNS
+				Expect(managementClient.Get(context.Background(), gatewayDNSName, &gatewayDNS)).To(Succeed())
+				gatewayDNS.Spec.StaleRecordTTL = &metav1.Duration{Duration: time.Minute}
+				Expect(managementClient.Update(context.Background(), &gatewayDNS)).To(Succeed())
+				since = metav1.NewTime(time.Now().Add(-time.Hour)).Rfc3339Copy()
+				gatewayDNS.Status.UnreachableGateways = []connectivityv1alpha1.UnreachableGateway{
+					{Cluster: gatewayCluster.String(), Since: since},
+				}
+				Expect(managementClient.Status().Update(context.Background(), &gatewayDNS)).To(Succeed())
+
+				delete(clusterClients, gatewayCluster.String())
+			})
+
+			It("plans the withdrawal of its records without changing the status", func() {
+				report, err := inspector.GatewayDNSStatus(context.Background(), gatewayDNSName)
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, state := range report.Clusters {
+					if state.Cluster == workloadCluster {
+						Expect(state.Diff.Undesired).To(HaveLen(1))
+						Expect(state.Diff.Undesired[0].Name).To(Equal("some-namespace-some-gateway-cluster-gateway"))
+					}
+				}
+				Expect(report.GatewayDNS.Status.UnreachableGateways).To(HaveLen(1))
+				Expect(report.GatewayDNS.Status.UnreachableGateways[0].Since.Equal(&since)).To(BeTrue())
+				Expect(report.GatewayDNS.Status.UnreachableGateways[0].Withdrawn).To(BeFalse())
+			})
+		})
+
+		Context("when a cluster is paused", func() {
+			BeforeEach(func() {
+				Expect(workloadClusterClient.Create(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+
+				var cluster clusterv1beta1.Cluster
+				Expect(managementClient.Get(context.Background(), workloadCluster, &cluster)).To(Succeed())
+				cluster.Spec.Paused = true
+				Expect(managementClient.Update(context.Background(), &cluster)).To(Succeed())
+			})
+
+			It("reports it as skipped without planning changes", func() {
+				report, err := inspector.GatewayDNSStatus(context.Background(), gatewayDNSName)
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, state := range report.Clusters {
+					if state.Cluster == workloadCluster {
+						Expect(state.SkipReason).To(Equal("Cluster is paused"))
+						Expect(state.Diff.Empty()).To(BeTrue())
+						Expect(state.InSync()).To(BeFalse())
+					}
+				}
+			})
+		})
+
+		Context("when the GatewayDNS is being deleted", func() {
+			BeforeEach(func() {
+				var gatewayDNS connectivityv1alpha1.GatewayDNS
+				Expect(managementClient.Get(context.Background(), gatewayDNSName, &gatewayDNS)).To(Succeed())
+				gatewayDNS.Finalizers = []string{connectivityv1alpha1.GatewayDNSFinalizer}
+				Expect(managementClient.Update(context.Background(), &gatewayDNS)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), &gatewayDNS)).To(Succeed())
+
+				var cluster clusterv1beta1.Cluster
+				Expect(managementClient.Get(context.Background(), workloadCluster, &cluster)).To(Succeed())
+				cluster.Finalizers = []string{clusterv1beta1.ClusterFinalizer}
+				Expect(managementClient.Update(context.Background(), &cluster)).To(Succeed())
+				Expect(managementClient.Delete(context.Background(), &cluster)).To(Succeed())
+			})
+
+			It("plans the removal of the records and skips the clusters being deleted", func() {
+				report, err := inspector.GatewayDNSStatus(context.Background(), gatewayDNSName)
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, state := range report.Clusters {
+					switch state.Cluster {
+					case gatewayCluster:
+						Expect(state.Diff.Undesired).To(HaveLen(1))
+						Expect(state.Diff.Undesired[0].Name).To(Equal("some-namespace-some-gateway-cluster-gateway"))
+					case workloadCluster:
+						Expect(state.SkipReason).To(Equal("Cluster is being deleted"))
+					}
+				}
+			})
+		})
+
+		It("returns an error when the GatewayDNS does not exist", func() {
+			_, err := inspector.GatewayDNSStatus(context.Background(), types.NamespacedName{Namespace: "some-namespace", Name: "missing"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Records", func() {
+		It("returns the records the dns-server of the cluster serves, sorted by name", func() {
+			records, err := inspector.Records(context.Background(), gatewayCluster)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(records).To(HaveLen(2))
+			Expect(records[0].FQDN).To(Equal("*.gateway.some-gateway-cluster.some-namespace.clusters.xcc.test"))
+			Expect(records[0].Addresses).To(Equal([]string{"1.2.3.4"}))
+			Expect(records[0].GatewayDNS).To(Equal(gatewayDNSName.String()))
+			Expect(records[1].FQDN).To(Equal("some-service.xcc.test"))
+			Expect(records[1].GatewayDNS).To(BeEmpty())
+		})
+
+		It("returns an error when the dns-server is not installed on the cluster", func() {
+			_, err := inspector.Records(context.Background(), workloadCluster)
+			Expect(err).To(MatchError(ContainSubstring("the dns-server is not installed")))
+		})
+	})
+
+	Describe("Resolve", func() {
+		It("answers names matching a wildcard", func() {
+			records, err := inspector.Resolve(context.Background(), gatewayCluster, "app.gateway.some-gateway-cluster.some-namespace.clusters.xcc.test")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Addresses).To(Equal([]string{"1.2.3.4"}))
+			Expect(records[0].ResourceKey).To(Equal("xcc-dns/some-namespace-some-gateway-cluster-gateway"))
+		})
+
+		It("answers nothing for unknown names", func() {
+			records, err := inspector.Resolve(context.Background(), gatewayCluster, "unknown.xcc.test")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(BeEmpty())
+		})
+	})
+})
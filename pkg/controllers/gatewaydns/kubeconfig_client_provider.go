@@ -0,0 +1,47 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KubeconfigClientProvider returns uncached clients of workload clusters built
+// from the kubeconfig Secrets Cluster API writes next to each Cluster. It
+// reads the same Secrets as the ClusterCacheTracker of xcc-dns-controller, for
+// programs that cannot run a manager, like xccctl.
+type KubeconfigClientProvider struct {
+	// Client reads the kubeconfig Secrets from the management cluster. Its
+	// scheme is used by the clients of the workload clusters.
+	Client client.Client
+
+	// SourceName identifies the program in the user agent of the clients.
+	SourceName string
+
+	mu      sync.Mutex
+	clients map[client.ObjectKey]client.Client
+}
+
+// GetClient returns the client of the cluster, creating it the first time.
+func (k *KubeconfigClientProvider) GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if clusterClient, ok := k.clients[cluster]; ok {
+		return clusterClient, nil
+	}
+	clusterClient, err := remote.NewClusterClient(ctx, k.SourceName, k.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if k.clients == nil {
+		k.clients = map[client.ObjectKey]client.Client{}
+	}
+	k.clients[cluster] = clusterClient
+	return clusterClient, nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	plannedEndpointSliceChanges = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xcc_dns_controller_planned_endpointslice_changes",
+		Help: "Number of EndpointSlices the last dry run of a GatewayDNS would create, update or delete on a cluster, by action.",
+	}, []string{"gatewaydns", "cluster", "action"})
+
+	orphanedEndpointSlices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "xcc_dns_controller_orphaned_endpointslices",
+		Help: "Number of orphaned EndpointSlices on a cluster waiting for their grace period to expire, or kept by a dry run.",
+	}, []string{"cluster"})
+
+	orphanedEndpointSlicesDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xcc_dns_controller_orphaned_endpointslices_deleted_total",
+		Help: "Number of orphaned EndpointSlices deleted from a cluster, by the reason they were orphaned.",
+	}, []string{"cluster", "reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(plannedEndpointSliceChanges, orphanedEndpointSlices, orphanedEndpointSlicesDeleted)
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
+)
+
+const (
+	orphanReasonGatewayDNSNotFound     = "GatewayDNSNotFound"
+	orphanReasonClusterNotFound        = "ClusterNotFound"
+	orphanReasonClusterNotSelected     = "ClusterNotSelected"
+	orphanReasonConsumerNotInNamespace = "ConsumerNotInNamespace"
+)
+
+// OrphanCollector periodically deletes the EndpointSlices published by
+// xcc-dns-controller whose GatewayDNS or gateway Cluster no longer exists,
+// from every workload cluster. Those are left behind when a GatewayDNS is
+// deleted while the controller is down, or while a workload cluster is
+// unreachable. So are the EndpointSlices of a gateway Cluster that left the
+// namespace of the GatewayDNS or no longer matches its cluster selector, and
+// those on a workload cluster that is no longer in the namespace of their
+// GatewayDNS.
+type OrphanCollector struct {
+	// Client reads the GatewayDNS and Clusters of the management cluster.
+	Client         client.Client
+	Log            logr.Logger
+	ClientProvider clientProvider
+	Namespace      string
+
+	// Inventory lists the clusters, defaults to the Cluster API Clusters.
+	Inventory inventory.Lister
+
+	// Interval between two passes, defaults to 10 minutes.
+	Interval time.Duration
+
+	// GracePeriod an EndpointSlice has to be seen orphaned for before it is
+	// deleted, so that it survives a GatewayDNS or a Cluster being recreated
+	// and caches catching up. Defaults to 10 minutes.
+	GracePeriod time.Duration
+
+	// DryRun logs the orphaned EndpointSlices instead of deleting them.
+	DryRun bool
+
+	mu            sync.Mutex
+	orphanedSince map[orphanKey]time.Time
+
+	// gaugedClusters are the clusters with an orphaned EndpointSlices gauge.
+	gaugedClusters map[string]bool
+}
+
+// Start runs a pass every Interval until the context is done.
+func (o *OrphanCollector) Start(ctx context.Context) error {
+	interval := o.Interval
+	if interval == 0 {
+		interval = 10 * time.Minute
+	}
+	o.Log.Info("Start", "Interval", interval, "GracePeriod", o.gracePeriod())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := o.Collect(ctx); err != nil {
+				o.Log.Error(err, "Failed to collect orphaned EndpointSlices")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes only the leader delete EndpointSlices.
+func (o *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect runs a single pass over all workload clusters. Clusters that cannot
+// be reached are skipped until the next pass.
+func (o *OrphanCollector) Collect(ctx context.Context) error {
+	var gatewayDNSList connectivityv1alpha1.GatewayDNSList
+	if err := o.Client.List(ctx, &gatewayDNSList); err != nil {
+		return err
+	}
+	gatewayDNSs := make(map[string]gatewayDNSScope, len(gatewayDNSList.Items))
+	for _, gatewayDNS := range gatewayDNSList.Items {
+		scope := gatewayDNSScope{namespace: gatewayDNS.Namespace}
+		selector, err := metav1.LabelSelectorAsSelector(&gatewayDNS.Spec.ClusterSelector)
+		if err != nil {
+			o.Log.Error(err, "Encountered invalid Selector as LabelSelector", "GatewayDNS", fmt.Sprintf("%s/%s", gatewayDNS.Namespace, gatewayDNS.Name))
+		} else {
+			scope.selector = selector
+		}
+		gatewayDNSs[types.NamespacedName{Namespace: gatewayDNS.Namespace, Name: gatewayDNS.Name}.String()] = scope
+	}
+
+	clusterList, err := inventory.WithDefault(o.Inventory, o.Client).ListClusters(ctx)
+	if err != nil {
+		return err
+	}
+	clusters := make(map[string]labels.Set, len(clusterList))
+	for _, cluster := range clusterList {
+		clusters[types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}.String()] = labels.Set(cluster.Labels)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.orphanedSince == nil {
+		o.orphanedSince = map[orphanKey]time.Time{}
+	}
+	if o.gaugedClusters == nil {
+		o.gaugedClusters = map[string]bool{}
+	}
+
+	// The gauge of a cluster that cannot be reached keeps its last value,
+	// only those of the clusters gone from the inventory are deleted.
+	for cluster := range o.gaugedClusters {
+		if _, ok := clusters[cluster]; !ok {
+			orphanedEndpointSlices.DeleteLabelValues(cluster)
+			delete(o.gaugedClusters, cluster)
+		}
+	}
+
+	seen := map[orphanKey]bool{}
+	for _, cluster := range clusterList {
+		clusterNamespacedName := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}
+		log := o.Log.WithValues("Cluster", clusterNamespacedName.String())
+
+		clusterClient, orphans, err := o.orphansOnCluster(ctx, clusterNamespacedName, gatewayDNSs, clusters)
+		if err != nil {
+			log.Error(err, "Failed to look for orphaned EndpointSlices")
+			// Keep the orphans of the cluster pending until it is
+			// reachable again.
+			for key := range o.orphanedSince {
+				if key.cluster == clusterNamespacedName {
+					seen[key] = true
+				}
+			}
+			continue
+		}
+
+		pending := 0
+		for _, orphan := range orphans {
+			key := orphanKey{cluster: clusterNamespacedName, endpointSlice: EndpointSliceKey(orphan.endpointSlice)}
+			seen[key] = true
+			since, ok := o.orphanedSince[key]
+			if !ok {
+				since = time.Now()
+				o.orphanedSince[key] = since
+				log.Info("Found orphaned EndpointSlice", "EndpointSlice", key.endpointSlice, "Reason", orphan.reason)
+			}
+			if o.DryRun || time.Since(since) < o.gracePeriod() {
+				pending++
+				continue
+			}
+
+			uid := orphan.endpointSlice.UID
+			err := clusterClient.Delete(ctx, &orphan.endpointSlice, client.Preconditions{UID: &uid})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				log.Error(err, "Failed to delete orphaned EndpointSlice", "EndpointSlice", key.endpointSlice)
+				pending++
+				continue
+			}
+			delete(o.orphanedSince, key)
+			orphanedEndpointSlicesDeleted.WithLabelValues(clusterNamespacedName.String(), orphan.reason).Inc()
+			log.Info("Deleted orphaned EndpointSlice", "EndpointSlice", key.endpointSlice, "Reason", orphan.reason)
+		}
+		orphanedEndpointSlices.WithLabelValues(clusterNamespacedName.String()).Set(float64(pending))
+		o.gaugedClusters[clusterNamespacedName.String()] = true
+	}
+
+	for key := range o.orphanedSince {
+		if !seen[key] {
+			delete(o.orphanedSince, key)
+		}
+	}
+	return nil
+}
+
+// orphansOnCluster returns the EndpointSlices published by xcc-dns-controller
+// on the cluster whose GatewayDNS or gateway Cluster is not in the given sets,
+// whose gateway Cluster is not selected by the GatewayDNS, or whose GatewayDNS
+// is in another namespace than the cluster. EndpointSlices published before
+// the ClusterRefAnnotation was set are not checked for their gateway Cluster.
+func (o *OrphanCollector) orphansOnCluster(ctx context.Context, cluster types.NamespacedName, gatewayDNSs map[string]gatewayDNSScope, clusters map[string]labels.Set) (client.Client, []orphan, error) {
+	clusterClient, err := o.ClientProvider.GetClient(ctx, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var namespace corev1.Namespace
+	err = clusterClient.Get(ctx, client.ObjectKey{Name: o.Namespace}, &namespace)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return clusterClient, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var endpointSliceList discoveryv1.EndpointSliceList
+	if err := clusterClient.List(ctx, &endpointSliceList, client.InNamespace(o.Namespace)); err != nil {
+		return nil, nil, err
+	}
+
+	var orphans []orphan
+	for _, endpointSlice := range endpointSliceList.Items {
+		if !isManagedEndpointSlice(endpointSlice) {
+			continue
+		}
+		gatewayDNS, ok := gatewayDNSs[endpointSlice.Annotations[connectivityv1alpha1.GatewayDNSRefAnnotation]]
+		if !ok {
+			orphans = append(orphans, orphan{endpointSlice: endpointSlice, reason: orphanReasonGatewayDNSNotFound})
+			continue
+		}
+		if gatewayDNS.namespace != cluster.Namespace {
+			orphans = append(orphans, orphan{endpointSlice: endpointSlice, reason: orphanReasonConsumerNotInNamespace})
+			continue
+		}
+		clusterRef, ok := endpointSlice.Annotations[connectivityv1alpha1.ClusterRefAnnotation]
+		if !ok {
+			continue
+		}
+		clusterLabels, ok := clusters[clusterRef]
+		if !ok {
+			orphans = append(orphans, orphan{endpointSlice: endpointSlice, reason: orphanReasonClusterNotFound})
+			continue
+		}
+		if !gatewayDNS.selects(clusterRef, clusterLabels) {
+			orphans = append(orphans, orphan{endpointSlice: endpointSlice, reason: orphanReasonClusterNotSelected})
+		}
+	}
+	return clusterClient, orphans, nil
+}
+
+func (o *OrphanCollector) gracePeriod() time.Duration {
+	if o.GracePeriod == 0 {
+		return 10 * time.Minute
+	}
+	return o.GracePeriod
+}
+
+// gatewayDNSScope holds what the orphan collection needs of a GatewayDNS: its
+// namespace and its cluster selector, which is nil when it is invalid.
+type gatewayDNSScope struct {
+	namespace string
+	selector  labels.Selector
+}
+
+// selects returns whether the gateway Cluster is in the namespace of the
+// GatewayDNS and matches its selector. Clusters are kept when the selector is
+// invalid, as the GatewayDNS is not reconciled until it is fixed.
+func (g gatewayDNSScope) selects(cluster string, clusterLabels labels.Set) bool {
+	if !strings.HasPrefix(cluster, g.namespace+"/") {
+		return false
+	}
+	return g.selector == nil || g.selector.Matches(clusterLabels)
+}
+
+type orphanKey struct {
+	cluster       types.NamespacedName
+	endpointSlice string
+}
+
+type orphan struct {
+	endpointSlice discoveryv1.EndpointSlice
+	reason        string
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns/gatewaydnsfakes"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrphanCollector", func() {
+	var (
+		managementClient      client.Client
+		workloadClusterClient client.Client
+		clusterClients        map[string]client.Client
+		orphanCollector       *gatewaydns.OrphanCollector
+		namespace             string
+	)
+
+	managedEndpointSlice := func(name, gatewayDNS, cluster string) *discoveryv1.EndpointSlice {
+		annotations := map[string]string{
+			connectivityv1alpha1.DNSHostnameAnnotation:   "*.gateway." + name + ".xcc.test",
+			connectivityv1alpha1.GatewayDNSRefAnnotation: gatewayDNS,
+		}
+		if cluster != "" {
+			annotations[connectivityv1alpha1.ClusterRefAnnotation] = cluster
+		}
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Labels: map[string]string{
+					discoveryv1.LabelManagedBy: connectivityv1alpha1.EndpointSliceManagedBy,
+				},
+				Annotations: annotations,
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"1.2.3.4"}}},
+		}
+	}
+
+	endpointSliceNames := func() []string {
+		var endpointSliceList discoveryv1.EndpointSliceList
+		Expect(workloadClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+		return endpointSliceItemsToName(endpointSliceList.Items)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = connectivityv1alpha1.AddToScheme(scheme)
+		_ = clusterv1beta1.AddToScheme(scheme)
+		_ = discoveryv1.AddToScheme(scheme)
+
+		namespace = "xcc-dns"
+
+		managementClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		workloadClusterClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		clusterClients = map[string]client.Client{
+			"some-namespace/some-workload-cluster": workloadClusterClient,
+		}
+
+		clientProvider := &gatewaydnsfakes.FakeClientProvider{}
+		clientProvider.GetClientStub = func(ctx context.Context, namespacedName types.NamespacedName) (client.Client, error) {
+			clusterClient, ok := clusterClients[namespacedName.String()]
+			if !ok {
+				return nil, errors.New("cluster unreachable")
+			}
+			return clusterClient, nil
+		}
+
+		ctrl.SetLogger(zap.New(
+			zap.UseDevMode(true),
+			zap.WriteTo(GinkgoWriter),
+		))
+
+		orphanCollector = &gatewaydns.OrphanCollector{
+			Client:         managementClient,
+			Log:            ctrl.Log.WithName("OrphanCollector"),
+			ClientProvider: clientProvider,
+			Namespace:      namespace,
+			GracePeriod:    time.Millisecond,
+		}
+
+		Expect(managementClient.Create(context.Background(), &connectivityv1alpha1.GatewayDNS{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "some-gateway-dns"},
+		})).To(Succeed())
+		for _, name := range []string{"some-workload-cluster", "some-gateway-cluster", "some-unreachable-cluster"} {
+			Expect(managementClient.Create(context.Background(), &clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: name},
+			})).To(Succeed())
+		}
+
+		Expect(workloadClusterClient.Create(context.Background(), &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		})).To(Succeed())
+		Expect(workloadClusterClient.Create(context.Background(),
+			managedEndpointSlice("current", "some-namespace/some-gateway-dns", "some-namespace/some-gateway-cluster"))).To(Succeed())
+		Expect(workloadClusterClient.Create(context.Background(),
+			managedEndpointSlice("deleted-gateway-dns", "some-namespace/deleted-gateway-dns", "some-namespace/some-gateway-cluster"))).To(Succeed())
+		Expect(workloadClusterClient.Create(context.Background(),
+			managedEndpointSlice("deleted-cluster", "some-namespace/some-gateway-dns", "some-namespace/deleted-cluster"))).To(Succeed())
+		Expect(workloadClusterClient.Create(context.Background(),
+			managedEndpointSlice("without-cluster-ref", "some-namespace/some-gateway-dns", ""))).To(Succeed())
+
+		legacyEndpointSlice := managedEndpointSlice("legacy-deleted-gateway-dns", "some-namespace/deleted-gateway-dns", "")
+		legacyEndpointSlice.Labels = nil
+		Expect(workloadClusterClient.Create(context.Background(), legacyEndpointSlice)).To(Succeed())
+
+		foreignEndpointSlice := managedEndpointSlice("foreign", "some-namespace/deleted-gateway-dns", "")
+		foreignEndpointSlice.Labels[discoveryv1.LabelManagedBy] = "some-other-controller"
+		Expect(workloadClusterClient.Create(context.Background(), foreignEndpointSlice)).To(Succeed())
+	})
+
+	It("keeps orphaned EndpointSlices during the grace period", func() {
+		orphanCollector.GracePeriod = time.Hour
+		Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+		Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+		Expect(endpointSliceNames()).To(ConsistOf("current", "deleted-gateway-dns", "deleted-cluster", "without-cluster-ref", "legacy-deleted-gateway-dns", "foreign"))
+	})
+
+	It("deletes the EndpointSlices whose GatewayDNS or Cluster no longer exists once the grace period expired", func() {
+		Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+		time.Sleep(2 * time.Millisecond)
+		Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+		Expect(endpointSliceNames()).To(ConsistOf("current", "without-cluster-ref", "foreign"))
+	})
+
+	Context("when clusters leave the namespace of the GatewayDNS or stop matching its selector", func() {
+		var movedClusterClient client.Client
+
+		BeforeEach(func() {
+			Expect(managementClient.Create(context.Background(), &connectivityv1alpha1.GatewayDNS{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "selective-gateway-dns"},
+				Spec: connectivityv1alpha1.GatewayDNSSpec{
+					ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"hasContour": "true"}},
+				},
+			})).To(Succeed())
+			for _, cluster := range []types.NamespacedName{
+				{Namespace: "some-namespace", Name: "labelled-gateway-cluster"},
+				{Namespace: "other-namespace", Name: "labelled-gateway-cluster"},
+			} {
+				Expect(managementClient.Create(context.Background(), &clusterv1beta1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: cluster.Name, Labels: map[string]string{"hasContour": "true"}},
+				})).To(Succeed())
+			}
+
+			Expect(workloadClusterClient.Create(context.Background(),
+				managedEndpointSlice("selected", "some-namespace/selective-gateway-dns", "some-namespace/labelled-gateway-cluster"))).To(Succeed())
+			Expect(workloadClusterClient.Create(context.Background(),
+				managedEndpointSlice("not-selected", "some-namespace/selective-gateway-dns", "some-namespace/some-gateway-cluster"))).To(Succeed())
+			Expect(workloadClusterClient.Create(context.Background(),
+				managedEndpointSlice("gateway-in-other-namespace", "some-namespace/selective-gateway-dns", "other-namespace/labelled-gateway-cluster"))).To(Succeed())
+
+			// The workload cluster was recreated in another namespace,
+			// keeping the EndpointSlices of its former GatewayDNS.
+			Expect(managementClient.Create(context.Background(), &clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "other-namespace", Name: "moved-workload-cluster"},
+			})).To(Succeed())
+			movedClusterClient = fake.NewClientBuilder().WithScheme(managementClient.Scheme()).Build()
+			clusterClients["other-namespace/moved-workload-cluster"] = movedClusterClient
+			Expect(movedClusterClient.Create(context.Background(), &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			})).To(Succeed())
+			Expect(movedClusterClient.Create(context.Background(),
+				managedEndpointSlice("current", "some-namespace/some-gateway-dns", "some-namespace/some-gateway-cluster"))).To(Succeed())
+		})
+
+		It("deletes their EndpointSlices once the grace period expired", func() {
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+			time.Sleep(2 * time.Millisecond)
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			Expect(endpointSliceNames()).To(ConsistOf("current", "without-cluster-ref", "foreign", "selected"))
+
+			var endpointSliceList discoveryv1.EndpointSliceList
+			Expect(movedClusterClient.List(context.Background(), &endpointSliceList)).To(Succeed())
+			Expect(endpointSliceList.Items).To(BeEmpty())
+		})
+	})
+
+	Context("when an orphaned EndpointSlice is adopted again within the grace period", func() {
+		It("restarts its grace period", func() {
+			orphanCollector.GracePeriod = 50 * time.Millisecond
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			Expect(managementClient.Create(context.Background(), &connectivityv1alpha1.GatewayDNS{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "deleted-gateway-dns"},
+			})).To(Succeed())
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			var gatewayDNS connectivityv1alpha1.GatewayDNS
+			Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: "some-namespace", Name: "deleted-gateway-dns"}, &gatewayDNS)).To(Succeed())
+			Expect(managementClient.Delete(context.Background(), &gatewayDNS)).To(Succeed())
+			time.Sleep(60 * time.Millisecond)
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			Expect(endpointSliceNames()).To(ContainElements("deleted-gateway-dns", "legacy-deleted-gateway-dns"))
+		})
+	})
+
+	Context("when dry run is enabled", func() {
+		It("does not delete them", func() {
+			orphanCollector.DryRun = true
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+			time.Sleep(2 * time.Millisecond)
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			Expect(endpointSliceNames()).To(HaveLen(6))
+		})
+	})
+
+	Context("when a cluster becomes unreachable", func() {
+		It("keeps its orphaned EndpointSlices gauge", func() {
+			orphanCollector.GracePeriod = time.Hour
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+			orphaned, ok := orphanedEndpointSlicesGauge("some-namespace/some-workload-cluster")
+			Expect(ok).To(BeTrue())
+			Expect(orphaned).To(BeNumerically(">", 0))
+
+			delete(clusterClients, "some-namespace/some-workload-cluster")
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			unreachableOrphaned, ok := orphanedEndpointSlicesGauge("some-namespace/some-workload-cluster")
+			Expect(ok).To(BeTrue())
+			Expect(unreachableOrphaned).To(Equal(orphaned))
+		})
+	})
+
+	Context("when a cluster is removed from the inventory", func() {
+		It("deletes its orphaned EndpointSlices gauge", func() {
+			orphanCollector.GracePeriod = time.Hour
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+			_, ok := orphanedEndpointSlicesGauge("some-namespace/some-workload-cluster")
+			Expect(ok).To(BeTrue())
+
+			Expect(managementClient.Delete(context.Background(), &clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "some-workload-cluster"},
+			})).To(Succeed())
+			Expect(orphanCollector.Collect(context.Background())).To(Succeed())
+
+			_, ok = orphanedEndpointSlicesGauge("some-namespace/some-workload-cluster")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when listing the GatewayDNS fails", func() {
+		It("does not delete anything", func() {
+			failingClient := &gatewaydnsfakes.FakeClient{}
+			failingClient.ListReturns(errors.New("management cluster unreachable"))
+			orphanCollector.Client = failingClient
+
+			Expect(orphanCollector.Collect(context.Background())).To(MatchError("management cluster unreachable"))
+			Expect(endpointSliceNames()).To(HaveLen(6))
+		})
+	})
+})
+
+// orphanedEndpointSlicesGauge returns the xcc_dns_controller_orphaned_endpointslices
+// gauge of the cluster, and whether it exists.
+func orphanedEndpointSlicesGauge(cluster string) (float64, bool) {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != "xcc_dns_controller_orphaned_endpointslices" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "cluster" && label.GetValue() == cluster {
+					return metric.GetGauge().GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
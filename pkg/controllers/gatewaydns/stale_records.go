@@ -0,0 +1,67 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gatewaydns
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+)
+
+// withdrawStaleGateways records in the status of the GatewayDNS since when
+// the cluster of each unreachable gateway could not be reached, and leaves out
+// of the returned gateways those unreachable for longer than its
+// staleRecordTTL, so that their records are withdrawn. It returns whether the
+// status changed.
+func withdrawStaleGateways(log logr.Logger, gatewayDNS *connectivityv1alpha1.GatewayDNS, clusterGateways []ClusterGateway, now time.Time) ([]ClusterGateway, bool) {
+	previous := make(map[string]connectivityv1alpha1.UnreachableGateway, len(gatewayDNS.Status.UnreachableGateways))
+	for _, unreachableGateway := range gatewayDNS.Status.UnreachableGateways {
+		previous[unreachableGateway.Cluster] = unreachableGateway
+	}
+
+	var desired []ClusterGateway
+	var unreachableGateways []connectivityv1alpha1.UnreachableGateway
+	for _, clusterGateway := range clusterGateways {
+		if !clusterGateway.Unreachable {
+			desired = append(desired, clusterGateway)
+			continue
+		}
+
+		cluster := clusterGateway.ClusterNamespacedName.String()
+		unreachableGateway, ok := previous[cluster]
+		if !ok {
+			unreachableGateway = connectivityv1alpha1.UnreachableGateway{
+				Cluster: cluster,
+				Since:   metav1.NewTime(now).Rfc3339Copy(),
+			}
+		}
+
+		ttl := gatewayDNS.Spec.StaleRecordTTL
+		if ttl != nil && now.Sub(unreachableGateway.Since.Time) > ttl.Duration {
+			if !unreachableGateway.Withdrawn {
+				log.Info("Withdrawing the records of a gateway unreachable for longer than the staleRecordTTL", "Cluster", cluster, "UnreachableSince", unreachableGateway.Since, "StaleRecordTTL", ttl.Duration)
+			}
+			unreachableGateway.Withdrawn = true
+		} else {
+			unreachableGateway.Withdrawn = false
+			desired = append(desired, clusterGateway)
+		}
+		unreachableGateways = append(unreachableGateways, unreachableGateway)
+	}
+	sort.Slice(unreachableGateways, func(i, j int) bool {
+		return unreachableGateways[i].Cluster < unreachableGateways[j].Cluster
+	})
+
+	if len(unreachableGateways) == 0 && len(gatewayDNS.Status.UnreachableGateways) == 0 {
+		return desired, false
+	}
+	changed := !reflect.DeepEqual(unreachableGateways, gatewayDNS.Status.UnreachableGateways)
+	gatewayDNS.Status.UnreachableGateways = unreachableGateways
+	return desired, changed
+}
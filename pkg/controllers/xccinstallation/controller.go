@@ -0,0 +1,290 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package xccinstallation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/consumer"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/gatewaydns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
+)
+
+// XCCInstallationReconciler installs the dns-server and the
+// dns-config-patcher on the clusters matched by each XCCInstallation, and
+// upgrades them when its version changes.
+type XCCInstallationReconciler struct {
+	client.Client
+	Log            logr.Logger
+	ClientProvider clientProvider
+
+	// Inventory lists the clusters, defaults to the Cluster API Clusters.
+	Inventory inventory.Lister
+
+	// WatchRegisteredClusters reconciles the XCCInstallations matching a
+	// RegisteredCluster when it changes. The RegisteredCluster CRD must be
+	// installed.
+	WatchRegisteredClusters bool
+
+	// DryRun logs the clusters the dns-server would be installed on instead
+	// of installing it.
+	DryRun bool
+
+	// Namespace the dns-server is installed in. It must be the namespace
+	// xcc-dns-controller publishes EndpointSlices to.
+	Namespace string
+
+	// ResyncInterval between two checks that the dns-server is still
+	// installed on the clusters, defaults to 10 minutes.
+	ResyncInterval time.Duration
+}
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . clientProvider
+type clientProvider interface {
+	GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error)
+}
+
+// +kubebuilder:rbac:groups=connectivity.tanzu.vmware.com,resources=xccinstallations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=connectivity.tanzu.vmware.com,resources=xccinstallations/status,verbs=get;update;patch
+
+func (r *XCCInstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("XCCInstallation", req.NamespacedName)
+	log.Info("Start Reconciling")
+
+	var xccInstallation connectivityv1alpha1.XCCInstallation
+	if err := r.Client.Get(ctx, req.NamespacedName, &xccInstallation); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get XCCInstallation")
+		return ctrl.Result{}, err
+	}
+	if !xccInstallation.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&xccInstallation.Spec.ClusterSelector)
+	if err != nil {
+		log.Error(err, "Encountered invalid Selector as LabelSelector")
+		return ctrl.Result{}, err
+	}
+	clusters, err := inventory.WithDefault(r.Inventory, r.Client).ListClusters(ctx,
+		client.InNamespace(xccInstallation.Namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		log.Error(err, "Failed to list matching Clusters")
+		return ctrl.Result{}, err
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+
+	previous := make(map[string]connectivityv1alpha1.ClusterInstallation, len(xccInstallation.Status.Clusters))
+	for _, installation := range xccInstallation.Status.Clusters {
+		previous[installation.Cluster] = installation
+	}
+
+	var installations []connectivityv1alpha1.ClusterInstallation
+	var notInstalled, failed []string
+	for _, cluster := range clusters {
+		clusterNamespacedName := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}
+		clusterLog := log.WithValues("Cluster", clusterNamespacedName.String())
+
+		installation, ok := previous[clusterNamespacedName.String()]
+		if !ok {
+			installation = connectivityv1alpha1.ClusterInstallation{Cluster: clusterNamespacedName.String()}
+		}
+
+		switch {
+		case gatewaydns.IsClusterDeleting(cluster):
+			clusterLog.Info("Skipping Cluster", "Reason", "Cluster is being deleted")
+		case gatewaydns.IsClusterPaused(cluster):
+			clusterLog.Info("Skipping Cluster", "Reason", "Cluster is paused")
+		default:
+			if err := r.ensureInstalled(ctx, clusterLog, xccInstallation, clusterNamespacedName, &installation); err != nil {
+				failed = append(failed, installation.Cluster)
+			}
+		}
+
+		if !isUpToDate(installation, xccInstallation) {
+			notInstalled = append(notInstalled, installation.Cluster)
+		}
+		installations = append(installations, installation)
+	}
+
+	status := xccInstallation.Status.DeepCopy()
+	status.Clusters = installations
+	condition := metav1.Condition{
+		Type:               connectivityv1alpha1.InstalledCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Installed",
+		Message:            fmt.Sprintf("Version %s is installed on %d clusters", xccInstallation.Spec.Version, len(installations)),
+		ObservedGeneration: xccInstallation.Generation,
+	}
+	if len(notInstalled) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NotInstalled"
+		condition.Message = fmt.Sprintf("Version %s is not installed on %d of %d clusters: %s",
+			xccInstallation.Spec.Version, len(notInstalled), len(installations), strings.Join(notInstalled, ", "))
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	if !reflect.DeepEqual(status, &xccInstallation.Status) {
+		xccInstallation.Status = *status
+		if err := r.Client.Status().Update(ctx, &xccInstallation); err != nil {
+			log.Error(err, "Failed to update status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if len(failed) > 0 {
+		return ctrl.Result{}, fmt.Errorf("failed to install on clusters %s", strings.Join(failed, ", "))
+	}
+	log.Info("Finished Reconciling")
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
+}
+
+// ensureInstalled installs the XCCInstallation on the cluster unless the
+// installation already has its generation and the namespace still exists.
+func (r *XCCInstallationReconciler) ensureInstalled(ctx context.Context, log logr.Logger,
+	xccInstallation connectivityv1alpha1.XCCInstallation, cluster types.NamespacedName, installation *connectivityv1alpha1.ClusterInstallation) error {
+	clusterClient, err := r.ClientProvider.GetClient(ctx, cluster)
+	if err != nil {
+		log.Error(err, "Failed to get Cluster client")
+		installation.Error = err.Error()
+		return err
+	}
+
+	// The error of a cluster that could not be reached does not mean that
+	// the installation is gone.
+	if installation.LastInstallTime != nil && installation.ObservedGeneration == xccInstallation.Generation {
+		var namespace corev1.Namespace
+		err := clusterClient.Get(ctx, client.ObjectKey{Name: r.Namespace}, &namespace)
+		if err == nil {
+			installation.Error = ""
+			return nil
+		}
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "Failed to get namespace")
+			installation.Error = err.Error()
+			return err
+		}
+		log.Info("Namespace is gone, installing again", "Namespace", r.Namespace)
+	}
+
+	if r.DryRun {
+		log.Info("Would install", "Version", xccInstallation.Spec.Version, "PreviousVersion", installation.Version)
+		return nil
+	}
+
+	installer := &consumer.Installer{
+		Options: consumer.Options{
+			Namespace:       r.Namespace,
+			DomainSuffix:    xccInstallation.Spec.DomainSuffix,
+			Version:         xccInstallation.Spec.Version,
+			ImageRepository: xccInstallation.Spec.ImageRepository,
+		},
+		FieldManager: gatewaydns.FieldManager,
+	}
+	if err := installer.Install(ctx, clusterClient); err != nil {
+		log.Error(err, "Failed to install", "Version", xccInstallation.Spec.Version)
+		installation.Error = err.Error()
+		return err
+	}
+
+	log.Info("Installed", "Version", xccInstallation.Spec.Version, "PreviousVersion", installation.Version)
+	now := metav1.NewTime(time.Now()).Rfc3339Copy()
+	installation.Version = xccInstallation.Spec.Version
+	installation.ObservedGeneration = xccInstallation.Generation
+	installation.LastInstallTime = &now
+	installation.Error = ""
+	return nil
+}
+
+func (r *XCCInstallationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&connectivityv1alpha1.XCCInstallation{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &clusterv1beta1.Cluster{}},
+			handler.EnqueueRequestsFromMapFunc(r.ClusterToXCCInstallation),
+		)
+	if r.WatchRegisteredClusters {
+		b = b.Watches(
+			&source.Kind{Type: &connectivityv1alpha1.RegisteredCluster{}},
+			handler.EnqueueRequestsFromMapFunc(r.ClusterToXCCInstallation),
+		)
+	}
+	return b.Complete(r)
+}
+
+// ClusterToXCCInstallation returns the XCCInstallations that match the
+// Cluster.
+func (r *XCCInstallationReconciler) ClusterToXCCInstallation(o client.Object) []reconcile.Request {
+	log := r.Log.WithName("ClusterToXCCInstallation")
+	var xccInstallationList connectivityv1alpha1.XCCInstallationList
+	err := r.Client.List(
+		context.Background(),
+		&xccInstallationList,
+		client.InNamespace(o.GetNamespace()),
+	)
+	if err != nil {
+		log.Error(err, "Failed to list XCCInstallation")
+		return nil
+	}
+
+	matchingXCCInstallations := []reconcile.Request{}
+	clusterLabels := labels.Set(o.GetLabels())
+	for _, xccInstallation := range xccInstallationList.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&xccInstallation.Spec.ClusterSelector)
+		if err != nil {
+			log.Error(err, "Encountered invalid Selector as LabelSelector", "XCCInstallation", fmt.Sprintf("%s/%s", xccInstallation.Namespace, xccInstallation.Name))
+			continue
+		}
+		if selector.Matches(clusterLabels) {
+			matchingXCCInstallations = append(matchingXCCInstallations, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      xccInstallation.Name,
+					Namespace: xccInstallation.Namespace,
+				},
+			})
+		}
+	}
+	return matchingXCCInstallations
+}
+
+func (r *XCCInstallationReconciler) resyncInterval() time.Duration {
+	if r.ResyncInterval == 0 {
+		return 10 * time.Minute
+	}
+	return r.ResyncInterval
+}
+
+// isUpToDate returns whether the current generation of the XCCInstallation
+// was installed on the cluster.
+func isUpToDate(installation connectivityv1alpha1.ClusterInstallation, xccInstallation connectivityv1alpha1.XCCInstallation) bool {
+	return installation.LastInstallTime != nil &&
+		installation.ObservedGeneration == xccInstallation.Generation &&
+		installation.Error == ""
+}
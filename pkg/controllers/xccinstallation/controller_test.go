@@ -0,0 +1,327 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package xccinstallation_test
+
+import (
+	"context"
+	"errors"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/xccinstallation"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/xccinstallation/xccinstallationfakes"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/internal/applytest"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Controller", func() {
+	var (
+		managementClient client.Client
+		clusterClientA   *applytest.Client
+		clusterClientB   *applytest.Client
+		clusterClients   map[string]client.Client
+
+		reconciler      *xccinstallation.XCCInstallationReconciler
+		xccInstallation *connectivityv1alpha1.XCCInstallation
+		req             reconcile.Request
+
+		namespace string
+	)
+
+	dnsServerImage := func(clusterClient client.Client) string {
+		var deployment appsv1.Deployment
+		Expect(clusterClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "dns-server"}, &deployment)).To(Succeed())
+		return deployment.Spec.Template.Spec.Containers[0].Image
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = connectivityv1alpha1.AddToScheme(scheme)
+		_ = clusterv1beta1.AddToScheme(scheme)
+
+		namespace = "xcc-dns"
+
+		managementClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		clusterClientA = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+		clusterClientB = applytest.NewClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+		clusterClients = map[string]client.Client{
+			"some-namespace/cluster-a": clusterClientA,
+			"some-namespace/cluster-b": clusterClientB,
+		}
+
+		clientProvider := &xccinstallationfakes.FakeClientProvider{}
+		clientProvider.GetClientStub = func(ctx context.Context, namespacedName types.NamespacedName) (client.Client, error) {
+			clusterClient, ok := clusterClients[namespacedName.String()]
+			if !ok {
+				return nil, errors.New("cluster unreachable")
+			}
+			return clusterClient, nil
+		}
+
+		ctrl.SetLogger(zap.New(
+			zap.UseDevMode(true),
+			zap.WriteTo(GinkgoWriter),
+		))
+
+		reconciler = &xccinstallation.XCCInstallationReconciler{
+			Client:         managementClient,
+			Log:            ctrl.Log.WithName("controllers").WithName("XCCInstallation"),
+			ClientProvider: clientProvider,
+			Namespace:      namespace,
+		}
+
+		for _, cluster := range []*clusterv1beta1.Cluster{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-a", Labels: map[string]string{"xcc": "true"}}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-b", Labels: map[string]string{"xcc": "true"}}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-c"}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "some-other-namespace", Name: "cluster-a", Labels: map[string]string{"xcc": "true"}}},
+		} {
+			Expect(managementClient.Create(context.Background(), cluster)).To(Succeed())
+		}
+
+		xccInstallation = &connectivityv1alpha1.XCCInstallation{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "some-namespace",
+				Name:       "some-xcc-installation",
+				Generation: 1,
+			},
+			Spec: connectivityv1alpha1.XCCInstallationSpec{
+				ClusterSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{"xcc": "true"},
+				},
+				DomainSuffix: "xcc.test",
+				Version:      "v1.0.0",
+			},
+		}
+		Expect(managementClient.Create(context.Background(), xccInstallation)).To(Succeed())
+		req = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "some-namespace", Name: "some-xcc-installation"}}
+	})
+
+	Describe("Reconcile", func() {
+		It("installs the version on every matching cluster", func() {
+			_, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dnsServerImage(clusterClientA)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.0.0"))
+			Expect(dnsServerImage(clusterClientB)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.0.0"))
+
+			var job batchv1.Job
+			Expect(clusterClientA.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "dns-config-patcher"}, &job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "DOMAIN_SUFFIX", Value: "xcc.test"}))
+		})
+
+		It("records the installed version per cluster in the status", func() {
+			_, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+			Expect(xccInstallation.Status.Clusters).To(HaveLen(2))
+			for i, cluster := range []string{"some-namespace/cluster-a", "some-namespace/cluster-b"} {
+				installation := xccInstallation.Status.Clusters[i]
+				Expect(installation.Cluster).To(Equal(cluster))
+				Expect(installation.Version).To(Equal("v1.0.0"))
+				Expect(installation.ObservedGeneration).To(Equal(int64(1)))
+				Expect(installation.LastInstallTime).NotTo(BeNil())
+				Expect(installation.Error).To(BeEmpty())
+			}
+			Expect(meta.IsStatusConditionTrue(xccInstallation.Status.Conditions, connectivityv1alpha1.InstalledCondition)).To(BeTrue())
+		})
+
+		It("does not install again when the version is installed", func() {
+			_, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+			applies := clusterClientA.Applies["Job"]
+
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterClientA.Applies["Job"]).To(Equal(applies))
+		})
+
+		Context("when the version changes", func() {
+			BeforeEach(func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				xccInstallation.Spec.Version = "v1.1.0"
+				xccInstallation.Generation = 2
+				Expect(managementClient.Update(context.Background(), xccInstallation)).To(Succeed())
+			})
+
+			It("upgrades every matching cluster", func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dnsServerImage(clusterClientA)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.1.0"))
+				Expect(dnsServerImage(clusterClientB)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.1.0"))
+				Expect(clusterClientA.Applies["Job"]).To(Equal(2))
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				for _, installation := range xccInstallation.Status.Clusters {
+					Expect(installation.Version).To(Equal("v1.1.0"))
+					Expect(installation.ObservedGeneration).To(Equal(int64(2)))
+				}
+			})
+		})
+
+		Context("when the namespace is deleted from a cluster", func() {
+			It("installs again", func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(clusterClientA.Delete(context.Background(), &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				})).To(Succeed())
+				_, err = reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(clusterClientA.Applies["Namespace"]).To(Equal(2))
+				Expect(clusterClientB.Applies["Namespace"]).To(Equal(1))
+			})
+		})
+
+		Context("when a cluster cannot be reached", func() {
+			BeforeEach(func() {
+				delete(clusterClients, "some-namespace/cluster-b")
+			})
+
+			It("installs on the other clusters and reports the cluster in the status", func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).To(MatchError("failed to install on clusters some-namespace/cluster-b"))
+
+				Expect(dnsServerImage(clusterClientA)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.0.0"))
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				Expect(xccInstallation.Status.Clusters[1].Cluster).To(Equal("some-namespace/cluster-b"))
+				Expect(xccInstallation.Status.Clusters[1].Error).To(Equal("cluster unreachable"))
+				Expect(xccInstallation.Status.Clusters[1].Version).To(BeEmpty())
+				condition := meta.FindStatusCondition(xccInstallation.Status.Conditions, connectivityv1alpha1.InstalledCondition)
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Message).To(Equal("Version v1.0.0 is not installed on 1 of 2 clusters: some-namespace/cluster-b"))
+			})
+
+			It("does not install again once the cluster is reachable if it was installed", func() {
+				clusterClients["some-namespace/cluster-b"] = clusterClientB
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				delete(clusterClients, "some-namespace/cluster-b")
+				_, err = reconciler.Reconcile(context.Background(), req)
+				Expect(err).To(HaveOccurred())
+
+				clusterClients["some-namespace/cluster-b"] = clusterClientB
+				_, err = reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(clusterClientB.Applies["Job"]).To(Equal(1))
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				Expect(xccInstallation.Status.Clusters[1].Error).To(BeEmpty())
+			})
+		})
+
+		Context("when a cluster is paused", func() {
+			BeforeEach(func() {
+				var cluster clusterv1beta1.Cluster
+				Expect(managementClient.Get(context.Background(), types.NamespacedName{Namespace: "some-namespace", Name: "cluster-b"}, &cluster)).To(Succeed())
+				cluster.Spec.Paused = true
+				Expect(managementClient.Update(context.Background(), &cluster)).To(Succeed())
+			})
+
+			It("does not install on it", func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(clusterClientB.Applies).To(BeEmpty())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				Expect(meta.IsStatusConditionFalse(xccInstallation.Status.Conditions, connectivityv1alpha1.InstalledCondition)).To(BeTrue())
+			})
+		})
+
+		Context("when it is a dry run", func() {
+			BeforeEach(func() {
+				reconciler.DryRun = true
+			})
+
+			It("does not install on the clusters", func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(clusterClientA.Applies).To(BeEmpty())
+				Expect(clusterClientB.Applies).To(BeEmpty())
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				Expect(xccInstallation.Status.Clusters).To(HaveLen(2))
+				Expect(xccInstallation.Status.Clusters[0].LastInstallTime).To(BeNil())
+				Expect(meta.IsStatusConditionFalse(xccInstallation.Status.Conditions, connectivityv1alpha1.InstalledCondition)).To(BeTrue())
+			})
+		})
+
+		Context("when a matching cluster is registered with a RegisteredCluster", func() {
+			var clusterClientEKS *applytest.Client
+
+			BeforeEach(func() {
+				Expect(managementClient.Create(context.Background(), &connectivityv1alpha1.RegisteredCluster{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "eks-cluster", Labels: map[string]string{"xcc": "true"}},
+					Spec: connectivityv1alpha1.RegisteredClusterSpec{
+						KubeconfigSecretRef: connectivityv1alpha1.SecretKeyReference{Name: "eks-cluster-kubeconfig"},
+					},
+				})).To(Succeed())
+				clusterClientEKS = applytest.NewClient(fake.NewClientBuilder().Build())
+				clusterClients["some-namespace/eks-cluster"] = clusterClientEKS
+
+				reconciler.Inventory = inventory.Inventory{
+					inventory.Registered{Client: managementClient},
+					inventory.ClusterAPI{Client: managementClient},
+				}
+			})
+
+			It("installs on it like on the Cluster API Clusters", func() {
+				_, err := reconciler.Reconcile(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dnsServerImage(clusterClientEKS)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.0.0"))
+				Expect(dnsServerImage(clusterClientA)).To(Equal("gcr.io/tanzu-xcc/dns-server:v1.0.0"))
+
+				Expect(managementClient.Get(context.Background(), req.NamespacedName, xccInstallation)).To(Succeed())
+				var clusters []string
+				for _, installation := range xccInstallation.Status.Clusters {
+					clusters = append(clusters, installation.Cluster)
+				}
+				Expect(clusters).To(Equal([]string{"some-namespace/cluster-a", "some-namespace/cluster-b", "some-namespace/eks-cluster"}))
+			})
+		})
+	})
+
+	Describe("ClusterToXCCInstallation", func() {
+		It("returns the XCCInstallations that match the Cluster", func() {
+			requests := reconciler.ClusterToXCCInstallation(&clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-a", Labels: map[string]string{"xcc": "true"}},
+			})
+			Expect(requests).To(ConsistOf(req))
+
+			requests = reconciler.ClusterToXCCInstallation(&clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-c"},
+			})
+			Expect(requests).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,16 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package xccinstallation_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestXCCInstallation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "XCCInstallation Suite")
+}
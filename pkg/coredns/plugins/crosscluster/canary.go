@@ -0,0 +1,27 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"github.com/miekg/dns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+)
+
+// canaryText is the content of the canary TXT record.
+const canaryText = "xcc-dns"
+
+// canaryName is the name the dns-config-patcher queries through the cluster
+// DNS to verify that the zone is forwarded to the dns-server.
+func canaryName(zone string) string {
+	return dnsconfig.CanaryName(zone)
+}
+
+// canaryRecords returns the canary TXT record of the zone. It has no TTL so
+// that resolvers never answer it from their cache.
+func canaryRecords(zone string) []dns.RR {
+	return []dns.RR{&dns.TXT{
+		Hdr: dns.RR_Header{Name: canaryName(zone), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0},
+		Txt: []string{canaryText},
+	}}
+}
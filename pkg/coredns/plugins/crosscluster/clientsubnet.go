@@ -0,0 +1,132 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// Locality maps a client network to the region the clients in it are in.
+type Locality struct {
+	Region  string
+	Network *net.IPNet
+}
+
+// clientSubnet returns the EDNS Client Subnet option of the request, if any.
+func clientSubnet(r *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return subnet
+		}
+	}
+	return nil
+}
+
+// clientLocality returns the locality of the client. It uses the EDNS Client
+// Subnet of the request when present (RFC 7871) and the source address of the
+// request otherwise, and picks the most specific locality containing it.
+//
+// scope is the prefix length of the client networks the answer is valid
+// for, to be echoed in the Client Subnet option of the response.
+func (c *CrossCluster) clientLocality(state request.Request) (locality Locality, scope uint8, ok bool) {
+	if len(c.Localities) == 0 {
+		return Locality{}, 0, false
+	}
+
+	ip := net.ParseIP(state.IP())
+	ones := 8 * net.IPv6len
+	if ip != nil && ip.To4() != nil {
+		ip = ip.To4()
+		ones = 8 * net.IPv4len
+	}
+	if subnet := clientSubnet(state.Req); subnet != nil {
+		if subnet.SourceNetmask == 0 {
+			// The client asked not to reveal any part of its address.
+			return Locality{}, 0, false
+		}
+		address, bits := subnet.Address.To16(), 8*net.IPv6len
+		if subnet.Family == 1 {
+			address, bits = subnet.Address.To4(), 8*net.IPv4len
+		}
+		if address == nil || int(subnet.SourceNetmask) > bits {
+			return Locality{}, 0, false
+		}
+		ones = int(subnet.SourceNetmask)
+		ip = address.Mask(net.CIDRMask(ones, bits))
+		scope = subnet.SourceNetmask
+	}
+	if ip == nil {
+		return Locality{}, 0, false
+	}
+
+	matchedOnes := -1
+	for _, candidate := range c.Localities {
+		candidateOnes, _ := candidate.Network.Mask.Size()
+		if candidateOnes <= ones && candidateOnes > matchedOnes && candidate.Network.Contains(ip) {
+			locality, matchedOnes, ok = candidate, candidateOnes, true
+		}
+	}
+	if !ok {
+		return Locality{}, scope, false
+	}
+
+	// The answer holds for the whole matched network, unless a more specific
+	// locality inside it covers some of its clients.
+	for _, candidate := range c.Localities {
+		candidateOnes, _ := candidate.Network.Mask.Size()
+		if candidateOnes > matchedOnes && locality.Network.Contains(candidate.Network.IP) {
+			return locality, scope, true
+		}
+	}
+	if scope != 0 {
+		scope = uint8(matchedOnes)
+	}
+	return locality, scope, true
+}
+
+// clientTopology returns the topology answers are ordered for: the region of
+// the client when its locality is known, and the topology of the dns-server
+// otherwise.
+func (c *CrossCluster) clientTopology(state request.Request) Topology {
+	if locality, _, ok := c.clientLocality(state); ok {
+		if locality.Region == c.Topology.Region {
+			return c.Topology
+		}
+		return Topology{Region: locality.Region}
+	}
+	return c.Topology
+}
+
+// setClientSubnet echoes the EDNS Client Subnet option of the request in the
+// response with the given scope prefix length, as required by RFC 7871.
+func setClientSubnet(state request.Request, m *dns.Msg, scope uint8) {
+	subnet := clientSubnet(state.Req)
+	if subnet == nil {
+		return
+	}
+
+	opt := m.IsEdns0()
+	if opt == nil {
+		opt = &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+		opt.SetUDPSize(state.Req.IsEdns0().UDPSize())
+		if state.Do() {
+			opt.SetDo()
+		}
+		m.Extra = append(m.Extra, opt)
+	}
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        subnet.Family,
+		SourceNetmask: subnet.SourceNetmask,
+		SourceScope:   scope,
+		Address:       subnet.Address,
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster_test
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/coredns/plugins/crosscluster"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EDNS Client Subnet", func() {
+	var (
+		dnsCache  *endpointslicedns.DNSCache
+		dnsPlugin *crosscluster.CrossCluster
+	)
+
+	locality := func(region, cidr string) crosscluster.Locality {
+		_, network, err := net.ParseCIDR(cidr)
+		Expect(err).NotTo(HaveOccurred())
+		return crosscluster.Locality{Region: region, Network: network}
+	}
+
+	// query sends an A query from 10.240.0.1 with the given client subnet,
+	// or none when cidr is empty.
+	query := func(name, cidr string) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		if cidr != "" {
+			ip, network, err := net.ParseCIDR(cidr)
+			Expect(err).NotTo(HaveOccurred())
+			ones, _ := network.Mask.Size()
+			subnet := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: uint8(ones), Address: ip.To4()}
+			if ip.To4() == nil {
+				subnet.Family, subnet.Address = 2, ip
+			}
+			r.SetEdns0(4096, false)
+			r.IsEdns0().Option = append(r.IsEdns0().Option, subnet)
+		}
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := dnsPlugin.ServeDNS(context.Background(), w, r)
+		Expect(err).NotTo(HaveOccurred())
+		return w.Msg
+	}
+
+	answerAddresses := func(m *dns.Msg) []string {
+		var addresses []string
+		for _, answer := range m.Answer {
+			addresses = append(addresses, answer.(*dns.A).A.String())
+		}
+		return addresses
+	}
+
+	responseSubnet := func(m *dns.Msg) *dns.EDNS0_SUBNET {
+		opt := m.IsEdns0()
+		if opt == nil {
+			return nil
+		}
+		for _, option := range opt.Option {
+			if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+				return subnet
+			}
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		dnsCache = &endpointslicedns.DNSCache{}
+		dnsPlugin = &crosscluster.CrossCluster{
+			RecordsCache: dnsCache,
+			Zones:        []string{"xcc.test."},
+			Log:          ctrl.Log.WithName("dnsserver"),
+			Topology:     crosscluster.Topology{Region: "us-east-1"},
+			Localities: []crosscluster.Locality{
+				locality("eu-west-1", "198.51.100.0/22"),
+			},
+		}
+
+		dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+			ResourceKey: "xcc-dns/eu",
+			FQDN:        "*.gateway.xcc.test",
+			Addresses:   []string{"2.2.2.2"},
+			Region:      "eu-west-1",
+		})
+		dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+			ResourceKey: "xcc-dns/us",
+			FQDN:        "*.gateway.xcc.test",
+			Addresses:   []string{"1.1.1.1"},
+			Region:      "us-east-1",
+		})
+	})
+
+	It("orders answers for the topology of the dns-server without a client subnet", func() {
+		m := query("foo.gateway.xcc.test.", "")
+		Expect(answerAddresses(m)).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
+		Expect(responseSubnet(m)).To(BeNil())
+	})
+
+	It("orders answers for the region of the client subnet and echoes the scope", func() {
+		m := query("foo.gateway.xcc.test.", "198.51.101.0/24")
+		Expect(answerAddresses(m)).To(Equal([]string{"2.2.2.2", "1.1.1.1"}))
+
+		subnet := responseSubnet(m)
+		Expect(subnet).NotTo(BeNil())
+		Expect(subnet.Family).To(Equal(uint16(1)))
+		Expect(subnet.SourceNetmask).To(Equal(uint8(24)))
+		Expect(subnet.SourceScope).To(Equal(uint8(22)))
+		Expect(subnet.Address.String()).To(Equal("198.51.101.0"))
+	})
+
+	It("scopes answers to the client subnet when no locality contains it", func() {
+		m := query("foo.gateway.xcc.test.", "192.0.2.0/24")
+		Expect(answerAddresses(m)).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
+		Expect(responseSubnet(m).SourceScope).To(Equal(uint8(24)))
+	})
+
+	It("scopes answers to the client subnet when a more specific locality is nested in the match", func() {
+		dnsPlugin.Localities = append(dnsPlugin.Localities, locality("us-east-1", "198.51.103.0/24"))
+
+		m := query("foo.gateway.xcc.test.", "198.51.101.0/24")
+		Expect(answerAddresses(m)).To(Equal([]string{"2.2.2.2", "1.1.1.1"}))
+		Expect(responseSubnet(m).SourceScope).To(Equal(uint8(24)))
+
+		m = query("foo.gateway.xcc.test.", "198.51.103.0/24")
+		Expect(answerAddresses(m)).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
+		Expect(responseSubnet(m).SourceScope).To(Equal(uint8(24)))
+	})
+
+	It("ignores a client subnet with a source prefix length of 0", func() {
+		m := query("foo.gateway.xcc.test.", "0.0.0.0/0")
+		Expect(answerAddresses(m)).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
+		Expect(responseSubnet(m).SourceScope).To(BeZero())
+	})
+
+	It("uses the source address of the request without a client subnet", func() {
+		dnsPlugin.Localities = []crosscluster.Locality{locality("eu-west-1", "10.240.0.0/16")}
+
+		m := query("foo.gateway.xcc.test.", "")
+		Expect(answerAddresses(m)).To(Equal([]string{"2.2.2.2", "1.1.1.1"}))
+	})
+
+	It("only returns gateways in the region of the client when filtering by region", func() {
+		dnsPlugin.FilterByRegion = true
+
+		Expect(answerAddresses(query("foo.gateway.xcc.test.", "198.51.100.0/24"))).To(Equal([]string{"2.2.2.2"}))
+		Expect(answerAddresses(query("foo.gateway.xcc.test.", ""))).To(Equal([]string{"1.1.1.1"}))
+
+		dnsPlugin.Topology = crosscluster.Topology{Region: "ap-south-1"}
+		Expect(answerAddresses(query("foo.gateway.xcc.test.", ""))).To(Equal([]string{"2.2.2.2", "1.1.1.1"}))
+	})
+
+	It("serves the answers without a TTL, so that caches ignoring the client subnet do not keep them", func() {
+		for _, cidr := range []string{"", "198.51.100.0/24", "192.0.2.0/24"} {
+			m := query("foo.gateway.xcc.test.", cidr)
+			Expect(m.Answer).NotTo(BeEmpty())
+			for _, answer := range m.Answer {
+				Expect(answer.Header().Ttl).To(BeZero())
+			}
+		}
+	})
+
+	It("echoes the client subnet with a scope of 0 in negative answers", func() {
+		m := query("not-exists.xcc.test.", "198.51.100.0/24")
+		Expect(m.Rcode).To(Equal(dns.RcodeNameError))
+		subnet := responseSubnet(m)
+		Expect(subnet).NotTo(BeNil())
+		Expect(subnet.SourceNetmask).To(Equal(uint8(24)))
+		Expect(subnet.SourceScope).To(BeZero())
+	})
+})
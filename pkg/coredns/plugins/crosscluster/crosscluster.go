@@ -7,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"strings"
-	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
@@ -15,6 +14,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/miekg/dns"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type CrossCluster struct {
@@ -23,6 +23,44 @@ type CrossCluster struct {
 	RecordsCache *endpointslicedns.DNSCache
 	Zones        []string
 	Log          logr.Logger
+
+	// Nameservers are published as the NS records at the apex of each zone.
+	Nameservers []Nameserver
+
+	// NameserverAddresses are the glue addresses of the Nameservers that
+	// take them from a Service.
+	NameserverAddresses *NameserverAddresses
+
+	// Topology is where the dns-server runs. Gateways in the same zone, then
+	// the same region, are returned first.
+	Topology Topology
+
+	// Localities map client networks, from the EDNS Client Subnet of a
+	// request or its source address, to regions. Answers for clients in a
+	// known locality are ordered for its region instead of Topology.
+	Localities []Locality
+
+	// FilterByRegion only returns gateways in the region of the client when
+	// there are any.
+	FilterByRegion bool
+
+	// Views map client networks to the addresses they are served. Clients
+	// outside all views are served the default addresses.
+	Views []View
+
+	// MaxAnswers limits the number of addresses in an answer when greater
+	// than zero.
+	MaxAnswers int
+
+	// DNSSECSecret is the name of the Secret holding the keys used to sign
+	// responses. Responses are not signed when it is empty.
+	DNSSECSecret string
+	signingKeys  signingKeys
+	nsecChains   nsecChains
+
+	// Client and Namespace locate the DNSSEC Secret.
+	Client    client.Reader
+	Namespace string
 }
 
 var errNotImplemented = errors.New("not implemented")
@@ -39,19 +77,41 @@ func (c *CrossCluster) Services(ctx context.Context, state request.Request, exac
 		return nil, errNameNotFound
 	}
 
+	topology := c.clientTopology(state)
+	cacheEntries = orderEntries(cacheEntries, topology)
+	if c.FilterByRegion {
+		cacheEntries = filterRegion(cacheEntries, topology.Region)
+	}
+
+	view := c.view(state)
+	ttl := c.answerTTL()
 	services := []msg.Service{}
 	for _, cacheEntry := range cacheEntries {
-		for _, address := range cacheEntry.Addresses {
+		for _, address := range cacheEntry.AddressesForView(view) {
 			services = append(services, msg.Service{
 				Host: address,
-				TTL:  30,
+				TTL:  ttl,
 			})
 		}
 	}
+	if c.MaxAnswers > 0 && len(services) > c.MaxAnswers {
+		services = services[:c.MaxAnswers]
+	}
 
 	return services, nil
 }
 
+// answerTTL returns the TTL of the addresses. Answers that depend on the
+// client, through views or the locality of its source address or EDNS Client
+// Subnet, have none, so that caches, such as the cache plugin, which ignores
+// both, do not serve them to other clients.
+func (c *CrossCluster) answerTTL() uint32 {
+	if len(c.Views) > 0 || len(c.Localities) > 0 {
+		return 0
+	}
+	return 30
+}
+
 // Reverse communicates with the backend to retrieve service definition based on a IP address
 // instead of a name. I.e. a reverse DNS lookup.
 func (c *CrossCluster) Reverse(ctx context.Context, state request.Request, exact bool, opt plugin.Options) ([]msg.Service, error) {
@@ -77,9 +137,10 @@ func (c *CrossCluster) IsNameError(err error) bool {
 	return err == errNameNotFound
 }
 
-// Serial returns a SOA serial number to construct a SOA record.
+// Serial returns a SOA serial number to construct a SOA record. It only
+// changes when the records served from the cache change.
 func (c *CrossCluster) Serial(state request.Request) uint32 {
-	return uint32(time.Now().Unix())
+	return c.RecordsCache.Serial()
 }
 
 // MinTTL returns the minimum TTL to be used in the SOA record.
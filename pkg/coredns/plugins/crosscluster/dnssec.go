@@ -0,0 +1,389 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// zoneKey is a DNSKEY together with its private key.
+type zoneKey struct {
+	dnskey *dns.DNSKEY
+	signer crypto.Signer
+}
+
+// signingKeys caches the keys parsed from the DNSSEC Secret until the Secret
+// changes.
+type signingKeys struct {
+	mutex           sync.Mutex
+	resourceVersion string
+	keys            []zoneKey
+}
+
+// nsecChains caches the sorted NSEC chain of each zone until the serial of the
+// records cache changes.
+type nsecChains struct {
+	mutex  sync.Mutex
+	serial uint32
+	chains map[string][]string
+}
+
+func (c *CrossCluster) signingEnabled() bool {
+	return c.DNSSECSecret != ""
+}
+
+// zoneKeys returns the keys from the DNSSEC Secret that belong to zone.
+//
+// The Secret holds BIND style key pairs as produced by dnssec-keygen: every
+// data entry named "<prefix>.key" holds a DNSKEY record and is paired with
+// the private key in "<prefix>.private".
+func (c *CrossCluster) zoneKeys(ctx context.Context, zone string) ([]zoneKey, error) {
+	var secret corev1.Secret
+	err := c.Client.Get(ctx, client.ObjectKey{Namespace: c.Namespace, Name: c.DNSSECSecret}, &secret)
+	if err != nil {
+		return nil, err
+	}
+
+	c.signingKeys.mutex.Lock()
+	defer c.signingKeys.mutex.Unlock()
+
+	if c.signingKeys.keys == nil || c.signingKeys.resourceVersion != secret.ResourceVersion {
+		keys, err := parseKeys(secret)
+		if err != nil {
+			return nil, err
+		}
+		c.signingKeys.keys = keys
+		c.signingKeys.resourceVersion = secret.ResourceVersion
+	}
+
+	var keys []zoneKey
+	for _, key := range c.signingKeys.keys {
+		if key.dnskey.Hdr.Name == zone {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func parseKeys(secret corev1.Secret) ([]zoneKey, error) {
+	keys := []zoneKey{}
+	for name, data := range secret.Data {
+		if !strings.HasSuffix(name, ".key") {
+			continue
+		}
+		rr, err := dns.NewRR(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s in Secret %s/%s: %w", name, secret.Namespace, secret.Name, err)
+		}
+		dnskey, ok := rr.(*dns.DNSKEY)
+		if !ok {
+			return nil, fmt.Errorf("%s in Secret %s/%s is not a DNSKEY record", name, secret.Namespace, secret.Name)
+		}
+		dnskey.Hdr.Name = dns.CanonicalName(dnskey.Hdr.Name)
+
+		privateName := strings.TrimSuffix(name, ".key") + ".private"
+		privateData, ok := secret.Data[privateName]
+		if !ok {
+			return nil, fmt.Errorf("Secret %s/%s has no %s for %s", secret.Namespace, secret.Name, privateName, name)
+		}
+		privateKey, err := dnskey.ReadPrivateKey(bytes.NewReader(privateData), privateName)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s in Secret %s/%s: %w", privateName, secret.Namespace, secret.Name, err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s in Secret %s/%s cannot be used for signing", privateName, secret.Namespace, secret.Name)
+		}
+		keys = append(keys, zoneKey{dnskey: dnskey, signer: signer})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].dnskey.KeyTag() < keys[j].dnskey.KeyTag()
+	})
+	return keys, nil
+}
+
+func (c *CrossCluster) dnskeyRecords(ctx context.Context, zone string) ([]dns.RR, error) {
+	keys, err := c.zoneKeys(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	var records []dns.RR
+	for _, key := range keys {
+		dnskey := *key.dnskey
+		dnskey.Hdr.Ttl = c.MinTTL(request.Request{})
+		records = append(records, &dnskey)
+	}
+	return records, nil
+}
+
+// sign adds RRSIG records to every RRset inside the zone in the answer,
+// authority and additional sections of m.
+func (c *CrossCluster) sign(ctx context.Context, zone string, m *dns.Msg) error {
+	keys, err := c.zoneKeys(ctx, zone)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no DNSSEC keys for zone %s", zone)
+	}
+
+	// Backdate the inception to tolerate clock skew between resolvers.
+	now := time.Now()
+	inception := uint32(now.Add(-3 * time.Hour).Unix())
+	expiration := uint32(now.Add(7 * 24 * time.Hour).Unix())
+
+	for _, section := range []*[]dns.RR{&m.Answer, &m.Ns, &m.Extra} {
+		signed, err := signRRsets(*section, keys, zone, inception, expiration)
+		if err != nil {
+			return err
+		}
+		*section = signed
+	}
+	return nil
+}
+
+func signRRsets(records []dns.RR, keys []zoneKey, zone string, inception, expiration uint32) ([]dns.RR, error) {
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+	}
+	var order []rrsetKey
+	rrsets := map[rrsetKey][]dns.RR{}
+	for _, rr := range records {
+		header := rr.Header()
+		if header.Rrtype == dns.TypeOPT || header.Rrtype == dns.TypeRRSIG || !dns.IsSubDomain(zone, header.Name) {
+			continue
+		}
+		key := rrsetKey{name: dns.CanonicalName(header.Name), rtype: header.Rrtype}
+		if _, ok := rrsets[key]; !ok {
+			order = append(order, key)
+		}
+		rrsets[key] = append(rrsets[key], rr)
+	}
+
+	signed := records
+	for _, key := range order {
+		rrset := rrsets[key]
+		for _, signingKey := range keysFor(key.rtype, keys) {
+			sig := &dns.RRSIG{
+				Hdr:        dns.RR_Header{Ttl: rrset[0].Header().Ttl},
+				Algorithm:  signingKey.dnskey.Algorithm,
+				KeyTag:     signingKey.dnskey.KeyTag(),
+				SignerName: zone,
+				Inception:  inception,
+				Expiration: expiration,
+			}
+			if err := sig.Sign(signingKey.signer, rrset); err != nil {
+				return nil, err
+			}
+			signed = append(signed, sig)
+		}
+	}
+	return signed, nil
+}
+
+// keysFor returns the key signing keys for the DNSKEY RRset and the zone
+// signing keys for everything else. A zone with only one kind of key signs
+// everything with it.
+func keysFor(rtype uint16, keys []zoneKey) []zoneKey {
+	var ksks, zsks []zoneKey
+	for _, key := range keys {
+		if key.dnskey.Flags&dns.SEP != 0 {
+			ksks = append(ksks, key)
+		} else {
+			zsks = append(zsks, key)
+		}
+	}
+	if rtype == dns.TypeDNSKEY && len(ksks) > 0 || len(zsks) == 0 {
+		return ksks
+	}
+	return zsks
+}
+
+// denialOfExistence returns the NSEC records that prove a negative response
+// for name. The NSEC chain is made of the zone apex, the canary, the
+// nameservers and every name in the records cache.
+func (c *CrossCluster) denialOfExistence(ctx context.Context, zone string, rcode int, name string) []dns.RR {
+	chain := c.nsecChain(zone)
+
+	if rcode == dns.RcodeNameError {
+		records := []dns.RR{c.coveringNSEC(ctx, zone, chain, name)}
+		wildcard := "*." + c.closestEncloser(zone, name)
+		if wildcardNSEC := c.coveringNSEC(ctx, zone, chain, wildcard); wildcardNSEC.Hdr.Name != records[0].Header().Name {
+			records = append(records, wildcardNSEC)
+		}
+		return records
+	}
+
+	for i, owner := range chain {
+		if owner == name {
+			return []dns.RR{c.nsec(ctx, zone, owner, chain[(i+1)%len(chain)])}
+		}
+	}
+	if len(c.RecordsCache.Lookup(name)) > 0 {
+		// The name only exists through a wildcard. Answers for it are signed
+		// as if it existed, so deny the other types with an NSEC for the name
+		// that covers nothing else.
+		return []dns.RR{c.nsec(ctx, zone, name, `\000.`+name)}
+	}
+	// An empty non-terminal is proven by the NSEC whose next name is below it.
+	return []dns.RR{c.coveringNSEC(ctx, zone, chain, name)}
+}
+
+// nsecChain returns the names of the zone in canonical order. The chain is
+// shared and must not be modified.
+func (c *CrossCluster) nsecChain(zone string) []string {
+	// The serial is read before the names, so that a chain built while the
+	// records change is built again on the next call.
+	serial := c.RecordsCache.Serial()
+
+	c.nsecChains.mutex.Lock()
+	defer c.nsecChains.mutex.Unlock()
+
+	if c.nsecChains.chains == nil || c.nsecChains.serial != serial {
+		c.nsecChains.chains = map[string][]string{}
+		c.nsecChains.serial = serial
+	}
+	chain, ok := c.nsecChains.chains[zone]
+	if !ok {
+		chain = c.buildNSECChain(zone)
+		c.nsecChains.chains[zone] = chain
+	}
+	return chain
+}
+
+func (c *CrossCluster) buildNSECChain(zone string) []string {
+	chain := []string{zone, canaryName(zone)}
+	for _, name := range c.RecordsCache.Names() {
+		if name != zone && dns.IsSubDomain(zone, name) {
+			chain = append(chain, name)
+		}
+	}
+	for _, nameserver := range c.Nameservers {
+		if nameserver.Host != zone && dns.IsSubDomain(zone, nameserver.Host) {
+			chain = append(chain, nameserver.Host)
+		}
+	}
+	sort.Slice(chain, func(i, j int) bool {
+		return canonicalCompare(chain[i], chain[j]) < 0
+	})
+	return chain
+}
+
+func (c *CrossCluster) coveringNSEC(ctx context.Context, zone string, chain []string, name string) *dns.NSEC {
+	i := sort.Search(len(chain), func(i int) bool {
+		return canonicalCompare(chain[i], name) > 0
+	})
+	// The apex sorts before every name in the zone, so i is at least 1.
+	return c.nsec(ctx, zone, chain[i-1], chain[i%len(chain)])
+}
+
+func (c *CrossCluster) closestEncloser(zone, name string) string {
+	for name != zone {
+		end, _ := dns.NextLabel(name, 0)
+		name = name[end:]
+		if c.nameExists(zone, name) {
+			return name
+		}
+	}
+	return zone
+}
+
+func (c *CrossCluster) nsec(ctx context.Context, zone, owner, next string) *dns.NSEC {
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: c.MinTTL(request.Request{})},
+		NextDomain: next,
+		TypeBitMap: c.typesAt(ctx, zone, owner),
+	}
+}
+
+// typesAt returns the record types that exist at name, in ascending order.
+func (c *CrossCluster) typesAt(ctx context.Context, zone, name string) []uint16 {
+	types := map[uint16]bool{dns.TypeRRSIG: true, dns.TypeNSEC: true}
+	if name == zone {
+		types[dns.TypeSOA] = true
+		types[dns.TypeDNSKEY] = true
+		if len(c.Nameservers) > 0 {
+			types[dns.TypeNS] = true
+		}
+	}
+	if name == canaryName(zone) {
+		types[dns.TypeTXT] = true
+	}
+	if nameserver, ok := c.nameserver(name); ok {
+		for _, address := range c.nameserverAddresses(nameserver) {
+			if net.ParseIP(address).To4() != nil {
+				types[dns.TypeA] = true
+			} else {
+				types[dns.TypeAAAA] = true
+			}
+		}
+	}
+	for _, entry := range c.RecordsCache.Lookup(name) {
+		for _, address := range entry.Addresses {
+			if net.ParseIP(address) != nil {
+				types[dns.TypeA] = true
+			} else {
+				types[dns.TypeCNAME] = true
+			}
+		}
+	}
+
+	bitmap := make([]uint16, 0, len(types))
+	for t := range types {
+		bitmap = append(bitmap, t)
+	}
+	sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+	return bitmap
+}
+
+// canonicalCompare orders names as described in RFC 4034, Section 6.1: label
+// by label starting from the root, comparing lowercased labels as bytes.
+func canonicalCompare(a, b string) int {
+	aLabels := dns.SplitDomainName(dns.CanonicalName(a))
+	bLabels := dns.SplitDomainName(dns.CanonicalName(b))
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		if res := strings.Compare(unescapeLabel(aLabels[len(aLabels)-i]), unescapeLabel(bLabels[len(bLabels)-i])); res != 0 {
+			return res
+		}
+	}
+	return len(aLabels) - len(bLabels)
+}
+
+// unescapeLabel replaces \DDD escapes, such as the \000 label used in NSEC
+// next names, with the byte they represent.
+func unescapeLabel(label string) string {
+	if !strings.Contains(label, `\`) {
+		return label
+	}
+	var b strings.Builder
+	for i := 0; i < len(label); i++ {
+		if label[i] == '\\' && i+3 < len(label) && isDigit(label[i+1]) && isDigit(label[i+2]) && isDigit(label[i+3]) {
+			b.WriteByte((label[i+1]-'0')*100 + (label[i+2]-'0')*10 + (label[i+3] - '0'))
+			i += 3
+			continue
+		}
+		if label[i] == '\\' && i+1 < len(label) {
+			i++
+		}
+		b.WriteByte(label[i])
+	}
+	return b.String()
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
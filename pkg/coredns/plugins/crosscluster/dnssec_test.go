@@ -0,0 +1,227 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster_test
+
+import (
+	"context"
+	"crypto"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/coredns/plugins/crosscluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DNSSEC", func() {
+	var (
+		dnsCache  *endpointslicedns.DNSCache
+		dnsPlugin *crosscluster.CrossCluster
+		key       *dns.DNSKEY
+	)
+
+	BeforeEach(func() {
+		key = &dns.DNSKEY{
+			Hdr:       dns.RR_Header{Name: "some.domain.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+			Flags:     257,
+			Protocol:  3,
+			Algorithm: dns.ECDSAP256SHA256,
+		}
+		privateKey, err := key.Generate(256)
+		Expect(err).NotTo(HaveOccurred())
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "xcc-dnssec-keys",
+				Namespace: "xcc-dns",
+			},
+			Data: map[string][]byte{
+				"ksk.key":     []byte(key.String()),
+				"ksk.private": []byte(key.PrivateKeyString(privateKey.(crypto.PrivateKey))),
+			},
+		}).Build()
+
+		dnsCache = &endpointslicedns.DNSCache{}
+		dnsPlugin = &crosscluster.CrossCluster{
+			RecordsCache: dnsCache,
+			Zones:        []string{"some.domain."},
+			Log:          ctrl.Log.WithName("dnsserver"),
+			Client:       kubeClient,
+			Namespace:    "xcc-dns",
+			DNSSECSecret: "xcc-dnssec-keys",
+		}
+
+		dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+			ResourceKey: "some-namespace/some-service",
+			FQDN:        "some-service.some.domain",
+			Addresses:   []string{"1.2.3.4"},
+		})
+		dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+			ResourceKey: "some-namespace/wildcard-gateway",
+			FQDN:        "*.gateway.some.domain",
+			Addresses:   []string{"3.4.5.6"},
+		})
+	})
+
+	query := func(name string, qtype uint16, do bool) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		r.SetEdns0(4096, do)
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		dnsPlugin.ServeDNS(context.Background(), w, r)
+		return w.Msg
+	}
+
+	// verifySection checks that every RRset in the section has a valid
+	// signature and returns the non-RRSIG records.
+	verifySection := func(section []dns.RR) []dns.RR {
+		var records []dns.RR
+		var sigs []*dns.RRSIG
+		for _, rr := range section {
+			if sig, ok := rr.(*dns.RRSIG); ok {
+				sigs = append(sigs, sig)
+			} else {
+				records = append(records, rr)
+			}
+		}
+		for _, rr := range records {
+			var rrset []dns.RR
+			for _, other := range records {
+				if other.Header().Name == rr.Header().Name && other.Header().Rrtype == rr.Header().Rrtype {
+					rrset = append(rrset, other)
+				}
+			}
+			signed := false
+			for _, sig := range sigs {
+				if sig.Hdr.Name == rr.Header().Name && sig.TypeCovered == rr.Header().Rrtype {
+					Expect(sig.KeyTag).To(Equal(key.KeyTag()))
+					Expect(sig.Verify(key, rrset)).To(Succeed())
+					Expect(sig.ValidityPeriod(time.Now())).To(BeTrue())
+					signed = true
+				}
+			}
+			Expect(signed).To(BeTrue(), "%s has no RRSIG", rr)
+		}
+		return records
+	}
+
+	nsecRecords := func(records []dns.RR) []*dns.NSEC {
+		var nsecs []*dns.NSEC
+		for _, rr := range records {
+			if nsec, ok := rr.(*dns.NSEC); ok {
+				nsecs = append(nsecs, nsec)
+			}
+		}
+		return nsecs
+	}
+
+	// canonicalLess orders names label by label from the root (RFC 4034,
+	// Section 6.1).
+	canonicalLess := func(a, b string) bool {
+		aLabels := dns.SplitDomainName(strings.ReplaceAll(dns.CanonicalName(a), `\000`, "\x00"))
+		bLabels := dns.SplitDomainName(strings.ReplaceAll(dns.CanonicalName(b), `\000`, "\x00"))
+		for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+			if aLabels[len(aLabels)-i] != bLabels[len(bLabels)-i] {
+				return aLabels[len(aLabels)-i] < bLabels[len(bLabels)-i]
+			}
+		}
+		return len(aLabels) < len(bLabels)
+	}
+
+	covers := func(nsec *dns.NSEC, name string) bool {
+		if canonicalLess(nsec.NextDomain, nsec.Hdr.Name) || nsec.NextDomain == nsec.Hdr.Name {
+			// The last NSEC of the chain wraps around to the apex.
+			return canonicalLess(nsec.Hdr.Name, name)
+		}
+		return canonicalLess(nsec.Hdr.Name, name) && canonicalLess(name, nsec.NextDomain)
+	}
+
+	It("answers DNSKEY queries at the zone apex with signed keys", func() {
+		m := query("some.domain.", dns.TypeDNSKEY, true)
+
+		Expect(m.Rcode).To(Equal(dns.RcodeSuccess))
+		answers := verifySection(m.Answer)
+		Expect(answers).To(HaveLen(1))
+		Expect(answers[0].(*dns.DNSKEY).PublicKey).To(Equal(key.PublicKey))
+	})
+
+	DescribeTable("signs positive answers", func(name string) {
+		m := query(name, dns.TypeA, true)
+
+		Expect(m.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(verifySection(m.Answer)).To(HaveLen(1))
+	},
+		Entry("for a name in the cache", "some-service.some.domain."),
+		Entry("for a name matching a wildcard", "foo.gateway.some.domain."),
+	)
+
+	It("does not sign answers when the client does not set the DO bit", func() {
+		m := query("some-service.some.domain.", dns.TypeA, false)
+
+		Expect(m.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(m.Answer).To(HaveLen(1))
+		Expect(m.Answer[0].Header().Rrtype).To(Equal(dns.TypeA))
+	})
+
+	It("proves NXDOMAIN with NSEC records covering the name and the wildcard", func() {
+		m := query("not-exists.some.domain.", dns.TypeA, true)
+
+		Expect(m.Rcode).To(Equal(dns.RcodeNameError))
+		nsecs := nsecRecords(verifySection(m.Ns))
+		Expect(nsecs).NotTo(BeEmpty())
+		coversName, coversWildcard := false, false
+		for _, nsec := range nsecs {
+			coversName = coversName || covers(nsec, "not-exists.some.domain.")
+			coversWildcard = coversWildcard || covers(nsec, "*.some.domain.")
+		}
+		Expect(coversName).To(BeTrue())
+		Expect(coversWildcard).To(BeTrue())
+	})
+
+	DescribeTable("proves NODATA with an NSEC record for the name without the requested type", func(name string, qtype uint16) {
+		m := query(name, qtype, true)
+
+		Expect(m.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(m.Answer).To(BeEmpty())
+		nsecs := nsecRecords(verifySection(m.Ns))
+		Expect(nsecs).To(HaveLen(1))
+		Expect(nsecs[0].Hdr.Name == name || covers(nsecs[0], name)).To(BeTrue())
+		Expect(nsecs[0].TypeBitMap).NotTo(ContainElement(qtype))
+	},
+		Entry("for a name in the cache", "some-service.some.domain.", dns.TypeAAAA),
+		Entry("for a name matching a wildcard", "foo.gateway.some.domain.", dns.TypeAAAA),
+		Entry("for an empty non-terminal", "gateway.some.domain.", dns.TypeA),
+		Entry("for the zone apex", "some.domain.", dns.TypeA),
+	)
+
+	It("proves NODATA for names added to the cache after a negative response", func() {
+		query("not-exists.some.domain.", dns.TypeA, true)
+
+		dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+			ResourceKey: "some-namespace/other-service",
+			FQDN:        "other-service.some.domain",
+			Addresses:   []string{"2.3.4.5"},
+		})
+		m := query("other-service.some.domain.", dns.TypeAAAA, true)
+
+		Expect(m.Rcode).To(Equal(dns.RcodeSuccess))
+		nsecs := nsecRecords(verifySection(m.Ns))
+		Expect(nsecs).To(HaveLen(1))
+		Expect(nsecs[0].Hdr.Name).To(Equal("other-service.some.domain."))
+	})
+})
@@ -20,38 +20,119 @@ func (c *CrossCluster) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dn
 		return dns.RcodeServerFailure, errors.New("unknown zone")
 	}
 
-	var records []dns.RR
+	var records, extra []dns.RR
 	var err error
+	// scope is the EDNS Client Subnet scope of the answer. It stays 0 for
+	// answers that do not depend on the location of the client.
+	var scope uint8
 	switch state.QType() {
 	case dns.TypeSOA:
-		records, err = plugin.SOA(ctx, c, zone, state, opt)
-	case dns.TypeA:
-		records, _, err = plugin.A(ctx, c, zone, state, nil, opt)
+		if state.Name() == zone {
+			records = c.soa(ctx, zone, state, opt)
+		}
+	case dns.TypeNS:
+		if state.Name() == zone {
+			records, extra = c.nameserverRecords(zone)
+		}
+	case dns.TypeDNSKEY:
+		if state.Name() == zone && c.signingEnabled() {
+			records, err = c.dnskeyRecords(ctx, zone)
+		}
+	case dns.TypeA, dns.TypeAAAA:
+		if nameserver, ok := c.nameserver(state.Name()); ok {
+			records = c.addressRecords(nameserver, state.QType())
+		} else if state.QType() == dns.TypeA {
+			records, _, err = plugin.A(ctx, c, zone, state, nil, opt)
+			_, scope, _ = c.clientLocality(state)
+		}
 	case dns.TypeCNAME:
 		records, err = plugin.CNAME(ctx, c, zone, state, opt)
-	default:
-		return plugin.BackendError(ctx, c, zone, dns.RcodeNameError, state, nil, opt)
+	case dns.TypeTXT:
+		if state.Name() == canaryName(zone) {
+			records = canaryRecords(zone)
+		}
 	}
 	if err != nil {
 		if c.IsNameError(err) {
 			c.Log.WithValues("name", state.Name()).Info("Couldn't find record in cache")
-			return plugin.BackendError(ctx, c, zone, dns.RcodeNameError, state, nil, opt)
+			return c.negativeResponse(ctx, zone, state, opt)
 		}
 		c.Log.Error(err, "Failed record lookup")
-		return plugin.BackendError(ctx, c, zone, dns.RcodeServerFailure, state, err, opt)
+		return c.backendError(ctx, zone, dns.RcodeServerFailure, state, err, opt)
 	}
 
 	if len(records) == 0 {
-		return plugin.BackendError(ctx, c, zone, dns.RcodeNameError, state, nil, opt)
+		return c.negativeResponse(ctx, zone, state, opt)
 	}
 
 	response := new(dns.Msg)
 	response.SetReply(r)
 	response.Authoritative = true
 	response.Answer = append(response.Answer, records...)
-	w.WriteMsg(response)
+	response.Extra = append(response.Extra, extra...)
+	setClientSubnet(state, response, scope)
+
+	return c.writeResponse(ctx, zone, state, response, nil)
+}
 
-	return dns.RcodeSuccess, nil
+// backendError is plugin.BackendError with the zone SOA from soa, and with
+// DNSSEC denial of existence for negative responses.
+func (c *CrossCluster) backendError(ctx context.Context, zone string, rcode int, state request.Request, err error, opt plugin.Options) (int, error) {
+	m := new(dns.Msg)
+	m.SetRcode(state.Req, rcode)
+	m.Authoritative = true
+	m.Ns = c.soa(ctx, zone, state, opt)
+
+	if c.shouldSign(state) && (rcode == dns.RcodeNameError || rcode == dns.RcodeSuccess) {
+		m.Ns = append(m.Ns, c.denialOfExistence(ctx, zone, rcode, state.Name())...)
+	}
+	setClientSubnet(state, m, 0)
+
+	return c.writeResponse(ctx, zone, state, m, err)
+}
+
+func (c *CrossCluster) shouldSign(state request.Request) bool {
+	return c.signingEnabled() && state.Do()
+}
+
+// writeResponse signs m when the client asked for DNSSEC records and writes
+// it. It returns success as the rcode to signal it has written to the client.
+func (c *CrossCluster) writeResponse(ctx context.Context, zone string, state request.Request, m *dns.Msg, err error) (int, error) {
+	if c.shouldSign(state) && m.Rcode != dns.RcodeServerFailure {
+		if signErr := c.sign(ctx, zone, m); signErr != nil {
+			c.Log.Error(signErr, "Failed to sign response")
+			failure := new(dns.Msg)
+			failure.SetRcode(state.Req, dns.RcodeServerFailure)
+			state.W.WriteMsg(failure)
+			return dns.RcodeSuccess, signErr
+		}
+	}
+
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, err
+}
+
+// negativeResponse answers NOERROR with no records (NODATA) when the name
+// exists with other types, and NXDOMAIN only when it does not exist at all.
+// Both carry the zone SOA in the authority section (RFC 2308).
+func (c *CrossCluster) negativeResponse(ctx context.Context, zone string, state request.Request, opt plugin.Options) (int, error) {
+	rcode := dns.RcodeNameError
+	if c.nameExists(zone, state.Name()) {
+		rcode = dns.RcodeSuccess
+	}
+	return c.backendError(ctx, zone, rcode, state, nil, opt)
+}
+
+func (c *CrossCluster) nameExists(zone, name string) bool {
+	if name == zone || name == canaryName(zone) || c.RecordsCache.NameExists(name) {
+		return true
+	}
+	for _, nameserver := range c.Nameservers {
+		if dns.IsSubDomain(name, nameserver.Host) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *CrossCluster) Name() string {
@@ -8,12 +8,20 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/miekg/dns"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/coredns/plugins/crosscluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	. "github.com/onsi/ginkgo"
@@ -64,6 +72,12 @@ var _ = Describe("CrossCluster", func() {
 				FQDN:        "another-service.other.domain",
 				Addresses:   []string{"BAZ.com"},
 			})
+
+			dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "some-namespace/wildcard-gateway",
+				FQDN:        "*.gateway.some.domain",
+				Addresses:   []string{"3.4.5.6"},
+			})
 		})
 
 		DescribeTable("returns an appropriate DNS response given an A record dns request", func(fqdn string, expectedIPs ...net.IP) {
@@ -123,24 +137,241 @@ var _ = Describe("CrossCluster", func() {
 		)
 
 		Context("when the FQDN provided is not in the cache", func() {
-			It("returns a DNS message NXDOMAIN", func() {
+			It("returns a DNS message NXDOMAIN with the zone SOA", func() {
 				r := new(dns.Msg)
 				r.SetQuestion(dns.Fqdn("not-exists.some.domain"), dns.TypeA)
 				w := dnstest.NewRecorder(&test.ResponseWriter{})
 				dnsPlugin.ServeDNS(context.Background(), w, r)
 
 				Expect(w.Msg.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(w.Msg.Answer).To(BeEmpty())
+				Expect(w.Msg.Ns).To(HaveLen(1))
+				Expect(w.Msg.Ns[0].Header().Name).To(Equal("some.domain."))
+				Expect(w.Msg.Ns[0].Header().Rrtype).To(Equal(dns.TypeSOA))
 			})
 		})
 
-		Context("when the dns request asks for record that is not type A or CNAME", func() {
-			It("returns a DNS message NXDOMAIN", func() {
+		DescribeTable("returns NODATA with the zone SOA when the name exists without the requested type", func(fqdn string, qtype uint16) {
+			r := new(dns.Msg)
+			r.SetQuestion(dns.Fqdn(fqdn), qtype)
+			w := dnstest.NewRecorder(&test.ResponseWriter{})
+			dnsPlugin.ServeDNS(context.Background(), w, r)
+
+			Expect(w.Msg.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(w.Msg.Authoritative).To(BeTrue())
+			Expect(w.Msg.Answer).To(BeEmpty())
+			Expect(w.Msg.Ns).To(HaveLen(1))
+			soa, ok := w.Msg.Ns[0].(*dns.SOA)
+			Expect(ok).To(BeTrue())
+			Expect(soa.Hdr.Name).To(Equal(plugin.Zones(dnsPlugin.Zones).Matches(dns.Fqdn(fqdn))))
+		},
+			Entry("AAAA for an A record name", "some-service.some.domain", dns.TypeAAAA),
+			Entry("MX for an A record name", "some-service.some.domain", dns.TypeMX),
+			Entry("TXT for an A record name", "another-service.some.domain", dns.TypeTXT),
+			Entry("CNAME for an A record name", "some-service.some.domain", dns.TypeCNAME),
+			Entry("AAAA for a CNAME record name", "some-service.other.domain", dns.TypeAAAA),
+			Entry("AAAA for a name matching a wildcard", "foo.gateway.some.domain", dns.TypeAAAA),
+			Entry("A for an empty non-terminal", "gateway.some.domain", dns.TypeA),
+			Entry("A for the zone apex", "some.domain", dns.TypeA),
+			Entry("SOA below the zone apex", "some-service.some.domain", dns.TypeSOA),
+			Entry("A for the canary", "_xcc-canary.some.domain", dns.TypeA),
+		)
+
+		Context("when the dns request asks for the TXT record of the canary", func() {
+			It("answers it in each zone without a TTL", func() {
+				for _, zone := range dnsPlugin.Zones {
+					r := new(dns.Msg)
+					r.SetQuestion("_xcc-canary."+zone, dns.TypeTXT)
+					w := dnstest.NewRecorder(&test.ResponseWriter{})
+					dnsPlugin.ServeDNS(context.Background(), w, r)
+
+					Expect(w.Msg.Rcode).To(Equal(dns.RcodeSuccess))
+					Expect(w.Msg.Answer).To(HaveLen(1))
+					txt, ok := w.Msg.Answer[0].(*dns.TXT)
+					Expect(ok).To(BeTrue())
+					Expect(txt.Hdr.Name).To(Equal("_xcc-canary." + zone))
+					Expect(txt.Hdr.Ttl).To(BeZero())
+					Expect(txt.Txt).To(Equal([]string{"xcc-dns"}))
+				}
+			})
+		})
+
+		Context("when the dns request asks for the SOA of the zone", func() {
+			It("answers with the SOA record", func() {
+				r := new(dns.Msg)
+				r.SetQuestion("some.domain.", dns.TypeSOA)
+				w := dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(w.Msg.Answer).To(HaveLen(1))
+				soa, ok := w.Msg.Answer[0].(*dns.SOA)
+				Expect(ok).To(BeTrue())
+				Expect(soa.Serial).To(Equal(dnsCache.Serial()))
+			})
+		})
+
+		Describe("the SOA serial", func() {
+			querySerial := func() uint32 {
+				r := new(dns.Msg)
+				r.SetQuestion("not-exists.some.domain.", dns.TypeA)
+				w := dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Ns).To(HaveLen(1))
+				return w.Msg.Ns[0].(*dns.SOA).Serial
+			}
+
+			It("stays the same while the records do not change", func() {
+				serial := querySerial()
+				dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+					ResourceKey: "some-namespace/some-service",
+					FQDN:        "some-service.some.domain",
+					Addresses:   []string{"1.2.3.4", "1.2.3.5"},
+				})
+				Consistently(querySerial, "1.5s", "250ms").Should(Equal(serial))
+			})
+
+			It("increases when the records change", func() {
+				serial := querySerial()
+				dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+					ResourceKey: "some-namespace/some-service",
+					FQDN:        "some-service.some.domain",
+					Addresses:   []string{"1.2.3.4"},
+				})
+				Expect(querySerial()).To(BeNumerically(">", serial))
+			})
+		})
+		Context("when nameservers are configured", func() {
+			var (
+				kubeClient           client.Client
+				nameserverReconciler *crosscluster.NameserverServiceReconciler
+				nameserverRequest    ctrl.Request
+			)
+
+			BeforeEach(func() {
+				scheme := runtime.NewScheme()
+				_ = clientgoscheme.AddToScheme(scheme)
+				kubeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "dns-server",
+						Namespace: "xcc-dns",
+					},
+					Spec: corev1.ServiceSpec{
+						Type:      corev1.ServiceTypeLoadBalancer,
+						ClusterIP: "10.96.0.20",
+					},
+					Status: corev1.ServiceStatus{
+						LoadBalancer: corev1.LoadBalancerStatus{
+							Ingress: []corev1.LoadBalancerIngress{{IP: "5.6.7.8"}},
+						},
+					},
+				}).Build()
+
+				dnsPlugin.Nameservers = []crosscluster.Nameserver{
+					{Host: "ns1.some.domain.", Addresses: []string{"4.5.6.7", "fd00::1"}},
+					{Host: "ns2.some.domain.", Service: "dns-server"},
+					{Host: "ns.corp.example."},
+				}
+				dnsPlugin.NameserverAddresses = new(crosscluster.NameserverAddresses)
+
+				nameserverReconciler = &crosscluster.NameserverServiceReconciler{
+					Client:    kubeClient,
+					Log:       ctrl.Log.WithName("NameserverService"),
+					Services:  []string{"dns-server"},
+					Addresses: dnsPlugin.NameserverAddresses,
+				}
+				nameserverRequest = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xcc-dns", Name: "dns-server"}}
+				_, err := nameserverReconciler.Reconcile(context.Background(), nameserverRequest)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("answers NS queries at the zone apex with glue for in-zone nameservers", func() {
 				r := new(dns.Msg)
-				r.SetQuestion(dns.Fqdn("some-service.some.domain"), dns.TypeAAAA)
+				r.SetQuestion("some.domain.", dns.TypeNS)
+				w := dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(w.Msg.Authoritative).To(BeTrue())
+				var nameservers []string
+				for _, answer := range w.Msg.Answer {
+					ns := answer.(*dns.NS)
+					Expect(ns.Hdr.Name).To(Equal("some.domain."))
+					nameservers = append(nameservers, ns.Ns)
+				}
+				Expect(nameservers).To(Equal([]string{"ns1.some.domain.", "ns2.some.domain.", "ns.corp.example."}))
+
+				var glue []string
+				for _, extra := range w.Msg.Extra {
+					switch rr := extra.(type) {
+					case *dns.A:
+						glue = append(glue, rr.Hdr.Name+" "+rr.A.String())
+					case *dns.AAAA:
+						glue = append(glue, rr.Hdr.Name+" "+rr.AAAA.String())
+					}
+				}
+				Expect(glue).To(ConsistOf("ns1.some.domain. 4.5.6.7", "ns1.some.domain. fd00::1", "ns2.some.domain. 5.6.7.8"))
+			})
+
+			It("answers A and AAAA queries for the nameserver hosts", func() {
+				r := new(dns.Msg)
+				r.SetQuestion("ns2.some.domain.", dns.TypeA)
+				w := dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Answer).To(HaveLen(1))
+				Expect(w.Msg.Answer[0].(*dns.A).A.String()).To(Equal("5.6.7.8"))
+
+				r.SetQuestion("ns1.some.domain.", dns.TypeAAAA)
+				w = dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Answer).To(HaveLen(1))
+				Expect(w.Msg.Answer[0].(*dns.AAAA).AAAA.String()).To(Equal("fd00::1"))
+			})
+
+			It("uses the first nameserver as the primary in the SOA", func() {
+				r := new(dns.Msg)
+				r.SetQuestion("not-exists.some.domain.", dns.TypeA)
 				w := dnstest.NewRecorder(&test.ResponseWriter{})
 				dnsPlugin.ServeDNS(context.Background(), w, r)
 
 				Expect(w.Msg.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(w.Msg.Ns).To(HaveLen(1))
+				Expect(w.Msg.Ns[0].(*dns.SOA).Ns).To(Equal("ns1.some.domain."))
+			})
+
+			It("falls back to the ClusterIP when the Service has no load balancer ingress", func() {
+				var service corev1.Service
+				Expect(kubeClient.Get(context.Background(), nameserverRequest.NamespacedName, &service)).To(Succeed())
+				service.Status.LoadBalancer.Ingress = nil
+				Expect(kubeClient.Update(context.Background(), &service)).To(Succeed())
+				_, err := nameserverReconciler.Reconcile(context.Background(), nameserverRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				r := new(dns.Msg)
+				r.SetQuestion("ns2.some.domain.", dns.TypeA)
+				w := dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Answer).To(HaveLen(1))
+				Expect(w.Msg.Answer[0].(*dns.A).A.String()).To(Equal("10.96.0.20"))
+			})
+
+			It("serves no glue for the Service once it is deleted", func() {
+				Expect(kubeClient.Delete(context.Background(), &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "xcc-dns", Name: "dns-server"},
+				})).To(Succeed())
+				_, err := nameserverReconciler.Reconcile(context.Background(), nameserverRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				r := new(dns.Msg)
+				r.SetQuestion("ns2.some.domain.", dns.TypeA)
+				w := dnstest.NewRecorder(&test.ResponseWriter{})
+				dnsPlugin.ServeDNS(context.Background(), w, r)
+
+				Expect(w.Msg.Answer).To(BeEmpty())
 			})
 		})
 	})
@@ -0,0 +1,191 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Nameserver is an authoritative name server for the cross-cluster zones. It
+// lets a parent zone delegate to the dns-server with NS and glue records.
+type Nameserver struct {
+	// Host is the fully qualified name of the name server.
+	Host string
+
+	// Addresses are the glue addresses of Host.
+	Addresses []string
+
+	// Service is the name of a Service in the dns-server namespace. When set,
+	// its load balancer ingress IPs, or its ClusterIP if it has none, are used
+	// as the glue addresses of Host.
+	Service string
+}
+
+func (c *CrossCluster) nameserver(name string) (Nameserver, bool) {
+	for _, nameserver := range c.Nameservers {
+		if nameserver.Host == name {
+			return nameserver, true
+		}
+	}
+	return Nameserver{}, false
+}
+
+func (c *CrossCluster) nameserverAddresses(nameserver Nameserver) []string {
+	if nameserver.Service == "" {
+		return nameserver.Addresses
+	}
+	return c.NameserverAddresses.Get(nameserver.Service)
+}
+
+// NameserverAddresses holds the glue addresses of the nameserver Services, so
+// that answering a query does not read the Services.
+type NameserverAddresses struct {
+	mutex     sync.RWMutex
+	addresses map[string][]string
+}
+
+// Get returns the addresses of the Service, or nil when it was not seen.
+func (n *NameserverAddresses) Get(service string) []string {
+	if n == nil {
+		return nil
+	}
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.addresses[service]
+}
+
+// Set replaces the addresses of the Service.
+func (n *NameserverAddresses) Set(service string, addresses []string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.addresses == nil {
+		n.addresses = map[string][]string{}
+	}
+	n.addresses[service] = addresses
+}
+
+// Delete forgets the addresses of the Service.
+func (n *NameserverAddresses) Delete(service string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.addresses, service)
+}
+
+// NameserverServiceReconciler keeps the NameserverAddresses up to date with
+// the load balancer ingress IPs of the nameserver Services, or their
+// ClusterIP when they have none.
+type NameserverServiceReconciler struct {
+	Client    client.Client
+	Log       logr.Logger
+	Services  []string
+	Addresses *NameserverAddresses
+}
+
+func (r *NameserverServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("Service", req.NamespacedName)
+
+	var service corev1.Service
+	if err := r.Client.Get(ctx, req.NamespacedName, &service); err != nil {
+		if k8serrors.IsNotFound(err) {
+			r.Addresses.Delete(req.Name)
+			log.Info("Nameserver Service not found, serving no glue for it")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get nameserver Service")
+		return ctrl.Result{}, err
+	}
+
+	addresses := serviceAddresses(service)
+	r.Addresses.Set(req.Name, addresses)
+	log.Info("Successfully synced", "Addresses", addresses)
+	return ctrl.Result{}, nil
+}
+
+func (r *NameserverServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isNameserverService := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		for _, service := range r.Services {
+			if object.GetName() == service {
+				return true
+			}
+		}
+		return false
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("nameserver-service").
+		For(&corev1.Service{}, builder.WithPredicates(isNameserverService)).
+		Complete(r)
+}
+
+func serviceAddresses(service corev1.Service) []string {
+	var addresses []string
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses = append(addresses, ingress.IP)
+		}
+	}
+	if len(addresses) == 0 && net.ParseIP(service.Spec.ClusterIP) != nil {
+		addresses = append(addresses, service.Spec.ClusterIP)
+	}
+	return addresses
+}
+
+// addressRecords returns the A or AAAA records of a nameserver.
+func (c *CrossCluster) addressRecords(nameserver Nameserver, qtype uint16) []dns.RR {
+	var records []dns.RR
+	for _, address := range c.nameserverAddresses(nameserver) {
+		ip := net.ParseIP(address)
+		header := dns.RR_Header{Name: nameserver.Host, Rrtype: qtype, Class: dns.ClassINET, Ttl: 30}
+		switch {
+		case qtype == dns.TypeA && ip.To4() != nil:
+			records = append(records, &dns.A{Hdr: header, A: ip.To4()})
+		case qtype == dns.TypeAAAA && ip.To4() == nil:
+			records = append(records, &dns.AAAA{Hdr: header, AAAA: ip})
+		}
+	}
+	return records
+}
+
+// nameserverRecords returns the NS records of the zone and, for nameservers
+// inside the zone, their glue records.
+func (c *CrossCluster) nameserverRecords(zone string) (records, extra []dns.RR) {
+	for _, nameserver := range c.Nameservers {
+		records = append(records, &dns.NS{
+			Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 30},
+			Ns:  nameserver.Host,
+		})
+		if !dns.IsSubDomain(zone, nameserver.Host) {
+			continue
+		}
+		extra = append(extra, c.addressRecords(nameserver, dns.TypeA)...)
+		extra = append(extra, c.addressRecords(nameserver, dns.TypeAAAA)...)
+	}
+	return records, extra
+}
+
+// soa returns the SOA record of the zone. When nameservers are configured the
+// first one is the primary name server of the zone.
+func (c *CrossCluster) soa(ctx context.Context, zone string, state request.Request, opt plugin.Options) []dns.RR {
+	records, _ := plugin.SOA(ctx, c, zone, state, opt)
+	if len(c.Nameservers) > 0 {
+		for _, record := range records {
+			if soa, ok := record.(*dns.SOA); ok {
+				soa.Ns = c.Nameservers[0].Host
+			}
+		}
+	}
+	return records
+}
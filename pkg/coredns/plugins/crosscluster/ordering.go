@@ -0,0 +1,114 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+)
+
+// Topology is the region and zone of the cluster the dns-server runs in.
+type Topology struct {
+	Region string
+	Zone   string
+}
+
+// defaultWeight applies to cache entries that carry no weight.
+const defaultWeight = 1
+
+// random is shared by all queries; rand.Rand is not safe for concurrent use.
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func randomFloat64() float64 {
+	random.Lock()
+	defer random.Unlock()
+	return random.Float64()
+}
+
+// orderEntries returns the cache entries with gateways in the zone of the
+// topology first, then those in its region, then all others. Within each of
+// those groups the entries are shuffled by weight when their weights differ,
+// and keep their order otherwise. The entries are not modified.
+func orderEntries(entries []endpointslicedns.DNSCacheEntry, topology Topology) []endpointslicedns.DNSCacheEntry {
+	weighted := false
+	for _, entry := range entries {
+		if entryWeight(entry) != entryWeight(entries[0]) {
+			weighted = true
+			break
+		}
+	}
+	if !weighted && topology.Region == "" {
+		return entries
+	}
+
+	type orderedEntry struct {
+		entry    endpointslicedns.DNSCacheEntry
+		distance int
+		key      float64
+	}
+	ordered := make([]orderedEntry, len(entries))
+	for i, entry := range entries {
+		ordered[i] = orderedEntry{entry: entry, distance: distance(topology, entry)}
+		if weighted {
+			// Weighted random sampling without replacement (Efraimidis and
+			// Spirakis): sorting by u^(1/w) descending picks heavier entries
+			// first proportionally to their weight.
+			ordered[i].key = math.Pow(randomFloat64(), 1/float64(entryWeight(entry)))
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].distance != ordered[j].distance {
+			return ordered[i].distance < ordered[j].distance
+		}
+		return ordered[i].key > ordered[j].key
+	})
+
+	result := make([]endpointslicedns.DNSCacheEntry, len(ordered))
+	for i, o := range ordered {
+		result[i] = o.entry
+	}
+	return result
+}
+
+func entryWeight(entry endpointslicedns.DNSCacheEntry) int32 {
+	if entry.Weight > 0 {
+		return entry.Weight
+	}
+	return defaultWeight
+}
+
+// distance is 0 for gateways in the zone of the topology, 1 for gateways in
+// its region and 2 for all others.
+func distance(topology Topology, entry endpointslicedns.DNSCacheEntry) int {
+	if topology.Region == "" || entry.Region != topology.Region {
+		return 2
+	}
+	if topology.Zone != "" && entry.Zone == topology.Zone {
+		return 0
+	}
+	return 1
+}
+
+// filterRegion returns the cache entries with gateways in the region, or all
+// of them when none are in it.
+func filterRegion(entries []endpointslicedns.DNSCacheEntry, region string) []endpointslicedns.DNSCacheEntry {
+	var filtered []endpointslicedns.DNSCacheEntry
+	for _, entry := range entries {
+		if entry.Region == region {
+			filtered = append(filtered, entry)
+		}
+	}
+	if region == "" || len(filtered) == 0 {
+		return entries
+	}
+	return filtered
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster_test
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/coredns/plugins/crosscluster"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Answer ordering", func() {
+	var (
+		dnsCache  *endpointslicedns.DNSCache
+		dnsPlugin *crosscluster.CrossCluster
+	)
+
+	BeforeEach(func() {
+		dnsCache = &endpointslicedns.DNSCache{}
+		dnsPlugin = &crosscluster.CrossCluster{
+			RecordsCache: dnsCache,
+			Zones:        []string{"xcc.test."},
+			Log:          ctrl.Log.WithName("dnsserver"),
+		}
+	})
+
+	queryA := func() []string {
+		r := new(dns.Msg)
+		r.SetQuestion("foo.gateway.xcc.test.", dns.TypeA)
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := dnsPlugin.ServeDNS(context.Background(), w, r)
+		Expect(err).NotTo(HaveOccurred())
+
+		var addresses []string
+		for _, answer := range w.Msg.Answer {
+			addresses = append(addresses, answer.(*dns.A).A.String())
+		}
+		return addresses
+	}
+
+	Context("when the gateways have topology", func() {
+		BeforeEach(func() {
+			dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "xcc-dns/other-region",
+				FQDN:        "*.gateway.xcc.test",
+				Addresses:   []string{"1.1.1.1"},
+				Region:      "eu-west-1",
+				Zone:        "eu-west-1a",
+			})
+			dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "xcc-dns/same-region",
+				FQDN:        "*.gateway.xcc.test",
+				Addresses:   []string{"2.2.2.2"},
+				Region:      "us-east-1",
+				Zone:        "us-east-1b",
+			})
+			dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "xcc-dns/same-zone",
+				FQDN:        "*.gateway.xcc.test",
+				Addresses:   []string{"3.3.3.3"},
+				Region:      "us-east-1",
+				Zone:        "us-east-1a",
+			})
+		})
+
+		It("keeps the cache order when no topology is configured", func() {
+			Expect(queryA()).To(Equal([]string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}))
+		})
+
+		It("returns gateways in the same zone, then the same region, first", func() {
+			dnsPlugin.Topology = crosscluster.Topology{Region: "us-east-1", Zone: "us-east-1a"}
+			Expect(queryA()).To(Equal([]string{"3.3.3.3", "2.2.2.2", "1.1.1.1"}))
+		})
+
+		It("only prefers the region when no zone is configured", func() {
+			dnsPlugin.Topology = crosscluster.Topology{Region: "us-east-1"}
+			Expect(queryA()).To(Equal([]string{"2.2.2.2", "3.3.3.3", "1.1.1.1"}))
+		})
+
+		It("limits the number of addresses to max_answers", func() {
+			dnsPlugin.Topology = crosscluster.Topology{Region: "us-east-1", Zone: "us-east-1a"}
+			dnsPlugin.MaxAnswers = 2
+			Expect(queryA()).To(Equal([]string{"3.3.3.3", "2.2.2.2"}))
+		})
+	})
+
+	Context("when the gateways have different weights", func() {
+		BeforeEach(func() {
+			dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "xcc-dns/heavy",
+				FQDN:        "*.gateway.xcc.test",
+				Addresses:   []string{"1.1.1.1"},
+				Weight:      9,
+			})
+			dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+				ResourceKey: "xcc-dns/light",
+				FQDN:        "*.gateway.xcc.test",
+				Addresses:   []string{"2.2.2.2"},
+			})
+		})
+
+		It("returns the heavier gateway first proportionally to its weight", func() {
+			heavyFirst := 0
+			for i := 0; i < 1000; i++ {
+				addresses := queryA()
+				Expect(addresses).To(ConsistOf("1.1.1.1", "2.2.2.2"))
+				if addresses[0] == "1.1.1.1" {
+					heavyFirst++
+				}
+			}
+			// The heavy gateway comes first 90% of the time.
+			Expect(heavyFirst).To(BeNumerically("~", 900, 60))
+		})
+
+		It("returns weighted random subsets with max_answers", func() {
+			dnsPlugin.MaxAnswers = 1
+			seen := map[string]int{}
+			for i := 0; i < 1000; i++ {
+				addresses := queryA()
+				Expect(addresses).To(HaveLen(1))
+				seen[addresses[0]]++
+			}
+			Expect(seen["1.1.1.1"]).To(BeNumerically("~", 900, 60))
+			Expect(seen["2.2.2.2"]).To(BeNumerically("~", 100, 60))
+		})
+	})
+})
@@ -6,7 +6,9 @@ package crosscluster
 import (
 	"context"
 	"errors"
+	"net"
 	"os"
+	"strconv"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
@@ -52,6 +54,11 @@ func setup(c *caddy.Controller) error {
 func setupController(c *caddy.Controller) (*CrossCluster, error) {
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	dnsPlugin, err := parse(c)
+	if err != nil {
+		return nil, err
+	}
+
 	dnsRecordsCache := new(endpointslicedns.DNSCache)
 
 	namespace, ok := os.LookupEnv("NAMESPACE")
@@ -81,11 +88,28 @@ func setupController(c *caddy.Controller) (*CrossCluster, error) {
 		os.Exit(1)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	nameserverAddresses := new(NameserverAddresses)
+	var nameserverServices []string
+	for _, nameserver := range dnsPlugin.Nameservers {
+		if nameserver.Service != "" {
+			nameserverServices = append(nameserverServices, nameserver.Service)
+		}
+	}
+	if len(nameserverServices) > 0 {
+		if err = (&NameserverServiceReconciler{
+			Client:    mgr.GetClient(),
+			Log:       ctrl.Log.WithName("controllers").WithName("NameserverService"),
+			Services:  nameserverServices,
+			Addresses: nameserverAddresses,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "NameserverService")
+			os.Exit(1)
+		}
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		setupLog.Info("starting manager")
-
 		if err := mgr.Start(ctx); err != nil {
 			setupLog.Error(err, "problem running manager")
 			os.Exit(1)
@@ -97,10 +121,29 @@ func setupController(c *caddy.Controller) (*CrossCluster, error) {
 		return nil
 	})
 
-	dnsPlugin := &CrossCluster{
-		RecordsCache: dnsRecordsCache,
-		Log:          ctrl.Log.WithName("dnsserver"),
-	}
+	dnsPlugin.RecordsCache = dnsRecordsCache
+	dnsPlugin.NameserverAddresses = nameserverAddresses
+	dnsPlugin.Log = ctrl.Log.WithName("dnsserver")
+	dnsPlugin.Client = mgr.GetClient()
+	dnsPlugin.Namespace = namespace
+
+	return dnsPlugin, nil
+}
+
+// parse reads the plugin configuration from the Corefile:
+//
+//	crosscluster [ZONES...] {
+//	    nameserver NAME [ADDRESS...]
+//	    nameserver_service NAME SERVICE
+//	    dnssec_secret SECRET
+//	    topology REGION [ZONE]
+//	    max_answers COUNT
+//	    view NAME CIDR...
+//	    client_region REGION CIDR...
+//	    filter_by_region
+//	}
+func parse(c *caddy.Controller) (*CrossCluster, error) {
+	dnsPlugin := &CrossCluster{}
 
 	// Consume the token "crosscluster" and get next token
 	if c.Next() {
@@ -112,6 +155,98 @@ func setupController(c *caddy.Controller) (*CrossCluster, error) {
 		for i, str := range dnsPlugin.Zones {
 			dnsPlugin.Zones[i] = plugin.Host(str).Normalize()
 		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "nameserver":
+				args := c.RemainingArgs()
+				if len(args) < 1 {
+					return nil, c.ArgErr()
+				}
+				nameserver := Nameserver{Host: plugin.Name(args[0]).Normalize()}
+				for _, address := range args[1:] {
+					if net.ParseIP(address) == nil {
+						return nil, c.Errf("invalid address %q for nameserver %q", address, args[0])
+					}
+					nameserver.Addresses = append(nameserver.Addresses, address)
+				}
+				dnsPlugin.Nameservers = append(dnsPlugin.Nameservers, nameserver)
+			case "nameserver_service":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				dnsPlugin.Nameservers = append(dnsPlugin.Nameservers, Nameserver{
+					Host:    plugin.Name(args[0]).Normalize(),
+					Service: args[1],
+				})
+			case "dnssec_secret":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				dnsPlugin.DNSSECSecret = args[0]
+			case "topology":
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				dnsPlugin.Topology.Region = args[0]
+				if len(args) == 2 {
+					dnsPlugin.Topology.Zone = args[1]
+				}
+			case "max_answers":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				maxAnswers, err := strconv.Atoi(args[0])
+				if err != nil || maxAnswers < 1 {
+					return nil, c.Errf("invalid max_answers %q", args[0])
+				}
+				dnsPlugin.MaxAnswers = maxAnswers
+			case "view":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				view := View{Name: args[0]}
+				for _, cidr := range args[1:] {
+					_, network, err := net.ParseCIDR(cidr)
+					if err != nil {
+						return nil, c.Errf("invalid network %q for view %q", cidr, args[0])
+					}
+					view.Networks = append(view.Networks, network)
+				}
+				dnsPlugin.Views = append(dnsPlugin.Views, view)
+			case "client_region":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				for _, cidr := range args[1:] {
+					_, network, err := net.ParseCIDR(cidr)
+					if err != nil {
+						return nil, c.Errf("invalid network %q for client_region %q", cidr, args[0])
+					}
+					dnsPlugin.Localities = append(dnsPlugin.Localities, Locality{Region: args[0], Network: network})
+				}
+			case "filter_by_region":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				dnsPlugin.FilterByRegion = true
+			default:
+				return nil, c.Errf("unknown property %q", c.Val())
+			}
+		}
+	}
+
+	for _, nameserver := range dnsPlugin.Nameservers {
+		inZone := plugin.Zones(dnsPlugin.Zones).Matches(nameserver.Host) != ""
+		if inZone && len(nameserver.Addresses) == 0 && nameserver.Service == "" {
+			return nil, c.Errf("nameserver %q is inside the zone and requires glue addresses", nameserver.Host)
+		}
 	}
 
 	return dnsPlugin, nil
@@ -0,0 +1,144 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"github.com/coredns/caddy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parse", func() {
+	It("uses the server block keys as zones when none are given", func() {
+		c := caddy.NewTestController("dns", `crosscluster`)
+		c.ServerBlockKeys = []string{"xcc.test:53"}
+
+		dnsPlugin, err := parse(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dnsPlugin.Zones).To(Equal([]string{"xcc.test."}))
+		Expect(dnsPlugin.Nameservers).To(BeEmpty())
+	})
+
+	It("parses nameservers with static and Service glue addresses", func() {
+		c := caddy.NewTestController("dns", `crosscluster xcc.test {
+			nameserver NS1.xcc.test 1.2.3.4 fd00::1
+			nameserver_service ns2.xcc.test dns-server
+			nameserver ns.corp.example
+		}`)
+
+		dnsPlugin, err := parse(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dnsPlugin.Zones).To(Equal([]string{"xcc.test."}))
+		Expect(dnsPlugin.Nameservers).To(Equal([]Nameserver{
+			{Host: "ns1.xcc.test.", Addresses: []string{"1.2.3.4", "fd00::1"}},
+			{Host: "ns2.xcc.test.", Service: "dns-server"},
+			{Host: "ns.corp.example."},
+		}))
+	})
+
+	It("parses the DNSSEC Secret", func() {
+		c := caddy.NewTestController("dns", `crosscluster xcc.test {
+			dnssec_secret xcc-dnssec-keys
+		}`)
+
+		dnsPlugin, err := parse(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dnsPlugin.DNSSECSecret).To(Equal("xcc-dnssec-keys"))
+	})
+
+	It("parses the topology and the answer limit", func() {
+		c := caddy.NewTestController("dns", `crosscluster xcc.test {
+			topology us-east-1 us-east-1a
+			max_answers 2
+		}`)
+
+		dnsPlugin, err := parse(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dnsPlugin.Topology).To(Equal(Topology{Region: "us-east-1", Zone: "us-east-1a"}))
+		Expect(dnsPlugin.MaxAnswers).To(Equal(2))
+	})
+
+	It("parses views in order", func() {
+		c := caddy.NewTestController("dns", `crosscluster xcc.test {
+			view private 10.0.0.0/8 fd00::/8
+			view public 0.0.0.0/0
+		}`)
+
+		dnsPlugin, err := parse(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dnsPlugin.Views).To(HaveLen(2))
+		Expect(dnsPlugin.Views[0].Name).To(Equal("private"))
+		Expect(dnsPlugin.Views[0].Networks).To(HaveLen(2))
+		Expect(dnsPlugin.Views[0].Networks[0].String()).To(Equal("10.0.0.0/8"))
+		Expect(dnsPlugin.Views[0].Networks[1].String()).To(Equal("fd00::/8"))
+		Expect(dnsPlugin.Views[1].Name).To(Equal("public"))
+	})
+
+	It("parses client regions", func() {
+		c := caddy.NewTestController("dns", `crosscluster xcc.test {
+			client_region eu-west-1 198.51.100.0/22 2001:db8::/32
+			filter_by_region
+		}`)
+
+		dnsPlugin, err := parse(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dnsPlugin.Localities).To(HaveLen(2))
+		Expect(dnsPlugin.Localities[0].Region).To(Equal("eu-west-1"))
+		Expect(dnsPlugin.Localities[0].Network.String()).To(Equal("198.51.100.0/22"))
+		Expect(dnsPlugin.Localities[1].Network.String()).To(Equal("2001:db8::/32"))
+		Expect(dnsPlugin.FilterByRegion).To(BeTrue())
+	})
+
+	DescribeTable("rejects invalid configuration", func(input string, expectedErr string) {
+		c := caddy.NewTestController("dns", input)
+
+		_, err := parse(c)
+		Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+	},
+		Entry("nameserver without a name", `crosscluster xcc.test {
+			nameserver
+		}`, "Wrong argument count"),
+		Entry("nameserver with an invalid address", `crosscluster xcc.test {
+			nameserver ns1.xcc.test not-an-ip
+		}`, `invalid address "not-an-ip"`),
+		Entry("nameserver_service without a Service", `crosscluster xcc.test {
+			nameserver_service ns1.xcc.test
+		}`, "Wrong argument count"),
+		Entry("in-zone nameserver without glue", `crosscluster xcc.test {
+			nameserver ns1.xcc.test
+		}`, `nameserver "ns1.xcc.test." is inside the zone and requires glue addresses`),
+		Entry("dnssec_secret without a Secret", `crosscluster xcc.test {
+			dnssec_secret
+		}`, "Wrong argument count"),
+		Entry("topology without a region", `crosscluster xcc.test {
+			topology
+		}`, "Wrong argument count"),
+		Entry("topology with too many arguments", `crosscluster xcc.test {
+			topology us-east-1 us-east-1a extra
+		}`, "Wrong argument count"),
+		Entry("max_answers that is not a positive number", `crosscluster xcc.test {
+			max_answers 0
+		}`, `invalid max_answers "0"`),
+		Entry("view without networks", `crosscluster xcc.test {
+			view private
+		}`, "Wrong argument count"),
+		Entry("view with an invalid network", `crosscluster xcc.test {
+			view private 10.0.0.1
+		}`, `invalid network "10.0.0.1" for view "private"`),
+		Entry("client_region without networks", `crosscluster xcc.test {
+			client_region eu-west-1
+		}`, "Wrong argument count"),
+		Entry("client_region with an invalid network", `crosscluster xcc.test {
+			client_region eu-west-1 nowhere
+		}`, `invalid network "nowhere" for client_region "eu-west-1"`),
+		Entry("filter_by_region with arguments", `crosscluster xcc.test {
+			filter_by_region yes
+		}`, "Wrong argument count"),
+		Entry("unknown property", `crosscluster xcc.test {
+			bogus
+		}`, `unknown property "bogus"`),
+	)
+})
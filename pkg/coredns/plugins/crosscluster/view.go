@@ -0,0 +1,37 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/request"
+)
+
+// View selects the addresses served to clients from the given networks.
+type View struct {
+	// Name is the view of the addresses, as in the
+	// "connectivity.tanzu.vmware.com/addresses.<name>" annotation.
+	Name string
+
+	// Networks are the client networks the view applies to.
+	Networks []*net.IPNet
+}
+
+// view returns the name of the first view whose networks contain the source
+// address of the request, or "" when none do.
+func (c *CrossCluster) view(state request.Request) string {
+	ip := net.ParseIP(state.IP())
+	if ip == nil {
+		return ""
+	}
+	for _, view := range c.Views {
+		for _, network := range view.Networks {
+			if network.Contains(ip) {
+				return view.Name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package crosscluster_test
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/controllers/endpointslicedns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/coredns/plugins/crosscluster"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Views", func() {
+	var (
+		dnsCache  *endpointslicedns.DNSCache
+		dnsPlugin *crosscluster.CrossCluster
+	)
+
+	mustParseCIDR := func(cidr string) *net.IPNet {
+		_, network, err := net.ParseCIDR(cidr)
+		Expect(err).NotTo(HaveOccurred())
+		return network
+	}
+
+	BeforeEach(func() {
+		dnsCache = &endpointslicedns.DNSCache{}
+		dnsPlugin = &crosscluster.CrossCluster{
+			RecordsCache: dnsCache,
+			Zones:        []string{"xcc.test."},
+			Log:          ctrl.Log.WithName("dnsserver"),
+		}
+
+		dnsCache.Upsert(endpointslicedns.DNSCacheEntry{
+			ResourceKey: "xcc-dns/cluster-a",
+			FQDN:        "*.gateway.cluster-a.xcc.test",
+			Addresses:   []string{"1.1.1.1"},
+			ViewAddresses: map[string][]string{
+				"private": {"10.0.0.1", "10.0.0.2"},
+			},
+		})
+	})
+
+	DescribeTable("serves the addresses of the view matching the client", func(views []string, w dns.ResponseWriter, expectedIPs ...string) {
+		for _, view := range views {
+			switch view {
+			case "private":
+				dnsPlugin.Views = append(dnsPlugin.Views, crosscluster.View{
+					Name:     "private",
+					Networks: []*net.IPNet{mustParseCIDR("10.240.0.0/16")},
+				})
+			case "unknown":
+				dnsPlugin.Views = append(dnsPlugin.Views, crosscluster.View{
+					Name:     "unknown",
+					Networks: []*net.IPNet{mustParseCIDR("0.0.0.0/0"), mustParseCIDR("::/0")},
+				})
+			}
+		}
+
+		r := new(dns.Msg)
+		r.SetQuestion("foo.gateway.cluster-a.xcc.test.", dns.TypeA)
+		recorder := dnstest.NewRecorder(w)
+		_, err := dnsPlugin.ServeDNS(context.Background(), recorder, r)
+		Expect(err).NotTo(HaveOccurred())
+
+		var addresses []string
+		for _, answer := range recorder.Msg.Answer {
+			addresses = append(addresses, answer.(*dns.A).A.String())
+		}
+		Expect(addresses).To(Equal(expectedIPs))
+	},
+		Entry("without views", nil, &test.ResponseWriter{}, "1.1.1.1"),
+		Entry("for a client in the private view", []string{"private"}, &test.ResponseWriter{}, "10.0.0.1", "10.0.0.2"),
+		Entry("for a client outside every view", []string{"private"}, &test.ResponseWriter6{}, "1.1.1.1"),
+		Entry("using the first matching view", []string{"private", "unknown"}, &test.ResponseWriter{}, "10.0.0.1", "10.0.0.2"),
+		Entry("for a view the gateway has no addresses in", []string{"unknown"}, &test.ResponseWriter{}, "1.1.1.1"),
+	)
+
+	It("serves the answers without a TTL when there are views, so that they are not cached", func() {
+		dnsPlugin.Views = []crosscluster.View{{
+			Name:     "private",
+			Networks: []*net.IPNet{mustParseCIDR("10.240.0.0/16")},
+		}}
+
+		for _, w := range []dns.ResponseWriter{&test.ResponseWriter{}, &test.ResponseWriter6{}} {
+			r := new(dns.Msg)
+			r.SetQuestion("foo.gateway.cluster-a.xcc.test.", dns.TypeA)
+			recorder := dnstest.NewRecorder(w)
+			_, err := dnsPlugin.ServeDNS(context.Background(), recorder, r)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(recorder.Msg.Answer).NotTo(BeEmpty())
+			for _, answer := range recorder.Msg.Answer {
+				Expect(answer.Header().Ttl).To(BeZero())
+			}
+		}
+	})
+})
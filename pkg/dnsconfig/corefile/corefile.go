@@ -0,0 +1,514 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package corefile edits CoreDNS Corefiles as a tree of server blocks and
+// directives. Comments, blank lines and indentation of the parts that are not
+// edited are kept, so that a Corefile that is loaded and written back without
+// changes is left as it was.
+package corefile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Corefile is a list of server blocks.
+type Corefile struct {
+	ServerBlocks []*ServerBlock
+
+	// Footer holds the comments and blank lines after the last server block.
+	Footer []string
+}
+
+// ServerBlock is a list of zones, the keys of the block, and the directives
+// that configure the server for them.
+type ServerBlock struct {
+	// Comments holds the comments and blank lines before the keys.
+	Comments []string
+
+	Keys []string
+
+	// Comment is the comment at the end of the line opening the block.
+	Comment string
+
+	Block
+
+	indent string
+}
+
+// Directive configures a plugin, e.g. "forward . 1.2.3.4".
+type Directive struct {
+	// Comments holds the comments and blank lines before the directive.
+	Comments []string
+
+	Name string
+	Args []string
+
+	// Comment is the comment at the end of the line of the directive.
+	Comment string
+
+	// Block holds the directives in braces after the arguments, or is nil
+	// when there are none.
+	Block *Block
+
+	indent string
+	parsed bool
+}
+
+// Block is a list of directives in braces.
+type Block struct {
+	Directives []*Directive
+
+	// Footer holds the comments and blank lines before the closing brace.
+	Footer []string
+
+	closeIndent  string
+	closeComment string
+	parsed       bool
+
+	// inline is set for blocks loaded from a single line, such as
+	// "forward . 1.2.3.4 { max_fails 3 }", which are written back on one
+	// line as long as they hold at most one directive.
+	inline bool
+}
+
+// indentation is used for the directives that were not loaded from a
+// Corefile, relative to the block they are in.
+const indentation = "    "
+
+// FindServerBlock returns the index of the first server block with the key,
+// or -1 when there is none. Keys are compared ignoring case and a trailing
+// dot.
+func (c *Corefile) FindServerBlock(key string) int {
+	for i, serverBlock := range c.ServerBlocks {
+		for _, k := range serverBlock.Keys {
+			if sameKey(k, key) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// InsertServerBlock inserts the server block at index i, moving the server
+// blocks from i on back. i may be len(c.ServerBlocks) to append it.
+func (c *Corefile) InsertServerBlock(i int, serverBlock *ServerBlock) {
+	c.ServerBlocks = append(c.ServerBlocks, nil)
+	copy(c.ServerBlocks[i+1:], c.ServerBlocks[i:])
+	c.ServerBlocks[i] = serverBlock
+}
+
+// ReplaceServerBlock replaces the server block at index i.
+func (c *Corefile) ReplaceServerBlock(i int, serverBlock *ServerBlock) {
+	c.ServerBlocks[i] = serverBlock
+}
+
+// RemoveServerBlock removes the server block at index i, together with its
+// comments.
+func (c *Corefile) RemoveServerBlock(i int) {
+	c.ServerBlocks = append(c.ServerBlocks[:i], c.ServerBlocks[i+1:]...)
+}
+
+// FindDirective returns the index of the first directive with the name, or -1
+// when there is none.
+func (b *Block) FindDirective(name string) int {
+	for i, directive := range b.Directives {
+		if directive.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// InsertDirective inserts the directive at index i, moving the directives from
+// i on back. i may be len(b.Directives) to append it.
+func (b *Block) InsertDirective(i int, directive *Directive) {
+	b.Directives = append(b.Directives, nil)
+	copy(b.Directives[i+1:], b.Directives[i:])
+	b.Directives[i] = directive
+}
+
+// ReplaceDirective replaces the directive at index i, keeping the indentation
+// of the directive it replaces.
+func (b *Block) ReplaceDirective(i int, directive *Directive) {
+	if b.Directives[i].parsed && !directive.parsed {
+		directive.indent, directive.parsed = b.Directives[i].indent, true
+	}
+	b.Directives[i] = directive
+}
+
+// RemoveDirective removes the directive at index i, together with its
+// comments.
+func (b *Block) RemoveDirective(i int) {
+	b.Directives = append(b.Directives[:i], b.Directives[i+1:]...)
+}
+
+func sameKey(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// String returns the Corefile as text.
+func (c *Corefile) String() string {
+	var sb strings.Builder
+	for _, serverBlock := range c.ServerBlocks {
+		writeComments(&sb, serverBlock.Comments, serverBlock.indent)
+		sb.WriteString(serverBlock.indent)
+		sb.WriteString(strings.Join(serverBlock.Keys, " "))
+		writeOpenBlock(&sb, &serverBlock.Block, serverBlock.Comment, serverBlock.indent)
+	}
+	writeComments(&sb, c.Footer, "")
+	return sb.String()
+}
+
+func writeBlock(sb *strings.Builder, block *Block, indent string) {
+	for _, directive := range block.Directives {
+		directiveIndent := indent + indentation
+		if directive.parsed {
+			directiveIndent = directive.indent
+		}
+		writeComments(sb, directive.Comments, directiveIndent)
+		sb.WriteString(directiveIndent)
+		sb.WriteString(strings.Join(append([]string{directive.Name}, directive.Args...), " "))
+		if directive.Block != nil {
+			writeOpenBlock(sb, directive.Block, directive.Comment, directiveIndent)
+		} else {
+			writeComment(sb, directive.Comment)
+		}
+	}
+	writeComments(sb, block.Footer, indent+indentation)
+
+	if block.parsed {
+		indent = block.closeIndent
+	}
+	sb.WriteString(indent)
+	sb.WriteString("}")
+	writeComment(sb, block.closeComment)
+}
+
+// writeOpenBlock writes the block after the keys or arguments opening it,
+// on the same line when it was loaded from one and still fits on it. Inline
+// blocks edited into several lines are indented as the blocks that were not
+// loaded.
+func writeOpenBlock(sb *strings.Builder, block *Block, comment string, indent string) {
+	if block.fitsOnOneLine() {
+		sb.WriteString(" ")
+		writeInlineBlock(sb, block)
+		writeComment(sb, comment)
+		return
+	}
+	sb.WriteString(" {")
+	writeComment(sb, comment)
+	writeBlock(sb, block, indent)
+}
+
+func (b *Block) fitsOnOneLine() bool {
+	if !b.inline || len(b.Directives) > 1 || len(b.Footer) > 0 {
+		return false
+	}
+	for _, directive := range b.Directives {
+		if len(directive.Comments) > 0 || directive.Comment != "" {
+			return false
+		}
+		if directive.Block != nil && !directive.Block.fitsOnOneLine() {
+			return false
+		}
+	}
+	return true
+}
+
+func writeInlineBlock(sb *strings.Builder, block *Block) {
+	sb.WriteString("{")
+	for _, directive := range block.Directives {
+		sb.WriteString(" ")
+		sb.WriteString(strings.Join(append([]string{directive.Name}, directive.Args...), " "))
+		if directive.Block != nil {
+			sb.WriteString(" ")
+			writeInlineBlock(sb, directive.Block)
+		}
+	}
+	sb.WriteString(" }")
+}
+
+// writeComment ends the line, with the comment if there is one.
+func writeComment(sb *strings.Builder, comment string) {
+	if comment != "" {
+		sb.WriteString(" ")
+		sb.WriteString(comment)
+	}
+	sb.WriteString("\n")
+}
+
+// writeComments writes comment lines that were loaded as they were, and
+// indents the others.
+func writeComments(sb *strings.Builder, comments []string, indent string) {
+	for _, comment := range comments {
+		if comment != "" && !strings.HasPrefix(comment, " ") && !strings.HasPrefix(comment, "\t") {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(comment)
+		sb.WriteString("\n")
+	}
+}
+
+// Load parses the text of a Corefile. It supports the syntax CoreDNS
+// Corefiles use in practice: one directive per line, and the opening brace of
+// a block at the end of the line of its keys or directive, or alone on the
+// next line. A block may also open and close on the line of its keys or
+// directive, holding at most one directive as in
+// "forward . 1.2.3.4 { max_fails 3 }".
+func Load(text string) (*Corefile, error) {
+	lines := splitLines(text)
+
+	corefile := &Corefile{}
+	var pending []string
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if len(l.tokens) == 0 {
+			pending = append(pending, l.commentLine())
+			i++
+			continue
+		}
+
+		if l.tokens[0] == "}" {
+			return nil, fmt.Errorf("line %d: unexpected }", l.number)
+		}
+
+		serverBlock := &ServerBlock{Comments: pending, indent: l.indent}
+		pending = nil
+
+		tokens := l.tokens
+		comment := l.comment
+		start := l.number
+		for indexOf(tokens, "{") < 0 {
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("line %d: expected { after the server block keys", start)
+			}
+			next := lines[i]
+			if strings.HasSuffix(tokens[len(tokens)-1], ",") {
+				tokens = append(tokens, next.tokens...)
+			} else if len(next.tokens) == 1 && next.tokens[0] == "{" {
+				tokens = append(tokens, "{")
+			} else {
+				return nil, fmt.Errorf("line %d: expected { after the server block keys", start)
+			}
+			if next.comment != "" {
+				comment = next.comment
+			}
+		}
+		open := indexOf(tokens, "{")
+		for _, key := range tokens[:open] {
+			key = strings.TrimSuffix(key, ",")
+			if key == "{" || key == "}" {
+				return nil, fmt.Errorf("line %d: unexpected %s", start, key)
+			}
+			if key != "" {
+				serverBlock.Keys = append(serverBlock.Keys, key)
+			}
+		}
+		if len(serverBlock.Keys) == 0 {
+			return nil, fmt.Errorf("line %d: server block without keys", start)
+		}
+		serverBlock.Comment = comment
+
+		if open < len(tokens)-1 {
+			block, err := loadInlineBlock(tokens[open+1:], lines[i].number)
+			if err != nil {
+				return nil, err
+			}
+			serverBlock.Block = *block
+			i++
+		} else {
+			var err error
+			i, err = loadBlock(lines, i+1, start, &serverBlock.Block)
+			if err != nil {
+				return nil, err
+			}
+		}
+		corefile.ServerBlocks = append(corefile.ServerBlocks, serverBlock)
+	}
+	corefile.Footer = pending
+
+	return corefile, nil
+}
+
+// loadBlock loads the directives of the block opened on line start from
+// lines[i:] and returns the index of the line after its closing brace.
+func loadBlock(lines []line, i int, start int, block *Block) (int, error) {
+	block.parsed = true
+
+	var pending []string
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if len(l.tokens) == 0 {
+			pending = append(pending, l.commentLine())
+			continue
+		}
+
+		if len(l.tokens) == 1 && l.tokens[0] == "}" {
+			block.Footer = pending
+			block.closeIndent = l.indent
+			block.closeComment = l.comment
+			return i + 1, nil
+		}
+
+		directive := &Directive{
+			Comments: pending,
+			Name:     l.tokens[0],
+			Args:     l.tokens[1:],
+			Comment:  l.comment,
+			indent:   l.indent,
+			parsed:   true,
+		}
+		pending = nil
+
+		if open := indexOf(l.tokens, "{"); open > 0 && open < len(l.tokens)-1 {
+			directive.Args = l.tokens[1:open]
+			if len(directive.Args) == 0 {
+				directive.Args = nil
+			}
+			var err error
+			directive.Block, err = loadInlineBlock(l.tokens[open+1:], l.number)
+			if err != nil {
+				return 0, err
+			}
+			block.Directives = append(block.Directives, directive)
+			continue
+		}
+
+		opensBlock := len(directive.Args) > 0 && directive.Args[len(directive.Args)-1] == "{"
+		if opensBlock {
+			directive.Args = directive.Args[:len(directive.Args)-1]
+		} else if i+1 < len(lines) && len(lines[i+1].tokens) == 1 && lines[i+1].tokens[0] == "{" {
+			opensBlock = true
+			i++
+		}
+		for _, token := range l.tokens {
+			if (token == "{" || token == "}") && !(opensBlock && token == "{") {
+				return 0, fmt.Errorf("line %d: unexpected %s", l.number, token)
+			}
+		}
+		if len(directive.Args) == 0 {
+			directive.Args = nil
+		}
+
+		if opensBlock {
+			directive.Block = &Block{}
+			next, err := loadBlock(lines, i+1, l.number, directive.Block)
+			if err != nil {
+				return 0, err
+			}
+			i = next - 1
+		}
+		block.Directives = append(block.Directives, directive)
+	}
+
+	return 0, fmt.Errorf("line %d: block is not closed", start)
+}
+
+// loadInlineBlock loads the block opened on line number from the tokens
+// after its opening brace, which must end with its closing brace.
+func loadInlineBlock(tokens []string, number int) (*Block, error) {
+	if len(tokens) == 0 || tokens[len(tokens)-1] != "}" {
+		return nil, fmt.Errorf("line %d: block is not closed", number)
+	}
+	tokens = tokens[:len(tokens)-1]
+
+	block := &Block{inline: true}
+	if len(tokens) == 0 {
+		return block, nil
+	}
+	if tokens[0] == "{" || tokens[0] == "}" {
+		return nil, fmt.Errorf("line %d: unexpected %s", number, tokens[0])
+	}
+
+	directive := &Directive{Name: tokens[0], Args: tokens[1:]}
+	if open := indexOf(directive.Args, "{"); open >= 0 {
+		var err error
+		directive.Block, err = loadInlineBlock(directive.Args[open+1:], number)
+		if err != nil {
+			return nil, err
+		}
+		directive.Args = directive.Args[:open]
+	}
+	if indexOf(directive.Args, "}") >= 0 {
+		return nil, fmt.Errorf("line %d: unexpected }", number)
+	}
+	if len(directive.Args) == 0 {
+		directive.Args = nil
+	}
+	block.Directives = []*Directive{directive}
+	return block, nil
+}
+
+// indexOf returns the index of the first token equal to s, or -1 when there
+// is none.
+func indexOf(tokens []string, s string) int {
+	for i, token := range tokens {
+		if token == s {
+			return i
+		}
+	}
+	return -1
+}
+
+type line struct {
+	number  int
+	indent  string
+	tokens  []string
+	comment string
+}
+
+// commentLine returns a line without tokens as it is kept in Comments and
+// Footer.
+func (l line) commentLine() string {
+	if l.comment == "" {
+		return ""
+	}
+	return l.indent + l.comment
+}
+
+// splitLines splits the text into lines of whitespace separated tokens.
+// Quoted tokens are kept as written, including the quotes.
+func splitLines(text string) []line {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+
+	var lines []line
+	for n, raw := range strings.Split(text, "\n") {
+		l := line{number: n + 1}
+		l.indent = raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+
+		var token strings.Builder
+		quoted, escaped := false, false
+		flush := func() {
+			if token.Len() > 0 {
+				l.tokens = append(l.tokens, token.String())
+				token.Reset()
+			}
+		}
+	chars:
+		for j, c := range raw {
+			switch {
+			case escaped:
+				escaped = false
+			case quoted && c == '\\':
+				escaped = true
+			case c == '"':
+				quoted = !quoted
+			case !quoted && (c == ' ' || c == '\t'):
+				flush()
+				continue
+			case !quoted && c == '#' && token.Len() == 0:
+				l.comment = strings.TrimRight(raw[j:], " \t")
+				break chars
+			}
+			token.WriteRune(c)
+		}
+		flush()
+		lines = append(lines, l)
+	}
+	return lines
+}
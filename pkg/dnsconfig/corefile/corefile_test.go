@@ -0,0 +1,306 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package corefile_test
+
+import (
+	"strings"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig/corefile"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Corefile", func() {
+	var kubeadmCorefile string
+
+	BeforeEach(func() {
+		kubeadmCorefile = strings.Join([]string{
+			".:53 {",
+			"    errors",
+			"    health {",
+			"       lameduck 5s",
+			"    }",
+			"    ready",
+			"    kubernetes cluster.local in-addr.arpa ip6.arpa {",
+			"       pods insecure",
+			"       fallthrough in-addr.arpa ip6.arpa",
+			"       ttl 30",
+			"    }",
+			"    prometheus :9153",
+			"    forward . /etc/resolv.conf {",
+			"       max_concurrent 1000",
+			"    }",
+			"    cache 30",
+			"    loop",
+			"    reload",
+			"    loadbalance",
+			"}",
+			"",
+		}, "\n")
+	})
+
+	table.DescribeTable("writes loaded Corefiles back unchanged",
+		func(lines ...string) {
+			text := strings.Join(lines, "\n")
+			c, err := corefile.Load(text)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.String()).To(Equal(text))
+		},
+		table.Entry("empty"),
+		table.Entry("comments and blank lines",
+			"# leading comment",
+			"",
+			".:53 { # opens the block",
+			"    # before errors",
+			"    errors # inline",
+			"",
+			"	# tab indented",
+			"    # before the closing brace",
+			"} # closes the block",
+			"",
+			"# trailing comment",
+			"",
+		),
+		table.Entry("several keys and quoted arguments",
+			"a.test b.test:1053 {",
+			"    template IN A {",
+			`        answer "{{ .Name }} 60 IN A 1.2.3.4" # quoted`,
+			"    }",
+			"}",
+			"",
+		),
+		table.Entry("nested blocks with unusual indentation",
+			"xcc.test {",
+			"  forward . 1.2.3.4 {",
+			"        policy sequential",
+			"   }",
+			"}",
+			"",
+		),
+		table.Entry("blocks opening and closing on one line",
+			". { whoami }",
+			"xcc.test {",
+			"    forward . 1.1.1.1 { max_fails 3 } # inline block",
+			"    template IN A { answer \"{{ .Name }} 60 IN A 1.2.3.4\" }",
+			"    cache { }",
+			"    a { b { c } }",
+			"}",
+			"",
+		),
+	)
+
+	It("writes a loaded kubeadm Corefile back unchanged", func() {
+		c, err := corefile.Load(kubeadmCorefile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.String()).To(Equal(kubeadmCorefile))
+	})
+
+	It("loads the server blocks and directives", func() {
+		c, err := corefile.Load(kubeadmCorefile)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.ServerBlocks).To(HaveLen(1))
+		Expect(c.ServerBlocks[0].Keys).To(Equal([]string{".:53"}))
+
+		i := c.ServerBlocks[0].FindDirective("kubernetes")
+		Expect(i).To(Equal(3))
+		kubernetes := c.ServerBlocks[0].Directives[i]
+		Expect(kubernetes.Args).To(Equal([]string{"cluster.local", "in-addr.arpa", "ip6.arpa"}))
+		Expect(kubernetes.Block.Directives).To(HaveLen(3))
+		Expect(kubernetes.Block.Directives[2].Name).To(Equal("ttl"))
+		Expect(kubernetes.Block.Directives[2].Args).To(Equal([]string{"30"}))
+
+		Expect(c.ServerBlocks[0].FindDirective("etcd")).To(Equal(-1))
+	})
+
+	It("loads keys split over several lines and braces on their own line", func() {
+		c, err := corefile.Load(strings.Join([]string{
+			"a.test,",
+			"b.test",
+			"{",
+			"    forward . 1.2.3.4",
+			"    {",
+			"        policy random",
+			"    }",
+			"}",
+		}, "\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.ServerBlocks[0].Keys).To(Equal([]string{"a.test", "b.test"}))
+		Expect(c.ServerBlocks[0].Directives[0].Block.Directives[0].Name).To(Equal("policy"))
+	})
+
+	It("loads blocks opening and closing on one line", func() {
+		c, err := corefile.Load(strings.Join([]string{
+			"xcc.test {",
+			"    forward . 1.1.1.1 { max_fails 3 }",
+			"    errors",
+			"}",
+		}, "\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		forward := c.ServerBlocks[0].Directives[0]
+		Expect(forward.Args).To(Equal([]string{".", "1.1.1.1"}))
+		Expect(forward.Block.Directives).To(HaveLen(1))
+		Expect(forward.Block.Directives[0].Name).To(Equal("max_fails"))
+		Expect(forward.Block.Directives[0].Args).To(Equal([]string{"3"}))
+		Expect(c.ServerBlocks[0].Directives[1].Name).To(Equal("errors"))
+
+		forward.Block.InsertDirective(1, &corefile.Directive{Name: "policy", Args: []string{"sequential"}})
+		Expect(c.String()).To(Equal(strings.Join([]string{
+			"xcc.test {",
+			"    forward . 1.1.1.1 {",
+			"        max_fails 3",
+			"        policy sequential",
+			"    }",
+			"    errors",
+			"}",
+			"",
+		}, "\n")))
+	})
+
+	table.DescribeTable("rejects Corefiles it cannot load",
+		func(message string, lines ...string) {
+			_, err := corefile.Load(strings.Join(lines, "\n"))
+			Expect(err).To(MatchError(message))
+		},
+		table.Entry("a block that is not closed", "line 1: block is not closed",
+			".:53 {",
+			"    errors",
+		),
+		table.Entry("a nested block that is not closed", "line 1: block is not closed",
+			".:53 {",
+			"    forward . 1.2.3.4 {",
+			"}",
+		),
+		table.Entry("keys without a block", "line 1: expected { after the server block keys",
+			".:53",
+			"errors",
+		),
+		table.Entry("an inline block that is not closed", "line 2: block is not closed",
+			".:53 {",
+			"    forward . 1.2.3.4 { max_fails 3",
+			"}",
+		),
+		table.Entry("an inline block with text after it", "line 2: unexpected }",
+			".:53 {",
+			"    forward . 1.2.3.4 { max_fails 3 } errors }",
+			"}",
+		),
+		table.Entry("a stray closing brace", "line 4: unexpected }",
+			".:53 {",
+			"    errors",
+			"}",
+			"}",
+		),
+	)
+
+	Describe("editing", func() {
+		var c *corefile.Corefile
+
+		BeforeEach(func() {
+			var err error
+			c, err = corefile.Load(strings.Join([]string{
+				".:53 {",
+				"    errors",
+				"}",
+				"# forwards other.test",
+				"other.test {",
+				"    forward . 1.2.3.5",
+				"}",
+			}, "\n"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("finds server blocks by key ignoring case and a trailing dot", func() {
+			Expect(c.FindServerBlock("other.test")).To(Equal(1))
+			Expect(c.FindServerBlock("Other.Test.")).To(Equal(1))
+			Expect(c.FindServerBlock("xcc.test")).To(Equal(-1))
+		})
+
+		It("inserts server blocks with indented directives", func() {
+			c.InsertServerBlock(1, &corefile.ServerBlock{
+				Comments: []string{"# forwards xcc.test"},
+				Keys:     []string{"xcc.test"},
+				Block: corefile.Block{
+					Directives: []*corefile.Directive{
+						{Name: "forward", Args: []string{".", "1.2.3.4"}, Block: &corefile.Block{
+							Directives: []*corefile.Directive{{Name: "policy", Args: []string{"sequential"}}},
+						}},
+						{Comments: []string{"# picks up changes"}, Name: "reload"},
+					},
+				},
+			})
+
+			Expect(c.String()).To(Equal(strings.Join([]string{
+				".:53 {",
+				"    errors",
+				"}",
+				"# forwards xcc.test",
+				"xcc.test {",
+				"    forward . 1.2.3.4 {",
+				"        policy sequential",
+				"    }",
+				"    # picks up changes",
+				"    reload",
+				"}",
+				"# forwards other.test",
+				"other.test {",
+				"    forward . 1.2.3.5",
+				"}",
+				"",
+			}, "\n")))
+		})
+
+		It("replaces server blocks", func() {
+			c.ReplaceServerBlock(1, &corefile.ServerBlock{
+				Keys: []string{"xcc.test"},
+				Block: corefile.Block{
+					Directives: []*corefile.Directive{{Name: "forward", Args: []string{".", "1.2.3.4"}}},
+				},
+			})
+
+			Expect(c.String()).To(Equal(strings.Join([]string{
+				".:53 {",
+				"    errors",
+				"}",
+				"xcc.test {",
+				"    forward . 1.2.3.4",
+				"}",
+				"",
+			}, "\n")))
+		})
+
+		It("removes server blocks with their comments", func() {
+			c.RemoveServerBlock(1)
+
+			Expect(c.String()).To(Equal(strings.Join([]string{
+				".:53 {",
+				"    errors",
+				"}",
+				"",
+			}, "\n")))
+		})
+
+		It("inserts, replaces and removes directives keeping their indentation", func() {
+			serverBlock := c.ServerBlocks[0]
+			serverBlock.InsertDirective(1, &corefile.Directive{Name: "cache", Args: []string{"30"}})
+			serverBlock.ReplaceDirective(serverBlock.FindDirective("errors"), &corefile.Directive{Name: "log"})
+			c.ServerBlocks[1].RemoveDirective(0)
+
+			Expect(c.String()).To(Equal(strings.Join([]string{
+				".:53 {",
+				"    log",
+				"    cache 30",
+				"}",
+				"# forwards other.test",
+				"other.test {",
+				"}",
+				"",
+			}, "\n")))
+		})
+	})
+})
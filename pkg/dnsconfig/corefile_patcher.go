@@ -6,11 +6,14 @@ package dnsconfig
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig/corefile"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -21,52 +24,265 @@ type CorefilePatcher struct {
 
 	Namespace     string
 	ConfigMapName string
+
+	// StubDomain adds domain suffixes and options to the stub domain block.
+	StubDomain StubDomainConfig
+
+	// CopyBind adds the bind directive of the other server blocks to the
+	// stub domain block, for Corefiles whose servers only listen on some
+	// addresses, like the one of NodeLocal DNSCache.
+	CopyBind bool
+}
+
+func (c *CorefilePatcher) ConfigMapKey() client.ObjectKey {
+	return client.ObjectKey{Namespace: c.Namespace, Name: c.ConfigMapName}
 }
 
 const sectionBegin string = "### BEGIN CROSS CLUSTER CONNECTIVITY"
 const sectionEnd string = "### END CROSS CLUSTER CONNECTIVITY"
 
-const xccForwardBlockTemplate string = `%s
-%s {
-    forward . %s
-    reload
+func (c *CorefilePatcher) AppendStubDomainBlock(forwardingIPs ...string) error {
+	_, err := c.EnsureStubDomainBlock(forwardingIPs...)
+	return err
 }
-%s`
 
-var serverBlockRegexp = regexp.MustCompile(`(?s)\s` + sectionBegin + ".*" + sectionEnd)
+// EnsureStubDomainBlock is AppendStubDomainBlock that also returns whether the
+// ConfigMap had to be updated.
+func (c *CorefilePatcher) EnsureStubDomainBlock(forwardingIPs ...string) (bool, error) {
+	if len(forwardingIPs) == 0 {
+		return false, errNoForwardingIPs
+	}
 
-func (c *CorefilePatcher) AppendStubDomainBlock(forwardingIP string) error {
 	var configMap corev1.ConfigMap
 	err := c.Client.Get(context.Background(), client.ObjectKey{
 		Namespace: c.Namespace,
 		Name:      c.ConfigMapName,
 	}, &configMap)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	original := configMap.Data["Corefile"]
+	coreFile, err := corefile.Load(original)
+	if err != nil {
+		return false, fmt.Errorf("refusing to update ConfigMap %s/%s: %w", c.Namespace, c.ConfigMapName, err)
 	}
 
-	xccBlock := fmt.Sprintf(xccForwardBlockTemplate,
-		sectionBegin,
-		c.DomainSuffix,
-		forwardingIP,
-		sectionEnd,
-	)
+	if _, err := stripStubDomainBlocks(coreFile); err != nil {
+		return false, fmt.Errorf("refusing to update ConfigMap %s/%s: %w", c.Namespace, c.ConfigMapName, err)
+	}
+
+	domainSuffixes := c.StubDomain.domainSuffixes(c.DomainSuffix)
+	directives := c.stubDomainDirectives(forwardingIPs)
+	if c.CopyBind {
+		bind := findBind(coreFile)
+		if bind == nil {
+			return false, fmt.Errorf("refusing to update ConfigMap %s/%s: no server block has a bind directive to copy", c.Namespace, c.ConfigMapName)
+		}
+		directives = append([]*corefile.Directive{bind}, directives...)
+	}
 
-	corefile := configMap.Data["Corefile"]
+	// The comments at the end of the Corefile stay in front of the block.
+	coreFile.ServerBlocks = append(coreFile.ServerBlocks, &corefile.ServerBlock{
+		Comments: append(coreFile.Footer, sectionBegin),
+		Keys:     domainSuffixes,
+		Block:    corefile.Block{Directives: directives},
+	})
+	coreFile.Footer = []string{sectionEnd}
 
-	if strings.Contains(corefile, xccBlock) {
+	patched := coreFile.String()
+	if patched == original {
 		c.Log.Info("up to date, skipping modification", "ConfigMap", fmt.Sprintf("%s/%s", c.Namespace, c.ConfigMapName))
-		return nil
+		return false, nil
 	}
 
-	corefile = stripXCCBlock(corefile)
+	if err := ValidateCorefile(patched, domainSuffixes...); err != nil {
+		return false, fmt.Errorf("refusing to update ConfigMap %s/%s: %w", c.Namespace, c.ConfigMapName, err)
+	}
 
-	configMap.Data["Corefile"] = fmt.Sprintf("%s\n%s\n", corefile, xccBlock)
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data["Corefile"] = patched
 	c.Log.Info("updating Corefile", "ConfigMap", fmt.Sprintf("%s/%s", c.Namespace, c.ConfigMapName))
 
+	return true, c.Client.Update(context.Background(), &configMap)
+}
+
+// stubDomainDirectives returns the directives of the stub domain block.
+func (c *CorefilePatcher) stubDomainDirectives(forwardingIPs []string) []*corefile.Directive {
+	var directives []*corefile.Directive
+	if c.StubDomain.Errors {
+		directives = append(directives, &corefile.Directive{Name: "errors"})
+	}
+	if c.StubDomain.Cache != nil {
+		directives = append(directives, &corefile.Directive{Name: "cache", Args: []string{strconv.Itoa(int(*c.StubDomain.Cache))}})
+	}
+
+	forward := &corefile.Directive{Name: "forward", Args: append([]string{"."}, c.StubDomain.nameservers(forwardingIPs)...)}
+	options := c.StubDomain.Forward
+	// The upstreams only back up the dns-server.
+	if options.Policy == "" && len(c.StubDomain.Upstreams) > 0 {
+		options.Policy = "sequential"
+	}
+	var forwardOptions []*corefile.Directive
+	if options.Policy != "" {
+		forwardOptions = append(forwardOptions, &corefile.Directive{Name: "policy", Args: []string{options.Policy}})
+	}
+	if options.HealthCheck != "" {
+		forwardOptions = append(forwardOptions, &corefile.Directive{Name: "health_check", Args: []string{options.HealthCheck}})
+	}
+	if options.MaxFails != nil {
+		forwardOptions = append(forwardOptions, &corefile.Directive{Name: "max_fails", Args: []string{strconv.Itoa(int(*options.MaxFails))}})
+	}
+	if len(forwardOptions) > 0 {
+		forward.Block = &corefile.Block{Directives: forwardOptions}
+	}
+
+	return append(directives, forward, &corefile.Directive{Name: "reload"})
+}
+
+// findBind returns a copy of the bind directive of the first server block that
+// has one, or nil.
+func findBind(coreFile *corefile.Corefile) *corefile.Directive {
+	for _, serverBlock := range coreFile.ServerBlocks {
+		if i := serverBlock.FindDirective("bind"); i >= 0 {
+			return &corefile.Directive{Name: "bind", Args: serverBlock.Directives[i].Args}
+		}
+	}
+	return nil
+}
+
+// NodeLocalDNSServer returns the host:port NodeLocal DNSCache answers on, the
+// first address of the bind directive of its Corefile, which the stub domain
+// block also listens on.
+func NodeLocalDNSServer(ctx context.Context, reader client.Reader, opts PatcherOptions) (string, error) {
+	var configMap corev1.ConfigMap
+	err := reader.Get(ctx, client.ObjectKey{Namespace: opts.Namespace, Name: opts.NodeLocalDNSConfigMapName}, &configMap)
+	if err != nil {
+		return "", err
+	}
+	coreFile, err := corefile.Load(configMap.Data["Corefile"])
+	if err != nil {
+		return "", fmt.Errorf("ConfigMap %s/%s: %w", opts.Namespace, opts.NodeLocalDNSConfigMapName, err)
+	}
+	bind := findBind(coreFile)
+	if bind == nil || len(bind.Args) == 0 {
+		return "", fmt.Errorf("ConfigMap %s/%s: no server block has a bind directive", opts.Namespace, opts.NodeLocalDNSConfigMapName)
+	}
+	if net.ParseIP(bind.Args[0]) == nil {
+		return "", fmt.Errorf("ConfigMap %s/%s: the bind address %q is not an IP", opts.Namespace, opts.NodeLocalDNSConfigMapName, bind.Args[0])
+	}
+	return net.JoinHostPort(bind.Args[0], "53"), nil
+}
+
+// RemoveStubDomainBlock removes the block added by AppendStubDomainBlock. It
+// does nothing when the ConfigMap or the block does not exist, so it is safe
+// to run more than once, e.g. from a pre-delete hook.
+func (c *CorefilePatcher) RemoveStubDomainBlock() error {
+	var configMap corev1.ConfigMap
+	err := c.Client.Get(context.Background(), client.ObjectKey{
+		Namespace: c.Namespace,
+		Name:      c.ConfigMapName,
+	}, &configMap)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			c.Log.Info("ConfigMap not found, nothing to remove", "ConfigMap", fmt.Sprintf("%s/%s", c.Namespace, c.ConfigMapName))
+			return nil
+		}
+		return err
+	}
+
+	coreFile, err := corefile.Load(configMap.Data["Corefile"])
+	if err != nil {
+		return fmt.Errorf("refusing to update ConfigMap %s/%s: %w", c.Namespace, c.ConfigMapName, err)
+	}
+
+	stripped, err := stripStubDomainBlocks(coreFile)
+	if err != nil {
+		return fmt.Errorf("refusing to update ConfigMap %s/%s: %w", c.Namespace, c.ConfigMapName, err)
+	}
+	if !stripped {
+		c.Log.Info("stub domain block not found, skipping modification", "ConfigMap", fmt.Sprintf("%s/%s", c.Namespace, c.ConfigMapName))
+		return nil
+	}
+
+	patched := coreFile.String()
+	if err := verifyStripped(patched); err != nil {
+		return fmt.Errorf("refusing to update ConfigMap %s/%s: %w", c.Namespace, c.ConfigMapName, err)
+	}
+
+	configMap.Data["Corefile"] = patched
+	c.Log.Info("removing stub domain block from Corefile", "ConfigMap", fmt.Sprintf("%s/%s", c.Namespace, c.ConfigMapName))
+
 	return c.Client.Update(context.Background(), &configMap)
 }
 
-func stripXCCBlock(corefile string) string {
-	return strings.TrimSpace(serverBlockRegexp.ReplaceAllString(corefile, ""))
+// verifyStripped checks that no part of the block is left behind in a
+// Corefile it was stripped from, and that the rest of it is still valid.
+func verifyStripped(corefile string) error {
+	if strings.Contains(corefile, sectionBegin) || strings.Contains(corefile, sectionEnd) {
+		return fmt.Errorf("Corefile has unmatched %q or %q markers", sectionBegin, sectionEnd)
+	}
+	return ValidateCorefile(corefile)
+}
+
+// stripStubDomainBlocks removes the server blocks between each pair of
+// markers, together with the markers, and the blank lines left at the end of
+// the Corefile. Comments before a marker are kept. It returns whether there
+// were any markers.
+func stripStubDomainBlocks(coreFile *corefile.Corefile) (bool, error) {
+	found, inside := false, false
+	var carried []string
+	stripComments := func(comments []string) ([]string, error) {
+		var kept []string
+		for _, comment := range comments {
+			switch strings.TrimSpace(comment) {
+			case sectionBegin:
+				if inside {
+					return nil, fmt.Errorf("Corefile has unmatched %q marker", sectionBegin)
+				}
+				found, inside = true, true
+			case sectionEnd:
+				if !inside {
+					return nil, fmt.Errorf("Corefile has unmatched %q marker", sectionEnd)
+				}
+				inside = false
+			default:
+				if !inside {
+					kept = append(kept, comment)
+				}
+			}
+		}
+		return kept, nil
+	}
+
+	var serverBlocks []*corefile.ServerBlock
+	for _, serverBlock := range coreFile.ServerBlocks {
+		comments, err := stripComments(serverBlock.Comments)
+		if err != nil {
+			return false, err
+		}
+		carried = append(carried, comments...)
+		if inside {
+			continue
+		}
+		serverBlock.Comments, carried = carried, nil
+		serverBlocks = append(serverBlocks, serverBlock)
+	}
+
+	footer, err := stripComments(coreFile.Footer)
+	if err != nil {
+		return false, err
+	}
+	if inside {
+		return false, fmt.Errorf("Corefile has unmatched %q marker", sectionBegin)
+	}
+	footer = append(carried, footer...)
+	for len(footer) > 0 && strings.TrimSpace(footer[len(footer)-1]) == "" {
+		footer = footer[:len(footer)-1]
+	}
+
+	coreFile.ServerBlocks, coreFile.Footer = serverBlocks, footer
+	return found, nil
 }
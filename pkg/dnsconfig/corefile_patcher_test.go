@@ -5,6 +5,7 @@ package dnsconfig_test
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
@@ -231,4 +232,423 @@ var _ = Describe("CorefilePatcher", func() {
 			}, "\n")))
 		})
 	})
+
+	Context("when the system corefile configmap has several xcc-dns blocks and hand edits", func() {
+		BeforeEach(func() {
+			corednsConfigMap.Data["Corefile"] = strings.Join([]string{
+				"# cluster DNS",
+				".:53 {",
+				"    original_zone_content # keep me",
+				"}",
+				"### BEGIN CROSS CLUSTER CONNECTIVITY",
+				"xcc.test {",
+				"    forward . 42.42.42.42",
+				"    # added by hand",
+				"    cache 30",
+				"}",
+				"### END CROSS CLUSTER CONNECTIVITY",
+				"# forwards other.test",
+				"other-zone.foobar {",
+				"    forward . 1.2.3.5",
+				"}",
+				"### BEGIN CROSS CLUSTER CONNECTIVITY",
+				"xcc-old.test {",
+				"    forward . 42.42.42.42",
+				"}",
+				"### END CROSS CLUSTER CONNECTIVITY",
+				"# end of Corefile",
+				"",
+			}, "\n")
+			err := kubeClient.Create(context.Background(), &corednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("replaces all of them with a single block and keeps the other comments", func() {
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(strings.Join([]string{
+				"# cluster DNS",
+				".:53 {",
+				"    original_zone_content # keep me",
+				"}",
+				"# forwards other.test",
+				"other-zone.foobar {",
+				"    forward . 1.2.3.5",
+				"}",
+				"# end of Corefile",
+				"### BEGIN CROSS CLUSTER CONNECTIVITY",
+				"xcc.test {",
+				"    forward . 1.2.3.4",
+				"    reload",
+				"}",
+				"### END CROSS CLUSTER CONNECTIVITY",
+				"",
+			}, "\n")))
+		})
+	})
+
+	Context("when the stub domain is configured", func() {
+		BeforeEach(func() {
+			cache, maxFails := int32(30), int32(3)
+			patcher.StubDomain = dnsconfig.StubDomainConfig{
+				DomainSuffixes: []string{"10.0.0.0/8", "xcc.test"},
+				Upstreams:      []string{"10.0.0.53"},
+				Errors:         true,
+				Cache:          &cache,
+				Forward: dnsconfig.ForwardOptions{
+					Policy:      "sequential",
+					HealthCheck: "5s",
+					MaxFails:    &maxFails,
+				},
+			}
+
+			err := kubeClient.Create(context.Background(), &corednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("forwards all domain suffixes with the options", func() {
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(strings.Join([]string{
+				".:53 {",
+				"    original_zone_content",
+				"}",
+				"### BEGIN CROSS CLUSTER CONNECTIVITY",
+				"xcc.test 10.0.0.0/8 {",
+				"    errors",
+				"    cache 30",
+				"    forward . 1.2.3.4 10.0.0.53 {",
+				"        policy sequential",
+				"        health_check 5s",
+				"        max_fails 3",
+				"    }",
+				"    reload",
+				"}",
+				"### END CROSS CLUSTER CONNECTIVITY",
+				"",
+			}, "\n")))
+		})
+
+		It("forwards to the upstreams after the dns-server without a policy", func() {
+			patcher.StubDomain = dnsconfig.StubDomainConfig{
+				Upstreams: []string{"10.0.0.53"},
+			}
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(ContainSubstring(strings.Join([]string{
+				"    forward . 1.2.3.4 10.0.0.53 {",
+				"        policy sequential",
+				"    }",
+			}, "\n")))
+		})
+	})
+
+	Context("when the Corefile is the one of NodeLocal DNSCache", func() {
+		BeforeEach(func() {
+			patcher.ConfigMapName = "node-local-dns"
+			patcher.CopyBind = true
+
+			corednsConfigMap.Name = "node-local-dns"
+			corednsConfigMap.Data["Corefile"] = strings.Join([]string{
+				"cluster.local:53 {",
+				"    errors",
+				"    cache 30",
+				"    bind 169.254.20.10 10.96.0.10",
+				"    forward . __PILLAR__CLUSTER__DNS__ {",
+				"        force_tcp",
+				"    }",
+				"}",
+				".:53 {",
+				"    errors",
+				"    bind 169.254.20.10 10.96.0.10",
+				"    forward . __PILLAR__UPSTREAM__SERVERS__",
+				"}",
+			}, "\n")
+			err := kubeClient.Create(context.Background(), &corednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("listens on the addresses of the other server blocks", func() {
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "node-local-dns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(HaveSuffix(strings.Join([]string{
+				"}",
+				"### BEGIN CROSS CLUSTER CONNECTIVITY",
+				"xcc.test {",
+				"    bind 169.254.20.10 10.96.0.10",
+				"    forward . 1.2.3.4",
+				"    reload",
+				"}",
+				"### END CROSS CLUSTER CONNECTIVITY",
+				"",
+			}, "\n")))
+		})
+
+		It("answers on the first bind address", func() {
+			server, err := dnsconfig.NodeLocalDNSServer(context.Background(), kubeClient, dnsconfig.PatcherOptions{
+				Namespace:                 "kube-system",
+				NodeLocalDNSConfigMapName: "node-local-dns",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server).To(Equal("169.254.20.10:53"))
+		})
+	})
+
+	Context("when the system corefile has blocks on a single line", func() {
+		BeforeEach(func() {
+			corednsConfigMap.Data["Corefile"] = strings.Join([]string{
+				".:53 {",
+				"    forward . 1.1.1.1 { max_fails 3 }",
+				"}",
+			}, "\n")
+			err := kubeClient.Create(context.Background(), &corednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("appends the server block and keeps them as they were", func() {
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(strings.Join([]string{
+				".:53 {",
+				"    forward . 1.1.1.1 { max_fails 3 }",
+				"}",
+				"### BEGIN CROSS CLUSTER CONNECTIVITY",
+				"xcc.test {",
+				"    forward . 1.2.3.4",
+				"    reload",
+				"}",
+				"### END CROSS CLUSTER CONNECTIVITY",
+				"",
+			}, "\n")))
+		})
+	})
+
+	Context("when another server block already serves the domain suffix", func() {
+		var originalCorefile string
+
+		BeforeEach(func() {
+			originalCorefile = strings.Join([]string{
+				".:53 {",
+				"    original_zone_content",
+				"}",
+				"xcc.test:53 {",
+				"    forward . 42.42.42.42",
+				"}",
+			}, "\n")
+			corednsConfigMap.Data["Corefile"] = originalCorefile
+			err := kubeClient.Create(context.Background(), &corednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error naming the conflicting block and leaves the configmap untouched", func() {
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).To(MatchError(ContainSubstring(`server block "xcc.test:53" (line 4)`)))
+
+			var conflict *dnsconfig.ZoneConflictError
+			Expect(errors.As(err, &conflict)).To(BeTrue())
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(originalCorefile))
+		})
+	})
+
+	Context("when the system corefile is malformed", func() {
+		var originalCorefile string
+
+		BeforeEach(func() {
+			originalCorefile = strings.Join([]string{
+				".:53 {",
+				"    original_zone_content",
+			}, "\n")
+			corednsConfigMap.Data["Corefile"] = originalCorefile
+			err := kubeClient.Create(context.Background(), &corednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error and leaves the configmap untouched", func() {
+			err := patcher.AppendStubDomainBlock(forwardingIP)
+			Expect(err).To(MatchError(ContainSubstring("line 1: block is not closed")))
+
+			err = kubeClient.Get(context.Background(), client.ObjectKey{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			}, &updatedCorednsConfigMap)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(originalCorefile))
+		})
+	})
+
+	Describe("RemoveStubDomainBlock", func() {
+		It("succeeds when the configmap does not exist", func() {
+			Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+		})
+
+		Context("when the system corefile configmap has been updated", func() {
+			BeforeEach(func() {
+				corednsConfigMap.Data["Corefile"] = strings.Join([]string{
+					".:53 {",
+					"    original_zone_content",
+					"}",
+					"### BEGIN CROSS CLUSTER CONNECTIVITY",
+					"xcc.test {",
+					"    forward . 1.2.3.4",
+					"    reload",
+					"}",
+					"### END CROSS CLUSTER CONNECTIVITY",
+					"",
+					"other-zone.foobar {",
+					"    forward . 1.2.3.5",
+					"}",
+				}, "\n")
+				err := kubeClient.Create(context.Background(), &corednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("removes the server block and keeps the rest of the Corefile", func() {
+				Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+
+				err := kubeClient.Get(context.Background(), client.ObjectKey{
+					Name:      "coredns",
+					Namespace: "kube-system",
+				}, &updatedCorednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(strings.Join([]string{
+					".:53 {",
+					"    original_zone_content",
+					"}",
+					"",
+					"other-zone.foobar {",
+					"    forward . 1.2.3.5",
+					"}",
+					"",
+				}, "\n")))
+			})
+
+			It("does not update the configmap when run again", func() {
+				Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+
+				err := kubeClient.Get(context.Background(), client.ObjectKey{
+					Name:      "coredns",
+					Namespace: "kube-system",
+				}, &updatedCorednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+				resourceVersion := updatedCorednsConfigMap.ResourceVersion
+
+				Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+
+				err = kubeClient.Get(context.Background(), client.ObjectKey{
+					Name:      "coredns",
+					Namespace: "kube-system",
+				}, &updatedCorednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedCorednsConfigMap.ResourceVersion).To(Equal(resourceVersion))
+			})
+		})
+
+		Context("when the server block is at the start of the Corefile", func() {
+			BeforeEach(func() {
+				corednsConfigMap.Data["Corefile"] = strings.Join([]string{
+					"### BEGIN CROSS CLUSTER CONNECTIVITY",
+					"xcc.test {",
+					"    forward . 1.2.3.4",
+					"    reload",
+					"}",
+					"### END CROSS CLUSTER CONNECTIVITY",
+					".:53 {",
+					"    original_zone_content",
+					"}",
+				}, "\n")
+				err := kubeClient.Create(context.Background(), &corednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("removes the server block", func() {
+				Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+
+				err := kubeClient.Get(context.Background(), client.ObjectKey{
+					Name:      "coredns",
+					Namespace: "kube-system",
+				}, &updatedCorednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(strings.Join([]string{
+					".:53 {",
+					"    original_zone_content",
+					"}",
+					"",
+				}, "\n")))
+			})
+		})
+
+		Context("when the end marker of the server block is missing", func() {
+			var originalCorefile string
+
+			BeforeEach(func() {
+				originalCorefile = strings.Join([]string{
+					".:53 {",
+					"    original_zone_content",
+					"}",
+					"### BEGIN CROSS CLUSTER CONNECTIVITY",
+					"xcc.test {",
+					"    forward . 1.2.3.4",
+					"}",
+				}, "\n")
+				corednsConfigMap.Data["Corefile"] = originalCorefile
+				err := kubeClient.Create(context.Background(), &corednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns an error and leaves the configmap untouched", func() {
+				err := patcher.RemoveStubDomainBlock()
+				Expect(err).To(MatchError(ContainSubstring("unmatched")))
+
+				err = kubeClient.Get(context.Background(), client.ObjectKey{
+					Name:      "coredns",
+					Namespace: "kube-system",
+				}, &updatedCorednsConfigMap)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedCorednsConfigMap.Data["Corefile"]).To(Equal(originalCorefile))
+			})
+		})
+	})
 })
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	corefileReconcilesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dns_config_patcher_corefile_reconciles_total",
+		Help: "Number of times the Corefile was checked for drift, by result: up_to_date, patched, error, service_not_found or service_not_ready.",
+	}, []string{"result"})
+
+	corefileInSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "dns_config_patcher_corefile_in_sync",
+		Help: "1 when the Corefile forwards the domain suffix to the DNS Service, 0 otherwise.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(corefileReconcilesTotal, corefileInSync)
+}
+
+// CorefileReconciler keeps the stub domain of the cluster DNS configuration
+// forwarding to the ClusterIP of the DNS Service, or to the IPs of its ready
+// pods when the Service is headless. It re-applies the stub domain when
+// something else rewrites the ConfigMap, the ClusterIP of the Service changes
+// or its pods come and go.
+type CorefileReconciler struct {
+	Client  client.Client
+	Log     logr.Logger
+	Patcher Patcher
+
+	DNSServiceNamespace string
+	DNSServiceName      string
+
+	synced int32
+}
+
+func (r *CorefileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("ConfigMap", r.Patcher.ConfigMapKey().String())
+
+	addresses, err := dnsServiceAddresses(ctx, r.Client, r.DNSServiceNamespace, r.DNSServiceName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// The Service watch triggers another reconcile once it exists.
+			log.Info("DNS Service not found, waiting for it", "Service", fmt.Sprintf("%s/%s", r.DNSServiceNamespace, r.DNSServiceName))
+			corefileReconcilesTotal.WithLabelValues("service_not_found").Inc()
+			corefileInSync.Set(0)
+			return ctrl.Result{}, nil
+		}
+		if errors.Is(err, errServiceNotReady) {
+			// So do the Service and EndpointSlice watches once it is ready.
+			log.Info("DNS Service has nothing to forward to, waiting for it", "reason", err.Error())
+			corefileReconcilesTotal.WithLabelValues("service_not_ready").Inc()
+			corefileInSync.Set(0)
+			return ctrl.Result{}, nil
+		}
+		corefileReconcilesTotal.WithLabelValues("error").Inc()
+		return ctrl.Result{}, err
+	}
+
+	patched, err := r.Patcher.EnsureStubDomainBlock(addresses...)
+	if err != nil {
+		log.Error(err, "Failed to patch Corefile")
+		corefileReconcilesTotal.WithLabelValues("error").Inc()
+		corefileInSync.Set(0)
+		return ctrl.Result{}, err
+	}
+
+	if patched {
+		log.Info("Re-applied stub domain block", "addresses", addresses)
+		corefileReconcilesTotal.WithLabelValues("patched").Inc()
+	} else {
+		corefileReconcilesTotal.WithLabelValues("up_to_date").Inc()
+	}
+	corefileInSync.Set(1)
+	atomic.StoreInt32(&r.synced, 1)
+	return ctrl.Result{}, nil
+}
+
+// ReadyzCheck is a healthz.Checker failing until the stub domain was first
+// found up to date or patched.
+func (r *CorefileReconciler) ReadyzCheck(*http.Request) error {
+	if atomic.LoadInt32(&r.synced) == 0 {
+		return errors.New("the stub domain has not been reconciled yet")
+	}
+	return nil
+}
+
+func (r *CorefileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isCorefileConfigMap := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return client.ObjectKeyFromObject(object) == r.Patcher.ConfigMapKey()
+	})
+	isDNSService := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetNamespace() == r.DNSServiceNamespace && object.GetName() == r.DNSServiceName
+	})
+	isDNSServiceEndpointSlice := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetNamespace() == r.DNSServiceNamespace && object.GetLabels()[discoveryv1.LabelServiceName] == r.DNSServiceName
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("corefile").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isCorefileConfigMap)).
+		Watches(
+			&source.Kind{Type: &corev1.Service{}},
+			handler.EnqueueRequestsFromMapFunc(r.corefileRequest),
+			builder.WithPredicates(isDNSService),
+		).
+		Watches(
+			&source.Kind{Type: &discoveryv1.EndpointSlice{}},
+			handler.EnqueueRequestsFromMapFunc(r.corefileRequest),
+			builder.WithPredicates(isDNSServiceEndpointSlice),
+		).
+		Complete(r)
+}
+
+// corefileRequest maps every DNS Service and EndpointSlice event to the
+// patched ConfigMap.
+func (r *CorefileReconciler) corefileRequest(client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: r.Patcher.ConfigMapKey()}}
+}
@@ -0,0 +1,318 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CorefileReconciler", func() {
+	var (
+		kubeClient client.Client
+
+		reconciler *dnsconfig.CorefileReconciler
+		request    ctrl.Request
+
+		corednsConfigMap corev1.ConfigMap
+		dnsService       corev1.Service
+
+		patchedCorefile string
+	)
+
+	getCorefile := func() string {
+		var configMap corev1.ConfigMap
+		err := kubeClient.Get(context.Background(), client.ObjectKey{
+			Name:      "coredns",
+			Namespace: "kube-system",
+		}, &configMap)
+		Expect(err).NotTo(HaveOccurred())
+		return configMap.Data["Corefile"]
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+
+		kubeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		ctrl.SetLogger(zap.New(
+			zap.UseDevMode(true),
+			zap.WriteTo(GinkgoWriter),
+		))
+
+		log := ctrl.Log.WithName("dnsconfig").WithName("CorefileReconciler")
+
+		reconciler = &dnsconfig.CorefileReconciler{
+			Client: kubeClient,
+			Log:    log,
+			Patcher: &dnsconfig.CorefilePatcher{
+				Client:        kubeClient,
+				Log:           log,
+				DomainSuffix:  "xcc.test",
+				Namespace:     "kube-system",
+				ConfigMapName: "coredns",
+			},
+			DNSServiceNamespace: "xcc-dns",
+			DNSServiceName:      "dns-server",
+		}
+		request = ctrl.Request{NamespacedName: types.NamespacedName{
+			Namespace: "kube-system",
+			Name:      "coredns",
+		}}
+
+		corednsConfigMap = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "coredns",
+				Namespace: "kube-system",
+			},
+			Data: map[string]string{
+				"Corefile": strings.Join([]string{
+					".:53 {",
+					"    original_zone_content",
+					"}",
+				}, "\n"),
+			},
+		}
+		Expect(kubeClient.Create(context.Background(), &corednsConfigMap)).To(Succeed())
+
+		dnsService = corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dns-server",
+				Namespace: "xcc-dns",
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: "1.2.3.4",
+			},
+		}
+
+		patchedCorefile = strings.Join([]string{
+			".:53 {",
+			"    original_zone_content",
+			"}",
+			"### BEGIN CROSS CLUSTER CONNECTIVITY",
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+			"    reload",
+			"}",
+			"### END CROSS CLUSTER CONNECTIVITY",
+			"",
+		}, "\n")
+	})
+
+	Context("when the DNS service exists", func() {
+		BeforeEach(func() {
+			Expect(kubeClient.Create(context.Background(), &dnsService)).To(Succeed())
+		})
+
+		It("appends the stub domain block forwarding to the DNS service", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(Equal(patchedCorefile))
+		})
+
+		It("does not update the configmap when it is up to date", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			var configMap corev1.ConfigMap
+			Expect(kubeClient.Get(context.Background(), request.NamespacedName, &configMap)).To(Succeed())
+			resourceVersion := configMap.ResourceVersion
+
+			_, err = reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(kubeClient.Get(context.Background(), request.NamespacedName, &configMap)).To(Succeed())
+			Expect(configMap.ResourceVersion).To(Equal(resourceVersion))
+		})
+
+		It("re-applies the stub domain block when the Corefile is overwritten", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			var configMap corev1.ConfigMap
+			Expect(kubeClient.Get(context.Background(), request.NamespacedName, &configMap)).To(Succeed())
+			configMap.Data["Corefile"] = strings.Join([]string{
+				".:53 {",
+				"    original_zone_content",
+				"}",
+			}, "\n")
+			Expect(kubeClient.Update(context.Background(), &configMap)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(Equal(patchedCorefile))
+		})
+
+		It("forwards to the new ClusterIP when the DNS service is recreated", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(kubeClient.Delete(context.Background(), &dnsService)).To(Succeed())
+			dnsService.ResourceVersion = ""
+			dnsService.Spec.ClusterIP = "5.6.7.8"
+			Expect(kubeClient.Create(context.Background(), &dnsService)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(ContainSubstring("forward . 5.6.7.8"))
+			Expect(getCorefile()).NotTo(ContainSubstring("forward . 1.2.3.4"))
+		})
+	})
+
+	Context("when the DNS service does not exist", func() {
+		It("is not ready until the stub domain is reconciled", func() {
+			Expect(reconciler.ReadyzCheck(nil)).NotTo(Succeed())
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reconciler.ReadyzCheck(nil)).NotTo(Succeed())
+
+			Expect(kubeClient.Create(context.Background(), &dnsService)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reconciler.ReadyzCheck(nil)).To(Succeed())
+		})
+
+		It("leaves the configmap untouched without failing", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(Equal(corednsConfigMap.Data["Corefile"]))
+		})
+
+		It("counts the reconcile as service_not_found", func() {
+			before := corefileReconciles("service_not_found")
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(corefileReconciles("service_not_found")).To(Equal(before + 1))
+		})
+	})
+
+	Context("when the DNS service does not have a ClusterIP yet", func() {
+		BeforeEach(func() {
+			dnsService.Spec.ClusterIP = ""
+			Expect(kubeClient.Create(context.Background(), &dnsService)).To(Succeed())
+		})
+
+		It("leaves the configmap untouched without failing", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(Equal(corednsConfigMap.Data["Corefile"]))
+		})
+
+		It("counts the reconcile as service_not_ready", func() {
+			before := corefileReconciles("service_not_ready")
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(corefileReconciles("service_not_ready")).To(Equal(before + 1))
+		})
+	})
+
+	Context("when the DNS service is headless", func() {
+		var endpointSlice discoveryv1.EndpointSlice
+
+		BeforeEach(func() {
+			dnsService.Spec.ClusterIP = corev1.ClusterIPNone
+			Expect(kubeClient.Create(context.Background(), &dnsService)).To(Succeed())
+
+			ready, notReady := true, false
+			endpointSlice = discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "dns-server-abcde",
+					Namespace: "xcc-dns",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: "dns-server"},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints: []discoveryv1.Endpoint{
+					{Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}},
+					{Addresses: []string{"10.0.0.1"}},
+					{Addresses: []string{"10.0.0.3"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+				},
+			}
+			Expect(kubeClient.Create(context.Background(), &endpointSlice)).To(Succeed())
+
+			Expect(kubeClient.Create(context.Background(), &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "other-service-abcde",
+					Namespace: "xcc-dns",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: "other-service"},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.9"}}},
+			})).To(Succeed())
+		})
+
+		It("forwards to the ready endpoints of the DNS service", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(ContainSubstring("forward . 10.0.0.1 10.0.0.2\n"))
+		})
+
+		It("follows the endpoints as pods come and go", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			endpointSlice.Endpoints = []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.4"}}}
+			Expect(kubeClient.Update(context.Background(), &endpointSlice)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(ContainSubstring("forward . 10.0.0.4\n"))
+		})
+
+		It("leaves the configmap untouched without failing when no endpoint is ready", func() {
+			Expect(kubeClient.Delete(context.Background(), &endpointSlice)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getCorefile()).To(Equal(corednsConfigMap.Data["Corefile"]))
+		})
+	})
+})
+
+// corefileReconciles returns the dns_config_patcher_corefile_reconciles_total
+// counter of the result.
+func corefileReconciles(result string) float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != "dns_config_patcher_corefile_reconciles_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" && label.GetValue() == result {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coredns/caddy/caddyfile"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+)
+
+// ZoneConflictError is returned when two server blocks of a Corefile serve
+// the same zone on the same port, which CoreDNS refuses to start with.
+type ZoneConflictError struct {
+	// Zone is the conflicting zone, e.g. dns://xcc.test.:53.
+	Zone string
+
+	// Block and ConflictingBlock are the server block keys that both serve
+	// Zone, with the line the block starts on.
+	Block            string
+	ConflictingBlock string
+}
+
+func (e *ZoneConflictError) Error() string {
+	return fmt.Sprintf("zone %s is served by both server block %s and server block %s", e.Zone, e.Block, e.ConflictingBlock)
+}
+
+// ValidateCorefile parses the corefile the way CoreDNS does and checks that no
+// zone is served by more than one server block, and that each of servedZones
+// is served by one. Directives are not checked
+// against the plugins compiled into CoreDNS, because that depends on the build
+// running in the cluster, and files imported by the corefile are skipped since
+// they only exist in the CoreDNS pods.
+func ValidateCorefile(corefile string, servedZones ...string) error {
+	serverBlocks, err := caddyfile.Parse("Corefile", strings.NewReader(withoutFileImports(corefile)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to parse Corefile: %w", err)
+	}
+
+	blockLines := serverBlockLines(corefile)
+	if len(blockLines) != len(serverBlocks) {
+		// Snippets or blocks without braces; the lines would be misleading.
+		blockLines = nil
+	}
+	describe := func(i int, key string) string {
+		if i < len(blockLines) {
+			return fmt.Sprintf("%q (line %d)", key, blockLines[i])
+		}
+		return fmt.Sprintf("%q", key)
+	}
+
+	servedBy := map[string]string{}
+	for i, serverBlock := range serverBlocks {
+		for _, key := range serverBlock.Keys {
+			zones, err := normalizeZones(key)
+			if err != nil {
+				return fmt.Errorf("invalid server block %s: %w", describe(i, key), err)
+			}
+			for _, zone := range zones {
+				if block, ok := servedBy[zone]; ok {
+					return &ZoneConflictError{
+						Zone:             zone,
+						Block:            block,
+						ConflictingBlock: describe(i, key),
+					}
+				}
+				servedBy[zone] = describe(i, key)
+			}
+		}
+	}
+
+	for _, servedZone := range servedZones {
+		zones, err := normalizeZones(servedZone)
+		if err != nil {
+			return err
+		}
+		for _, zone := range zones {
+			if _, ok := servedBy[zone]; !ok {
+				return fmt.Errorf("no server block serves zone %s, check for unbalanced braces", zone)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeZones returns the transport, zones and port a server block key
+// serves, e.g. dns://xcc.test.:53 for the key xcc.test.
+func normalizeZones(key string) ([]string, error) {
+	trans, address := parse.Transport(key)
+	hosts, port, err := plugin.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if port == "" {
+		switch trans {
+		case transport.TLS:
+			port = transport.TLSPort
+		case transport.GRPC:
+			port = transport.GRPCPort
+		case transport.HTTPS:
+			port = transport.HTTPSPort
+		default:
+			port = transport.Port
+		}
+	}
+
+	zones := make([]string, len(hosts))
+	for i, host := range hosts {
+		zones[i] = fmt.Sprintf("%s://%s:%s", trans, plugin.Name(host).Normalize(), port)
+	}
+	return zones, nil
+}
+
+// serverBlockLines returns the line each server block of the corefile starts
+// on, in order. The caddyfile parser does not keep track of them; a server
+// block starts wherever a line opens a brace at nesting level zero.
+func serverBlockLines(corefile string) []int {
+	var lines []int
+	nesting := 0
+	for i, line := range strings.Split(corefile, "\n") {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, c := range line {
+			switch c {
+			case '{':
+				if nesting == 0 {
+					lines = append(lines, i+1)
+				}
+				nesting++
+			case '}':
+				if nesting > 0 {
+					nesting--
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// withoutFileImports blanks the lines importing files, keeping those importing
+// snippets, without changing the line numbers of the corefile.
+func withoutFileImports(corefile string) string {
+	lines := strings.Split(corefile, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "import" && strings.ContainsAny(fields[1], "/*?") {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateCorefile", func() {
+	table.DescribeTable("accepts valid Corefiles",
+		func(corefile ...string) {
+			Expect(dnsconfig.ValidateCorefile(strings.Join(corefile, "\n"))).To(Succeed())
+		},
+		table.Entry("a single server block",
+			".:53 {",
+			"    forward . /etc/resolv.conf",
+			"}",
+		),
+		table.Entry("different zones",
+			".:53 {",
+			"    forward . /etc/resolv.conf",
+			"}",
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+			"}",
+		),
+		table.Entry("the same zone on different ports",
+			"xcc.test:53 {",
+			"    forward . 1.2.3.4",
+			"}",
+			"xcc.test:1053 {",
+			"    forward . 1.2.3.4",
+			"}",
+		),
+		table.Entry("the same zone on different transports",
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+			"}",
+			"tls://xcc.test {",
+			"    forward . 1.2.3.4",
+			"}",
+		),
+		table.Entry("imports of files that only exist in the CoreDNS pods",
+			".:53 {",
+			"    forward . /etc/resolv.conf",
+			"    import /etc/coredns/custom/*.override",
+			"}",
+			"import /etc/coredns/custom/*.server",
+			"import /etc/coredns/extra.server",
+		),
+	)
+
+	It("rejects Corefiles that fail to parse", func() {
+		err := dnsconfig.ValidateCorefile(strings.Join([]string{
+			".:53 {",
+			"    forward . /etc/resolv.conf",
+			"}",
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+		}, "\n"))
+		Expect(err).To(MatchError(ContainSubstring("failed to parse Corefile")))
+	})
+
+	It("rejects Corefiles without a server block for a zone that must be served", func() {
+		err := dnsconfig.ValidateCorefile(strings.Join([]string{
+			".:53 {",
+			"    forward . /etc/resolv.conf",
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+			"}",
+			"}",
+		}, "\n"), "xcc.test")
+		Expect(err).To(MatchError(ContainSubstring("no server block serves zone dns://xcc.test.:53")))
+	})
+
+	table.DescribeTable("reports the server blocks serving the same zone",
+		func(zone, block, conflictingBlock string, corefile ...string) {
+			err := dnsconfig.ValidateCorefile(strings.Join(corefile, "\n"))
+
+			var conflict *dnsconfig.ZoneConflictError
+			Expect(errors.As(err, &conflict)).To(BeTrue())
+			Expect(conflict.Zone).To(Equal(zone))
+			Expect(conflict.Block).To(Equal(block))
+			Expect(conflict.ConflictingBlock).To(Equal(conflictingBlock))
+		},
+		table.Entry("with the same key",
+			"dns://xcc.test.:53", `"xcc.test" (line 4)`, `"xcc.test" (line 7)`,
+			".:53 {",
+			"    forward . /etc/resolv.conf",
+			"}",
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+			"}",
+			"xcc.test {",
+			"    forward . 5.6.7.8",
+			"}",
+		),
+		table.Entry("with the default port, transport and case spelled differently",
+			"dns://xcc.test.:53", `"XCC.test" (line 1)`, `"dns://xcc.test.:53" (line 4)`,
+			"XCC.test {",
+			"    forward . 1.2.3.4",
+			"}",
+			"dns://xcc.test.:53 {",
+			"    forward . 5.6.7.8",
+			"}",
+		),
+		table.Entry("in a server block with several keys",
+			"dns://xcc.test.:53", `"xcc.test" (line 1)`, `"xcc.test" (line 4)`,
+			"xcc.test {",
+			"    forward . 1.2.3.4",
+			"}",
+			"other.test xcc.test {",
+			"    forward . 5.6.7.8",
+			"}",
+		),
+	)
+})
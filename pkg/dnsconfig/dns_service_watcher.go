@@ -5,13 +5,20 @@ package dnsconfig
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// errServiceNotReady is wrapped by the errors of dnsServiceAddresses when the
+// Service exists but cannot be forwarded to yet.
+var errServiceNotReady = errors.New("service not ready")
+
 type DNSServiceWatcher struct {
 	Client client.Client
 
@@ -46,3 +53,70 @@ func (d *DNSServiceWatcher) GetDNSServiceClusterIP(ctx context.Context) (string,
 		}
 	}
 }
+
+// GetDNSServiceAddresses waits for the IPs to forward to the DNS service: its
+// ClusterIP, or the IPs of its ready pods when it is headless.
+func (d *DNSServiceWatcher) GetDNSServiceAddresses(ctx context.Context) ([]string, error) {
+	for {
+		addresses, err := dnsServiceAddresses(ctx, d.Client, d.Namespace, d.ServiceName)
+		if err == nil {
+			return addresses, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf(`Timed out obtaining addresses of service "%s/%s": %s`, d.Namespace, d.ServiceName, err)
+		case <-time.After(d.PollingInterval):
+		}
+	}
+}
+
+// dnsServiceAddresses returns the ClusterIP of the Service or, when it is
+// headless, the sorted addresses of the ready endpoints of its
+// EndpointSlices.
+func dnsServiceAddresses(ctx context.Context, reader client.Reader, namespace, name string) ([]string, error) {
+	var dnsService corev1.Service
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &dnsService); err != nil {
+		return nil, err
+	}
+
+	switch dnsService.Spec.ClusterIP {
+	case "":
+		return nil, fmt.Errorf(`service "%s/%s" does not have a ClusterIP: %w`, namespace, name, errServiceNotReady)
+	case corev1.ClusterIPNone:
+	default:
+		return []string{dnsService.Spec.ClusterIP}, nil
+	}
+
+	var endpointSlices discoveryv1.EndpointSliceList
+	if err := reader.List(ctx, &endpointSlices,
+		client.InNamespace(namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: name},
+	); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var addresses []string
+	for _, endpointSlice := range endpointSlices.Items {
+		if endpointSlice.AddressType == discoveryv1.AddressTypeFQDN {
+			continue
+		}
+		for _, endpoint := range endpointSlice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				if !seen[address] {
+					seen[address] = true
+					addresses = append(addresses, address)
+				}
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf(`headless service "%s/%s" does not have ready endpoints: %w`, namespace, name, errServiceNotReady)
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
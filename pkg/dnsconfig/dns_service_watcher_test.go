@@ -9,6 +9,7 @@ import (
 
 	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -83,6 +84,48 @@ var _ = Describe("DNSServiceWatcher", func() {
 			Expect(clusterIP).To(BeEmpty())
 		})
 	})
+
+	Describe("GetDNSServiceAddresses", func() {
+		It("returns the ClusterIP for the DNS service", func() {
+			startupDNSService(kubeClient, dnsServiceClusterIP)
+
+			addresses, err := dnsServiceWatcher.GetDNSServiceAddresses(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(addresses).To(Equal([]string{dnsServiceClusterIP}))
+		})
+
+		Context("when the DNS service is headless", func() {
+			It("returns the addresses of its endpoints once they exist", func() {
+				startupDNSService(kubeClient, corev1.ClusterIPNone)
+				time.AfterFunc(10*time.Millisecond, func() {
+					defer GinkgoRecover()
+					Expect(kubeClient.Create(context.Background(), &discoveryv1.EndpointSlice{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "dns-server-abcde",
+							Namespace: "xcc-dns",
+							Labels:    map[string]string{discoveryv1.LabelServiceName: "dns-server"},
+						},
+						AddressType: discoveryv1.AddressTypeIPv6,
+						Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"fd00::2", "fd00::1"}}},
+					})).To(Succeed())
+				})
+
+				addresses, err := dnsServiceWatcher.GetDNSServiceAddresses(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(addresses).To(Equal([]string{"fd00::1", "fd00::2"}))
+			})
+
+			It("returns an error when it has no ready endpoints before the context is done", func() {
+				startupDNSService(kubeClient, corev1.ClusterIPNone)
+
+				addresses, err := dnsServiceWatcher.GetDNSServiceAddresses(ctx)
+				Expect(err).To(MatchError(`Timed out obtaining addresses of service "xcc-dns/dns-server": headless service "xcc-dns/dns-server" does not have ready endpoints: service not ready`))
+				Expect(addresses).To(BeEmpty())
+			})
+		})
+	})
 })
 
 func startupDNSService(kubeClient client.Client, desiredClusterIP string) {
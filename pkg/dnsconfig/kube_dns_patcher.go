@@ -0,0 +1,224 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stubDomainsKey is the key of the kube-dns ConfigMap holding the stub
+// domains, as JSON mapping each domain to the IPs of its name servers.
+const stubDomainsKey = "stubDomains"
+
+// stubDomainsAnnotation lists the stub domains added to the kube-dns ConfigMap
+// so that they can be removed without knowing the domain suffixes.
+const stubDomainsAnnotation = "connectivity.tanzu.vmware.com/stub-domains"
+
+// KubeDNSPatcher forwards the domain suffixes to the dns-server with stub
+// domains of kube-dns.
+type KubeDNSPatcher struct {
+	Client       client.Client
+	Log          logr.Logger
+	DomainSuffix string
+
+	Namespace     string
+	ConfigMapName string
+
+	// StubDomain adds domain suffixes and upstreams to the stub domains.
+	// kube-dns does not support the other options.
+	StubDomain StubDomainConfig
+}
+
+func (k *KubeDNSPatcher) ConfigMapKey() client.ObjectKey {
+	return client.ObjectKey{Namespace: k.Namespace, Name: k.ConfigMapName}
+}
+
+func (k *KubeDNSPatcher) AppendStubDomainBlock(forwardingIPs ...string) error {
+	_, err := k.EnsureStubDomainBlock(forwardingIPs...)
+	return err
+}
+
+// EnsureStubDomainBlock adds the stub domain, creating the ConfigMap when
+// kube-dns runs without one, and returns whether the ConfigMap was changed.
+func (k *KubeDNSPatcher) EnsureStubDomainBlock(forwardingIPs ...string) (bool, error) {
+	if len(forwardingIPs) == 0 {
+		return false, errNoForwardingIPs
+	}
+	domainSuffixes, err := k.stubDomainNames()
+	if err != nil {
+		return false, err
+	}
+
+	var configMap corev1.ConfigMap
+	err = k.Client.Get(context.Background(), k.ConfigMapKey(), &configMap)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return false, err
+	}
+	exists := err == nil
+	if !exists {
+		configMap.ObjectMeta = metav1.ObjectMeta{Namespace: k.Namespace, Name: k.ConfigMapName}
+	}
+
+	stubDomains, err := k.stubDomains(configMap)
+	if err != nil {
+		return false, err
+	}
+
+	nameservers := k.StubDomain.nameservers(forwardingIPs)
+	managed := managedStubDomains(configMap)
+	for _, domainSuffix := range domainSuffixes {
+		managed[domainSuffix] = true
+	}
+	upToDate := configMap.Annotations[stubDomainsAnnotation] == joinStubDomains(managed)
+	for _, domainSuffix := range domainSuffixes {
+		if !reflect.DeepEqual(stubDomains[domainSuffix], nameservers) {
+			stubDomains[domainSuffix] = nameservers
+			upToDate = false
+		}
+	}
+	if upToDate {
+		k.Log.Info("up to date, skipping modification", "ConfigMap", fmt.Sprintf("%s/%s", k.Namespace, k.ConfigMapName))
+		return false, nil
+	}
+
+	if err := k.setStubDomains(&configMap, stubDomains); err != nil {
+		return false, err
+	}
+
+	setManagedStubDomains(&configMap, managed)
+
+	if !exists {
+		k.Log.Info("creating ConfigMap with stub domain", "ConfigMap", fmt.Sprintf("%s/%s", k.Namespace, k.ConfigMapName))
+		return true, k.Client.Create(context.Background(), &configMap)
+	}
+	k.Log.Info("updating stub domains", "ConfigMap", fmt.Sprintf("%s/%s", k.Namespace, k.ConfigMapName))
+	return true, k.Client.Update(context.Background(), &configMap)
+}
+
+// RemoveStubDomainBlock removes the stub domains for the domain suffixes and
+// the ones recorded as added by EnsureStubDomainBlock, so that no domain
+// suffix is needed to remove them. It does nothing when the ConfigMap or the
+// stub domain does not exist.
+func (k *KubeDNSPatcher) RemoveStubDomainBlock() error {
+	var configMap corev1.ConfigMap
+	err := k.Client.Get(context.Background(), k.ConfigMapKey(), &configMap)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			k.Log.Info("ConfigMap not found, nothing to remove", "ConfigMap", fmt.Sprintf("%s/%s", k.Namespace, k.ConfigMapName))
+			return nil
+		}
+		return err
+	}
+
+	stubDomains, err := k.stubDomains(configMap)
+	if err != nil {
+		return err
+	}
+	managed := managedStubDomains(configMap)
+	found := len(managed) > 0
+	for _, domainSuffix := range k.StubDomain.domainSuffixes(k.DomainSuffix) {
+		managed[domainSuffix] = true
+	}
+	for domainSuffix := range managed {
+		if _, ok := stubDomains[domainSuffix]; ok {
+			delete(stubDomains, domainSuffix)
+			found = true
+		}
+	}
+	if !found {
+		k.Log.Info("stub domain not found, skipping modification", "ConfigMap", fmt.Sprintf("%s/%s", k.Namespace, k.ConfigMapName))
+		return nil
+	}
+
+	if err := k.setStubDomains(&configMap, stubDomains); err != nil {
+		return err
+	}
+	setManagedStubDomains(&configMap, nil)
+	k.Log.Info("removing stub domain", "ConfigMap", fmt.Sprintf("%s/%s", k.Namespace, k.ConfigMapName))
+	return k.Client.Update(context.Background(), &configMap)
+}
+
+// stubDomainNames returns the domain suffixes, which must all be domain
+// names: kube-dns rejects the whole ConfigMap when a key of its stubDomains is
+// not, such as a reverse zone given as a CIDR.
+func (k *KubeDNSPatcher) stubDomainNames() ([]string, error) {
+	domainSuffixes := k.StubDomain.domainSuffixes(k.DomainSuffix)
+	for _, domainSuffix := range domainSuffixes {
+		if errs := validation.IsDNS1123Subdomain(domainSuffix); len(errs) > 0 {
+			return nil, fmt.Errorf("domain suffix %q cannot be a kube-dns stub domain, give reverse zones as in-addr.arpa or ip6.arpa names instead of CIDRs: %s",
+				domainSuffix, strings.Join(errs, "; "))
+		}
+	}
+	return domainSuffixes, nil
+}
+
+func (k *KubeDNSPatcher) stubDomains(configMap corev1.ConfigMap) (map[string][]string, error) {
+	stubDomains := map[string][]string{}
+	if data := configMap.Data[stubDomainsKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &stubDomains); err != nil {
+			return nil, fmt.Errorf("refusing to update ConfigMap %s/%s: invalid %s: %w", k.Namespace, k.ConfigMapName, stubDomainsKey, err)
+		}
+	}
+	return stubDomains, nil
+}
+
+func (k *KubeDNSPatcher) setStubDomains(configMap *corev1.ConfigMap, stubDomains map[string][]string) error {
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	if len(stubDomains) == 0 {
+		delete(configMap.Data, stubDomainsKey)
+		return nil
+	}
+	data, err := json.Marshal(stubDomains)
+	if err != nil {
+		return err
+	}
+	configMap.Data[stubDomainsKey] = string(data)
+	return nil
+}
+
+// managedStubDomains returns the stub domains recorded in the annotation of
+// the ConfigMap.
+func managedStubDomains(configMap corev1.ConfigMap) map[string]bool {
+	managed := map[string]bool{}
+	for _, domain := range strings.Split(configMap.Annotations[stubDomainsAnnotation], ",") {
+		if domain != "" {
+			managed[domain] = true
+		}
+	}
+	return managed
+}
+
+func setManagedStubDomains(configMap *corev1.ConfigMap, managed map[string]bool) {
+	if len(managed) == 0 {
+		delete(configMap.Annotations, stubDomainsAnnotation)
+		return
+	}
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+	configMap.Annotations[stubDomainsAnnotation] = joinStubDomains(managed)
+}
+
+func joinStubDomains(domains map[string]bool) string {
+	var sorted []string
+	for domain := range domains {
+		sorted = append(sorted, domain)
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
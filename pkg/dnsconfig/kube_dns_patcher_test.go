@@ -0,0 +1,203 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig_test
+
+import (
+	"context"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KubeDNSPatcher", func() {
+	var (
+		kubeClient client.Client
+
+		patcher          *dnsconfig.KubeDNSPatcher
+		kubeDNSConfigMap corev1.ConfigMap
+	)
+
+	getConfigMap := func() corev1.ConfigMap {
+		var configMap corev1.ConfigMap
+		err := kubeClient.Get(context.Background(), client.ObjectKey{
+			Name:      "kube-dns",
+			Namespace: "kube-system",
+		}, &configMap)
+		Expect(err).NotTo(HaveOccurred())
+		return configMap
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+
+		kubeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		ctrl.SetLogger(zap.New(
+			zap.UseDevMode(true),
+			zap.WriteTo(GinkgoWriter),
+		))
+
+		patcher = &dnsconfig.KubeDNSPatcher{
+			Client:        kubeClient,
+			Log:           ctrl.Log.WithName("dnsconfig").WithName("KubeDNSPatcher"),
+			DomainSuffix:  "xcc.test",
+			Namespace:     "kube-system",
+			ConfigMapName: "kube-dns",
+		}
+
+		kubeDNSConfigMap = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kube-dns",
+				Namespace: "kube-system",
+			},
+			Data: map[string]string{
+				"stubDomains":         `{"acme.local": ["1.2.3.5"]}`,
+				"upstreamNameservers": `["8.8.8.8"]`,
+			},
+		}
+	})
+
+	It("creates the configmap with the stub domain when kube-dns runs without one", func() {
+		Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+
+		Expect(getConfigMap().Data).To(Equal(map[string]string{
+			"stubDomains": `{"xcc.test":["1.2.3.4"]}`,
+		}))
+	})
+
+	It("succeeds removing the stub domain when there is no configmap", func() {
+		Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+	})
+
+	Context("when the configmap exists", func() {
+		BeforeEach(func() {
+			Expect(kubeClient.Create(context.Background(), &kubeDNSConfigMap)).To(Succeed())
+		})
+
+		It("adds the stub domain and keeps the others", func() {
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+
+			Expect(getConfigMap().Data).To(Equal(map[string]string{
+				"stubDomains":         `{"acme.local":["1.2.3.5"],"xcc.test":["1.2.3.4"]}`,
+				"upstreamNameservers": `["8.8.8.8"]`,
+			}))
+		})
+
+		It("adds a stub domain for each domain suffix forwarding to the upstreams", func() {
+			patcher.StubDomain = dnsconfig.StubDomainConfig{
+				DomainSuffixes: []string{"other.test"},
+				Upstreams:      []string{"10.0.0.53"},
+			}
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+
+			Expect(getConfigMap().Data["stubDomains"]).To(Equal(`{"acme.local":["1.2.3.5"],"other.test":["1.2.3.4","10.0.0.53"],"xcc.test":["1.2.3.4","10.0.0.53"]}`))
+
+			Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+			Expect(getConfigMap().Data["stubDomains"]).To(Equal(`{"acme.local":["1.2.3.5"]}`))
+		})
+
+		It("does not update the configmap when it is up to date", func() {
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+			resourceVersion := getConfigMap().ResourceVersion
+
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+			Expect(getConfigMap().ResourceVersion).To(Equal(resourceVersion))
+		})
+
+		It("updates the stub domain when the IP changes", func() {
+			Expect(patcher.AppendStubDomainBlock("42.42.42.42")).To(Succeed())
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+
+			Expect(getConfigMap().Data["stubDomains"]).To(Equal(`{"acme.local":["1.2.3.5"],"xcc.test":["1.2.3.4"]}`))
+		})
+
+		It("removes the stub domain and keeps the others", func() {
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+			Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+
+			Expect(getConfigMap().Data).To(Equal(map[string]string{
+				"stubDomains":         `{"acme.local":["1.2.3.5"]}`,
+				"upstreamNameservers": `["8.8.8.8"]`,
+			}))
+		})
+
+		It("removes the stub domains it added without a domain suffix", func() {
+			patcher.StubDomain = dnsconfig.StubDomainConfig{DomainSuffixes: []string{"other.test"}}
+			Expect(patcher.AppendStubDomainBlock("1.2.3.4")).To(Succeed())
+			Expect(getConfigMap().Annotations).To(HaveKeyWithValue("connectivity.tanzu.vmware.com/stub-domains", "other.test,xcc.test"))
+
+			remover := &dnsconfig.KubeDNSPatcher{
+				Client:        kubeClient,
+				Log:           patcher.Log,
+				Namespace:     "kube-system",
+				ConfigMapName: "kube-dns",
+			}
+			Expect(remover.RemoveStubDomainBlock()).To(Succeed())
+
+			configMap := getConfigMap()
+			Expect(configMap.Data).To(Equal(map[string]string{
+				"stubDomains":         `{"acme.local":["1.2.3.5"]}`,
+				"upstreamNameservers": `["8.8.8.8"]`,
+			}))
+			Expect(configMap.Annotations).NotTo(HaveKey("connectivity.tanzu.vmware.com/stub-domains"))
+		})
+
+		It("removes the stub domain added without the annotation for the domain suffix", func() {
+			configMap := getConfigMap()
+			configMap.Data["stubDomains"] = `{"acme.local":["1.2.3.5"],"xcc.test":["1.2.3.4"]}`
+			Expect(kubeClient.Update(context.Background(), &configMap)).To(Succeed())
+
+			Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+			Expect(getConfigMap().Data["stubDomains"]).To(Equal(`{"acme.local":["1.2.3.5"]}`))
+		})
+
+		It("does not update the configmap when there is no stub domain to remove", func() {
+			resourceVersion := getConfigMap().ResourceVersion
+
+			Expect(patcher.RemoveStubDomainBlock()).To(Succeed())
+			Expect(getConfigMap().ResourceVersion).To(Equal(resourceVersion))
+		})
+	})
+
+	Context("when a domain suffix is a CIDR", func() {
+		BeforeEach(func() {
+			Expect(kubeClient.Create(context.Background(), &kubeDNSConfigMap)).To(Succeed())
+			patcher.StubDomain = dnsconfig.StubDomainConfig{
+				DomainSuffixes: []string{"10.0.0.0/8"},
+			}
+		})
+
+		It("returns an error and leaves the configmap untouched", func() {
+			err := patcher.AppendStubDomainBlock("1.2.3.4")
+			Expect(err).To(MatchError(ContainSubstring(`domain suffix "10.0.0.0/8" cannot be a kube-dns stub domain`)))
+
+			Expect(getConfigMap().Data).To(Equal(kubeDNSConfigMap.Data))
+		})
+	})
+
+	Context("when the stub domains are malformed", func() {
+		BeforeEach(func() {
+			kubeDNSConfigMap.Data["stubDomains"] = `{"acme.local": `
+			Expect(kubeClient.Create(context.Background(), &kubeDNSConfigMap)).To(Succeed())
+		})
+
+		It("returns an error and leaves the configmap untouched", func() {
+			err := patcher.AppendStubDomainBlock("1.2.3.4")
+			Expect(err).To(MatchError(ContainSubstring("invalid stubDomains")))
+
+			Expect(getConfigMap().Data["stubDomains"]).To(Equal(`{"acme.local": `))
+		})
+	})
+})
@@ -0,0 +1,140 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Patcher configures the DNS server of a cluster to forward the domain
+// suffix to the xcc dns-server.
+type Patcher interface {
+	// AppendStubDomainBlock forwards the domain suffix to forwardingIPs,
+	// e.g. the ClusterIP of the dns-server Service or the IPs of its pods.
+	AppendStubDomainBlock(forwardingIPs ...string) error
+
+	// EnsureStubDomainBlock is AppendStubDomainBlock that also returns
+	// whether the ConfigMap had to be changed.
+	EnsureStubDomainBlock(forwardingIPs ...string) (changed bool, err error)
+
+	// RemoveStubDomainBlock stops forwarding the domain suffix. It does
+	// nothing when it is not forwarded.
+	RemoveStubDomainBlock() error
+
+	// ConfigMapKey is the ConfigMap holding the configuration the patcher
+	// edits.
+	ConfigMapKey() client.ObjectKey
+}
+
+// errNoForwardingIPs is returned when a Patcher is given nothing to forward
+// the domain suffix to, which would make the stub domain answer nothing.
+var errNoForwardingIPs = errors.New("no IP to forward the stub domain to")
+
+// Strategy names the kind of cluster DNS a Patcher edits the configuration of.
+type Strategy string
+
+const (
+	// StrategyAuto detects the strategy from the ConfigMaps in the cluster.
+	StrategyAuto Strategy = "auto"
+
+	// StrategyCoreDNS appends a server block to the CoreDNS Corefile.
+	StrategyCoreDNS Strategy = "coredns"
+
+	// StrategyKubeDNS adds the domain suffix to the stubDomains of kube-dns.
+	StrategyKubeDNS Strategy = "kube-dns"
+
+	// StrategyNodeLocalDNS appends a server block to the Corefile of
+	// NodeLocal DNSCache, which answers stub domains without going through
+	// the cluster DNS.
+	StrategyNodeLocalDNS Strategy = "nodelocaldns"
+)
+
+// PatcherOptions holds what Patchers of all strategies are built from.
+type PatcherOptions struct {
+	Client       client.Client
+	Log          logr.Logger
+	DomainSuffix string
+	StubDomain   StubDomainConfig
+
+	// Namespace holds the ConfigMaps of the cluster DNS.
+	Namespace string
+
+	CorefileConfigMapName     string
+	KubeDNSConfigMapName      string
+	NodeLocalDNSConfigMapName string
+}
+
+// DomainSuffixes returns the domain suffixes the Patchers forward.
+func (o PatcherOptions) DomainSuffixes() []string {
+	return o.StubDomain.domainSuffixes(o.DomainSuffix)
+}
+
+// DetectStrategy returns the strategy for the cluster DNS deployed in the
+// namespace of the options. NodeLocal DNSCache is used when its ConfigMap
+// exists, CoreDNS when its Corefile ConfigMap exists, and kube-dns, whose
+// ConfigMap is optional, otherwise.
+func DetectStrategy(ctx context.Context, reader client.Reader, opts PatcherOptions) (Strategy, error) {
+	candidates := []struct {
+		strategy      Strategy
+		configMapName string
+	}{
+		{StrategyNodeLocalDNS, opts.NodeLocalDNSConfigMapName},
+		{StrategyCoreDNS, opts.CorefileConfigMapName},
+	}
+	for _, candidate := range candidates {
+		var configMap corev1.ConfigMap
+		err := reader.Get(ctx, client.ObjectKey{Namespace: opts.Namespace, Name: candidate.configMapName}, &configMap)
+		if err == nil {
+			return candidate.strategy, nil
+		}
+		if !k8serrors.IsNotFound(err) {
+			return "", err
+		}
+	}
+	return StrategyKubeDNS, nil
+}
+
+// NewPatcher returns the Patcher for the strategy, which must not be
+// StrategyAuto.
+func NewPatcher(strategy Strategy, opts PatcherOptions) (Patcher, error) {
+	switch strategy {
+	case StrategyCoreDNS:
+		return &CorefilePatcher{
+			Client:        opts.Client,
+			Log:           opts.Log.WithName("CorefilePatcher"),
+			DomainSuffix:  opts.DomainSuffix,
+			StubDomain:    opts.StubDomain,
+			Namespace:     opts.Namespace,
+			ConfigMapName: opts.CorefileConfigMapName,
+		}, nil
+	case StrategyNodeLocalDNS:
+		return &CorefilePatcher{
+			Client:        opts.Client,
+			Log:           opts.Log.WithName("CorefilePatcher"),
+			DomainSuffix:  opts.DomainSuffix,
+			StubDomain:    opts.StubDomain,
+			Namespace:     opts.Namespace,
+			ConfigMapName: opts.NodeLocalDNSConfigMapName,
+			CopyBind:      true,
+		}, nil
+	case StrategyKubeDNS:
+		return &KubeDNSPatcher{
+			Client:        opts.Client,
+			Log:           opts.Log.WithName("KubeDNSPatcher"),
+			DomainSuffix:  opts.DomainSuffix,
+			StubDomain:    opts.StubDomain,
+			Namespace:     opts.Namespace,
+			ConfigMapName: opts.KubeDNSConfigMapName,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q, must be one of %s, %s or %s", strategy, StrategyCoreDNS, StrategyKubeDNS, StrategyNodeLocalDNS)
+	}
+}
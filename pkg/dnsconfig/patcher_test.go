@@ -0,0 +1,88 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig_test
+
+import (
+	"context"
+
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Patcher", func() {
+	var (
+		scheme  *runtime.Scheme
+		options dnsconfig.PatcherOptions
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+
+		options = dnsconfig.PatcherOptions{
+			Log:                       ctrl.Log.WithName("dnsconfig"),
+			DomainSuffix:              "xcc.test",
+			Namespace:                 "kube-system",
+			CorefileConfigMapName:     "coredns",
+			KubeDNSConfigMapName:      "kube-dns",
+			NodeLocalDNSConfigMapName: "node-local-dns",
+		}
+	})
+
+	table.DescribeTable("DetectStrategy",
+		func(expected dnsconfig.Strategy, configMapNames ...string) {
+			var objects []client.Object
+			for _, name := range configMapNames {
+				objects = append(objects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+				})
+			}
+			kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+			strategy, err := dnsconfig.DetectStrategy(context.Background(), kubeClient, options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strategy).To(Equal(expected))
+		},
+		table.Entry("detects NodeLocal DNSCache before CoreDNS", dnsconfig.StrategyNodeLocalDNS, "coredns", "node-local-dns"),
+		table.Entry("detects CoreDNS", dnsconfig.StrategyCoreDNS, "coredns"),
+		table.Entry("detects kube-dns with its configmap", dnsconfig.StrategyKubeDNS, "kube-dns"),
+		table.Entry("falls back to kube-dns without any configmap", dnsconfig.StrategyKubeDNS),
+	)
+
+	table.DescribeTable("NewPatcher returns the patcher of the strategy",
+		func(strategy dnsconfig.Strategy, expected dnsconfig.Patcher) {
+			patcher, err := dnsconfig.NewPatcher(strategy, options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patcher).To(BeAssignableToTypeOf(expected))
+			Expect(patcher.ConfigMapKey()).To(Equal(client.ObjectKey{
+				Namespace: "kube-system",
+				Name:      map[dnsconfig.Strategy]string{dnsconfig.StrategyCoreDNS: "coredns", dnsconfig.StrategyKubeDNS: "kube-dns", dnsconfig.StrategyNodeLocalDNS: "node-local-dns"}[strategy],
+			}))
+		},
+		table.Entry("coredns", dnsconfig.StrategyCoreDNS, &dnsconfig.CorefilePatcher{}),
+		table.Entry("kube-dns", dnsconfig.StrategyKubeDNS, &dnsconfig.KubeDNSPatcher{}),
+		table.Entry("nodelocaldns", dnsconfig.StrategyNodeLocalDNS, &dnsconfig.CorefilePatcher{}),
+	)
+
+	It("copies the bind directive for NodeLocal DNSCache", func() {
+		patcher, err := dnsconfig.NewPatcher(dnsconfig.StrategyNodeLocalDNS, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patcher.(*dnsconfig.CorefilePatcher).CopyBind).To(BeTrue())
+	})
+
+	It("rejects unknown strategies", func() {
+		_, err := dnsconfig.NewPatcher(dnsconfig.StrategyAuto, options)
+		Expect(err).To(MatchError(ContainSubstring(`unknown strategy "auto"`)))
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// StubDomainConfig configures what the cluster DNS forwards to the
+// dns-server, beyond the domain suffix. It is loaded from YAML or JSON, e.g.
+//
+//	domainSuffixes:
+//	- 10.0.0.0/8
+//	upstreams:
+//	- 10.0.0.53
+//	errors: true
+//	cache: 30
+//	forward:
+//	  policy: sequential
+//	  healthCheck: 5s
+//	  maxFails: 3
+type StubDomainConfig struct {
+	// DomainSuffixes are forwarded in addition to the domain suffix of the
+	// patcher. Reverse zones may be given as CIDRs.
+	DomainSuffixes []string `json:"domainSuffixes,omitempty"`
+
+	// Upstreams are forwarded to after the ClusterIP of the dns-server: the
+	// forward policy defaults to sequential when they are set. kube-dns
+	// does not guarantee the order.
+	Upstreams []string `json:"upstreams,omitempty"`
+
+	// Errors logs the errors of the stub domain server block.
+	Errors bool `json:"errors,omitempty"`
+
+	// Cache caches answers for up to this many seconds when set.
+	Cache *int32 `json:"cache,omitempty"`
+
+	Forward ForwardOptions `json:"forward,omitempty"`
+}
+
+// ForwardOptions are the options of the CoreDNS forward plugin.
+type ForwardOptions struct {
+	// Policy is random, round_robin or sequential. It defaults to
+	// sequential with upstreams, and to random, the default of the forward
+	// plugin, otherwise.
+	Policy string `json:"policy,omitempty"`
+
+	// HealthCheck is the interval of the health checks of the upstreams.
+	HealthCheck string `json:"healthCheck,omitempty"`
+
+	// MaxFails is the number of failed health checks after which an
+	// upstream is considered down.
+	MaxFails *int32 `json:"maxFails,omitempty"`
+}
+
+// LoadStubDomainConfig parses and validates a StubDomainConfig.
+func LoadStubDomainConfig(data []byte) (StubDomainConfig, error) {
+	var config StubDomainConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return StubDomainConfig{}, fmt.Errorf("invalid stub domain config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return StubDomainConfig{}, fmt.Errorf("invalid stub domain config: %w", err)
+	}
+	return config, nil
+}
+
+func (s StubDomainConfig) validate() error {
+	for _, upstream := range s.Upstreams {
+		host := upstream
+		if h, _, err := net.SplitHostPort(upstream); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("upstream %q is not an IP address", upstream)
+		}
+	}
+	if s.Cache != nil && *s.Cache < 0 {
+		return fmt.Errorf("cache must not be negative")
+	}
+	switch s.Forward.Policy {
+	case "", "random", "round_robin", "sequential":
+	default:
+		return fmt.Errorf("forward policy %q must be random, round_robin or sequential", s.Forward.Policy)
+	}
+	if s.Forward.HealthCheck != "" {
+		if _, err := time.ParseDuration(s.Forward.HealthCheck); err != nil {
+			return fmt.Errorf("forward healthCheck: %w", err)
+		}
+	}
+	if s.Forward.MaxFails != nil && *s.Forward.MaxFails < 0 {
+		return fmt.Errorf("forward maxFails must not be negative")
+	}
+	return nil
+}
+
+// domainSuffixes returns the domain suffix followed by the additional ones,
+// without duplicates.
+func (s StubDomainConfig) domainSuffixes(domainSuffix string) []string {
+	var suffixes []string
+	seen := map[string]bool{}
+	for _, suffix := range append([]string{domainSuffix}, s.DomainSuffixes...) {
+		if suffix != "" && !seen[suffix] {
+			seen[suffix] = true
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// nameservers returns the forwarding IPs followed by the upstreams.
+func (s StubDomainConfig) nameservers(forwardingIPs []string) []string {
+	return append(append([]string{}, forwardingIPs...), s.Upstreams...)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig_test
+
+import (
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadStubDomainConfig", func() {
+	It("loads the domain suffixes, upstreams and options", func() {
+		config, err := dnsconfig.LoadStubDomainConfig([]byte(`
+domainSuffixes:
+- 10.0.0.0/8
+upstreams:
+- 10.0.0.53
+- "[fd00::53]:5353"
+errors: true
+cache: 30
+forward:
+  policy: sequential
+  healthCheck: 5s
+  maxFails: 3
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		cache, maxFails := int32(30), int32(3)
+		Expect(config).To(Equal(dnsconfig.StubDomainConfig{
+			DomainSuffixes: []string{"10.0.0.0/8"},
+			Upstreams:      []string{"10.0.0.53", "[fd00::53]:5353"},
+			Errors:         true,
+			Cache:          &cache,
+			Forward: dnsconfig.ForwardOptions{
+				Policy:      "sequential",
+				HealthCheck: "5s",
+				MaxFails:    &maxFails,
+			},
+		}))
+	})
+
+	table.DescribeTable("rejects invalid configs",
+		func(config, message string) {
+			_, err := dnsconfig.LoadStubDomainConfig([]byte(config))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		table.Entry("unknown fields", "domainSuffix: xcc.test", "unknown field"),
+		table.Entry("upstreams that are not IPs", "upstreams: [dns.example.com]", `upstream "dns.example.com" is not an IP address`),
+		table.Entry("negative cache", "cache: -1", "cache must not be negative"),
+		table.Entry("unknown policies", "forward: {policy: fastest}", `forward policy "fastest"`),
+		table.Entry("invalid health check intervals", "forward: {healthCheck: often}", "forward healthCheck"),
+		table.Entry("negative max fails", "forward: {maxFails: -1}", "forward maxFails must not be negative"),
+	)
+})
@@ -0,0 +1,114 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
+)
+
+// CanaryLabel is the label of the TXT record the dns-server serves at the apex
+// of each of its zones, so that it can be queried through the cluster DNS to
+// check that the zone is forwarded.
+const CanaryLabel = "_xcc-canary"
+
+// CanaryName returns the fully qualified name of the canary of the zone.
+func CanaryName(zone string) string {
+	return dns.Fqdn(CanaryLabel + "." + strings.TrimSuffix(zone, "."))
+}
+
+// Verifier checks that the cluster DNS answers for the stub domains once it
+// has reloaded the patched configuration.
+type Verifier struct {
+	Log logr.Logger
+
+	// Server is the host:port of the cluster DNS.
+	Server string
+
+	// ReloadWait is how long to wait for the cluster DNS to reload its
+	// configuration before the first query, e.g. the interval of the CoreDNS
+	// reload plugin.
+	ReloadWait time.Duration
+
+	PollingInterval time.Duration
+
+	// Client defaults to a UDP client when it is nil.
+	Client *dns.Client
+}
+
+// Verify queries the canary of each domain suffix until the cluster DNS
+// answers it or the context is done. Reverse zones given as CIDRs are
+// skipped since the dns-server serves no canary in them.
+func (v *Verifier) Verify(ctx context.Context, domainSuffixes []string) error {
+	if v.ReloadWait > 0 {
+		v.Log.Info("waiting for the cluster DNS to reload", "wait", v.ReloadWait.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(v.ReloadWait):
+		}
+	}
+
+	for _, domainSuffix := range domainSuffixes {
+		if _, _, err := net.ParseCIDR(domainSuffix); err == nil {
+			continue
+		}
+		if err := v.verifyZone(ctx, domainSuffix); err != nil {
+			return err
+		}
+		v.Log.Info("stub zone is live", "zone", domainSuffix, "server", v.Server)
+	}
+	return nil
+}
+
+func (v *Verifier) verifyZone(ctx context.Context, zone string) error {
+	c := v.Client
+	if c == nil {
+		c = &dns.Client{}
+	}
+	name := CanaryName(zone)
+	var lastErr error
+	for {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeTXT)
+		m.RecursionDesired = true
+
+		err := canaryAnswered(c.ExchangeContext(ctx, m, v.Server))
+		if err == nil {
+			return nil
+		}
+		// A query cut short by the context says nothing about the zone.
+		if lastErr == nil || ctx.Err() == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stub zone %s is not live: querying %s TXT from %s: %s", zone, name, v.Server, lastErr)
+		case <-time.After(v.PollingInterval):
+		}
+	}
+}
+
+// canaryAnswered returns an error unless r holds a TXT answer.
+func canaryAnswered(r *dns.Msg, _ time.Duration, err error) error {
+	if err != nil {
+		return err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("got %s", dns.RcodeToString[r.Rcode])
+	}
+	for _, rr := range r.Answer {
+		if _, ok := rr.(*dns.TXT); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("got no TXT record")
+}
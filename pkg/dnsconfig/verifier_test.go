@@ -0,0 +1,112 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnsconfig_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/dnsconfig"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verifier", func() {
+	var (
+		server    *dns.Server
+		live      atomic.Value
+		verifier  *dnsconfig.Verifier
+		ctx       context.Context
+		ctxCancel func()
+	)
+
+	BeforeEach(func() {
+		ctrl.SetLogger(zap.New(
+			zap.UseDevMode(true),
+			zap.WriteTo(GinkgoWriter),
+		))
+
+		// The server stands in for the cluster DNS, answering the canary of
+		// the zones that are live.
+		live.Store(map[string]bool{})
+		mux := dns.NewServeMux()
+		mux.HandleFunc(".", func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			name := r.Question[0].Name
+			if r.Question[0].Qtype == dns.TypeTXT && live.Load().(map[string]bool)[name] {
+				m.Answer = append(m.Answer, &dns.TXT{
+					Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+					Txt: []string{"xcc-dns"},
+				})
+			} else {
+				m.Rcode = dns.RcodeNameError
+			}
+			_ = w.WriteMsg(m)
+		})
+
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		server = &dns.Server{PacketConn: conn, Handler: mux}
+		go func() { _ = server.ActivateAndServe() }()
+
+		verifier = &dnsconfig.Verifier{
+			Log:             ctrl.Log.WithName("Verifier"),
+			Server:          conn.LocalAddr().String(),
+			PollingInterval: 5 * time.Millisecond,
+		}
+		ctx, ctxCancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	})
+
+	AfterEach(func() {
+		ctxCancel()
+		_ = server.Shutdown()
+	})
+
+	It("succeeds when the canary of each zone is answered", func() {
+		live.Store(map[string]bool{"_xcc-canary.xcc.test.": true, "_xcc-canary.other.test.": true})
+
+		Expect(verifier.Verify(ctx, []string{"xcc.test", "other.test."})).To(Succeed())
+	})
+
+	It("skips reverse zones given as CIDRs", func() {
+		live.Store(map[string]bool{"_xcc-canary.xcc.test.": true})
+
+		Expect(verifier.Verify(ctx, []string{"xcc.test", "10.0.0.0/8"})).To(Succeed())
+	})
+
+	It("keeps querying until the zone becomes live", func() {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			live.Store(map[string]bool{"_xcc-canary.xcc.test.": true})
+		}()
+
+		Expect(verifier.Verify(ctx, []string{"xcc.test"})).To(Succeed())
+	})
+
+	It("fails clearly when the zone is not live", func() {
+		err := verifier.Verify(ctx, []string{"xcc.test"})
+
+		Expect(err).To(MatchError(ContainSubstring("stub zone xcc.test is not live")))
+		Expect(err).To(MatchError(ContainSubstring("_xcc-canary.xcc.test. TXT")))
+		Expect(err).To(MatchError(ContainSubstring("NXDOMAIN")))
+	})
+
+	It("waits for the reload before querying", func() {
+		verifier.ReloadWait = time.Second
+
+		Expect(verifier.Verify(ctx, []string{"xcc.test"})).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("names the canary after the zone", func() {
+		Expect(dnsconfig.CanaryName("xcc.test")).To(Equal("_xcc-canary.xcc.test."))
+		Expect(dnsconfig.CanaryName("xcc.test.")).To(Equal("_xcc-canary.xcc.test."))
+	})
+})
@@ -0,0 +1,172 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package applytest emulates server-side apply on top of the fake client of
+// controller-runtime, which does not support it, for the tests of the
+// controllers.
+package applytest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client emulates server-side apply of EndpointSlices. It tracks the labels
+// and annotations that were applied to each EndpointSlice, and treats the
+// fields of an EndpointSlice that was never applied as owned by another field
+// manager. The unstructured objects that install consumers are created or
+// replaced.
+type Client struct {
+	client.Client
+
+	// Applies counts the applies of unstructured objects of each kind.
+	Applies map[string]int
+
+	applied map[types.NamespacedName]map[string]bool
+}
+
+// NewClient returns a Client applying to c.
+func NewClient(c client.Client) *Client {
+	return &Client{
+		Client:  c,
+		Applies: map[string]int{},
+		applied: map[types.NamespacedName]map[string]bool{},
+	}
+}
+
+func (a *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return a.Client.Patch(ctx, obj, patch, opts...)
+	}
+	if object, ok := obj.(*unstructured.Unstructured); ok {
+		return a.applyUnstructured(ctx, object)
+	}
+	applied, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		return fmt.Errorf("apply of %T is not emulated", obj)
+	}
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+	if patchOptions.FieldManager == "" {
+		return k8serrors.NewBadRequest("PATCH requests with apply patch type require a fieldManager")
+	}
+	force := patchOptions.Force != nil && *patchOptions.Force
+
+	key := client.ObjectKeyFromObject(applied)
+	var existing discoveryv1.EndpointSlice
+	err := a.Client.Get(ctx, key, &existing)
+	if k8serrors.IsNotFound(err) {
+		created := applied.DeepCopy()
+		created.TypeMeta = metav1.TypeMeta{}
+		if err := a.Client.Create(ctx, created); err != nil {
+			return err
+		}
+		a.record(applied)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	owned, everApplied := a.applied[key]
+	if !force {
+		var conflicts []string
+		for name, value := range applied.Labels {
+			if existingValue, ok := existing.Labels[name]; ok && existingValue != value && !owned["labels/"+name] {
+				conflicts = append(conflicts, fmt.Sprintf(".metadata.labels.%s", name))
+			}
+		}
+		for name, value := range applied.Annotations {
+			if existingValue, ok := existing.Annotations[name]; ok && existingValue != value && !owned["annotations/"+name] {
+				conflicts = append(conflicts, fmt.Sprintf(".metadata.annotations.%s", name))
+			}
+		}
+		if !everApplied {
+			if existing.AddressType != applied.AddressType {
+				conflicts = append(conflicts, ".addressType")
+			}
+			if !reflect.DeepEqual(existing.Endpoints, applied.Endpoints) {
+				conflicts = append(conflicts, ".endpoints")
+			}
+			if !reflect.DeepEqual(existing.Ports, applied.Ports) {
+				conflicts = append(conflicts, ".ports")
+			}
+		}
+		if len(conflicts) > 0 {
+			return k8serrors.NewConflict(discoveryv1.Resource("endpointslices"), applied.Name,
+				fmt.Errorf("Apply failed with %d conflicts: %s", len(conflicts), strings.Join(conflicts, ", ")))
+		}
+	}
+
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	if existing.Annotations == nil {
+		existing.Annotations = map[string]string{}
+	}
+	for field := range owned {
+		if strings.HasPrefix(field, "labels/") {
+			name := strings.TrimPrefix(field, "labels/")
+			if _, stillApplied := applied.Labels[name]; !stillApplied {
+				delete(existing.Labels, name)
+			}
+		}
+		if strings.HasPrefix(field, "annotations/") {
+			name := strings.TrimPrefix(field, "annotations/")
+			if _, stillApplied := applied.Annotations[name]; !stillApplied {
+				delete(existing.Annotations, name)
+			}
+		}
+	}
+	for name, value := range applied.Labels {
+		existing.Labels[name] = value
+	}
+	for name, value := range applied.Annotations {
+		existing.Annotations[name] = value
+	}
+	existing.AddressType = applied.AddressType
+	existing.Endpoints = applied.Endpoints
+	existing.Ports = applied.Ports
+	if err := a.Client.Update(ctx, &existing); err != nil {
+		return err
+	}
+	a.record(applied)
+	return nil
+}
+
+func (a *Client) applyUnstructured(ctx context.Context, applied *unstructured.Unstructured) error {
+	a.Applies[applied.GetKind()]++
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(applied.GroupVersionKind())
+	err := a.Client.Get(ctx, client.ObjectKeyFromObject(applied), existing)
+	if k8serrors.IsNotFound(err) {
+		return a.Client.Create(ctx, applied.DeepCopy())
+	}
+	if err != nil {
+		return err
+	}
+	replaced := applied.DeepCopy()
+	replaced.SetResourceVersion(existing.GetResourceVersion())
+	return a.Client.Update(ctx, replaced)
+}
+
+func (a *Client) record(applied *discoveryv1.EndpointSlice) {
+	fields := map[string]bool{}
+	for name := range applied.Labels {
+		fields["labels/"+name] = true
+	}
+	for name := range applied.Annotations {
+		fields["annotations/"+name] = true
+	}
+	a.applied[client.ObjectKeyFromObject(applied)] = fields
+}
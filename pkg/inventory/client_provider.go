@@ -0,0 +1,116 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+)
+
+const clientTimeout = 10 * time.Second
+
+// ClusterClientProvider returns the client of a workload cluster, like the
+// ClusterCacheTracker of Cluster API.
+type ClusterClientProvider interface {
+	GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error)
+}
+
+// ClientProvider returns the clients of the RegisteredClusters, built from
+// their kubeconfig Secrets, and those of the other clusters from Fallback,
+// such as the ClusterCacheTracker. Like the inventory, a RegisteredCluster
+// takes precedence over a Cluster with the same name.
+type ClientProvider struct {
+	// Client reads the RegisteredClusters and their Secrets from the
+	// management cluster.
+	Client client.Reader
+
+	// Scheme of the clients of the workload clusters.
+	Scheme *runtime.Scheme
+
+	// SourceName identifies the program in the user agent of the clients.
+	SourceName string
+
+	// Fallback returns the clients of the clusters that are not registered.
+	// Without it, they cannot be reached.
+	Fallback ClusterClientProvider
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]registeredClient
+}
+
+type registeredClient struct {
+	// kubeconfig is the UID and resource version of the Secret the client
+	// was built from, and the key of the kubeconfig in it.
+	kubeconfig string
+	client     client.Client
+}
+
+// GetClient returns the client of the cluster. The client of a
+// RegisteredCluster is built again when its kubeconfig changes. It reads
+// from the API server of the cluster without a cache.
+func (p *ClientProvider) GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error) {
+	var registeredCluster connectivityv1alpha1.RegisteredCluster
+	err := p.Client.Get(ctx, cluster, &registeredCluster)
+	if err != nil {
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			if p.Fallback == nil {
+				return nil, fmt.Errorf("RegisteredCluster %s not found", cluster)
+			}
+			return p.Fallback.GetClient(ctx, cluster)
+		}
+		return nil, err
+	}
+
+	secretRef := registeredCluster.Spec.KubeconfigSecretRef
+	key := secretRef.Key
+	if key == "" {
+		key = connectivityv1alpha1.DefaultKubeconfigSecretKey
+	}
+	var secret corev1.Secret
+	if err := p.Client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: secretRef.Name}, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get the kubeconfig Secret of RegisteredCluster %s: %w", cluster, err)
+	}
+	kubeconfig := fmt.Sprintf("%s/%s/%s", secret.UID, secret.ResourceVersion, key)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.clients[cluster]; ok && cached.kubeconfig == kubeconfig {
+		return cached.client, nil
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("the kubeconfig Secret %s of RegisteredCluster %s has no key %q", secretRef.Name, cluster, key)
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig for RegisteredCluster %s: %w", cluster, err)
+	}
+	restConfig.UserAgent = remote.DefaultClusterAPIUserAgent(p.SourceName)
+	restConfig.Timeout = clientTimeout
+
+	clusterClient, err := client.New(restConfig, client.Options{Scheme: p.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a client for RegisteredCluster %s: %w", cluster, err)
+	}
+	if p.clients == nil {
+		p.clients = map[types.NamespacedName]registeredClient{}
+	}
+	p.clients[cluster] = registeredClient{kubeconfig: kubeconfig, client: clusterClient}
+	return clusterClient, nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory_test
+
+import (
+	"context"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fallbackProvider struct {
+	client   client.Client
+	requests []client.ObjectKey
+}
+
+func (f *fallbackProvider) GetClient(ctx context.Context, cluster client.ObjectKey) (client.Client, error) {
+	f.requests = append(f.requests, cluster)
+	return f.client, nil
+}
+
+var _ = Describe("ClientProvider", func() {
+	var (
+		ctx            context.Context
+		scheme         *runtime.Scheme
+		hubClient      client.Client
+		fallback       *fallbackProvider
+		clientProvider *inventory.ClientProvider
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = connectivityv1alpha1.AddToScheme(scheme)
+
+		hubClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&connectivityv1alpha1.RegisteredCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "eks-cluster"},
+				Spec: connectivityv1alpha1.RegisteredClusterSpec{
+					KubeconfigSecretRef: connectivityv1alpha1.SecretKeyReference{Name: "eks-cluster-kubeconfig"},
+				},
+			},
+		).Build()
+		fallback = &fallbackProvider{client: fake.NewClientBuilder().Build()}
+		clientProvider = &inventory.ClientProvider{
+			Client:     hubClient,
+			Scheme:     scheme,
+			SourceName: "some-source",
+			Fallback:   fallback,
+		}
+	})
+
+	When("the cluster is not registered", func() {
+		It("returns the client of the fallback", func() {
+			clusterClient, err := clientProvider.GetClient(ctx, client.ObjectKey{Namespace: "some-namespace", Name: "cluster-a"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterClient).To(BeIdenticalTo(fallback.client))
+			Expect(fallback.requests).To(Equal([]client.ObjectKey{{Namespace: "some-namespace", Name: "cluster-a"}}))
+		})
+
+		When("there is no fallback", func() {
+			BeforeEach(func() {
+				clientProvider.Fallback = nil
+			})
+
+			It("returns an error", func() {
+				_, err := clientProvider.GetClient(ctx, client.ObjectKey{Namespace: "some-namespace", Name: "cluster-a"})
+				Expect(err).To(MatchError("RegisteredCluster some-namespace/cluster-a not found"))
+			})
+		})
+	})
+
+	When("the cluster is registered", func() {
+		When("the kubeconfig Secret does not exist", func() {
+			It("returns an error without falling back", func() {
+				_, err := clientProvider.GetClient(ctx, client.ObjectKey{Namespace: "some-namespace", Name: "eks-cluster"})
+				Expect(err).To(MatchError(ContainSubstring("failed to get the kubeconfig Secret of RegisteredCluster some-namespace/eks-cluster")))
+				Expect(fallback.requests).To(BeEmpty())
+			})
+		})
+
+		When("the kubeconfig Secret has no value key", func() {
+			BeforeEach(func() {
+				Expect(hubClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "eks-cluster-kubeconfig"},
+					Data:       map[string][]byte{"kubeconfig": []byte("some-kubeconfig")},
+				})).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := clientProvider.GetClient(ctx, client.ObjectKey{Namespace: "some-namespace", Name: "eks-cluster"})
+				Expect(err).To(MatchError(`the kubeconfig Secret eks-cluster-kubeconfig of RegisteredCluster some-namespace/eks-cluster has no key "value"`))
+			})
+		})
+
+		When("the kubeconfig is invalid", func() {
+			BeforeEach(func() {
+				Expect(hubClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "eks-cluster-kubeconfig"},
+					Data:       map[string][]byte{"value": []byte("not a kubeconfig")},
+				})).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := clientProvider.GetClient(ctx, client.ObjectKey{Namespace: "some-namespace", Name: "eks-cluster"})
+				Expect(err).To(MatchError(ContainSubstring("invalid kubeconfig for RegisteredCluster some-namespace/eks-cluster")))
+			})
+		})
+	})
+})
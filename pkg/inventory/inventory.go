@@ -0,0 +1,111 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package inventory lists the workload clusters and builds their clients,
+// whether they are managed by Cluster API or registered with a
+// RegisteredCluster.
+package inventory
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+)
+
+// Lister lists workload clusters. They are returned as Cluster API
+// Clusters, which is how the controllers handle every cluster.
+type Lister interface {
+	ListClusters(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error)
+}
+
+// WithDefault returns the lister, or one of the Cluster API Clusters read by
+// the client when it is nil.
+func WithDefault(lister Lister, c client.Reader) Lister {
+	if lister == nil {
+		return ClusterAPI{Client: c}
+	}
+	return lister
+}
+
+// ClusterAPI lists the Cluster API Clusters.
+type ClusterAPI struct {
+	Client client.Reader
+}
+
+func (c ClusterAPI) ListClusters(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+	var clusterList clusterv1beta1.ClusterList
+	if err := c.Client.List(ctx, &clusterList, opts...); err != nil {
+		return nil, err
+	}
+	return clusterList.Items, nil
+}
+
+// Registered lists the RegisteredClusters.
+type Registered struct {
+	Client client.Reader
+}
+
+func (r Registered) ListClusters(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+	var registeredClusterList connectivityv1alpha1.RegisteredClusterList
+	if err := r.Client.List(ctx, &registeredClusterList, opts...); err != nil {
+		return nil, err
+	}
+	var clusters []clusterv1beta1.Cluster
+	for _, registeredCluster := range registeredClusterList.Items {
+		clusters = append(clusters, AsCluster(registeredCluster))
+	}
+	return clusters, nil
+}
+
+// AsCluster returns the RegisteredCluster as a Cluster with its metadata, so
+// that its labels and paused annotation apply. It has no conditions, so it is
+// never treated as not ready.
+func AsCluster(registeredCluster connectivityv1alpha1.RegisteredCluster) clusterv1beta1.Cluster {
+	return clusterv1beta1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         registeredCluster.Namespace,
+			Name:              registeredCluster.Name,
+			UID:               registeredCluster.UID,
+			Generation:        registeredCluster.Generation,
+			CreationTimestamp: registeredCluster.CreationTimestamp,
+			DeletionTimestamp: registeredCluster.DeletionTimestamp,
+			Labels:            registeredCluster.Labels,
+			Annotations:       registeredCluster.Annotations,
+		},
+	}
+}
+
+// Inventory lists the clusters of each of its listers. Cluster names are
+// shared: a cluster listed by several of them is returned from the first
+// one only. A lister whose kind is not installed on the management cluster
+// lists nothing.
+type Inventory []Lister
+
+func (i Inventory) ListClusters(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+	var clusters []clusterv1beta1.Cluster
+	seen := map[types.NamespacedName]bool{}
+	for _, lister := range i {
+		listed, err := lister.ListClusters(ctx, opts...)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, cluster := range listed {
+			key := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
+}
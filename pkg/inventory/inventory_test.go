@@ -0,0 +1,186 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory_test
+
+import (
+	"context"
+	"errors"
+
+	connectivityv1alpha1 "github.com/vmware-tanzu/cross-cluster-connectivity/apis/connectivity/v1alpha1"
+	"github.com/vmware-tanzu/cross-cluster-connectivity/pkg/inventory"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type listerFunc func(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error)
+
+func (f listerFunc) ListClusters(ctx context.Context, opts ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+	return f(ctx, opts...)
+}
+
+func clusterNames(clusters []clusterv1beta1.Cluster) []string {
+	var names []string
+	for _, cluster := range clusters {
+		names = append(names, cluster.Namespace+"/"+cluster.Name)
+	}
+	return names
+}
+
+var _ = Describe("Inventory", func() {
+	var (
+		ctx        context.Context
+		hubClient  client.Client
+		deleteTime metav1.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = connectivityv1alpha1.AddToScheme(scheme)
+		_ = clusterv1beta1.AddToScheme(scheme)
+
+		deleteTime = metav1.Now().Rfc3339Copy()
+		hubClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-a", Labels: map[string]string{"hasContour": "true"}},
+			},
+			&clusterv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "cluster-b"},
+			},
+			&connectivityv1alpha1.RegisteredCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "some-namespace",
+					Name:        "cluster-b",
+					Labels:      map[string]string{"hasContour": "true"},
+					Annotations: map[string]string{clusterv1beta1.PausedAnnotation: ""},
+					UID:         "some-uid",
+				},
+				Spec: connectivityv1alpha1.RegisteredClusterSpec{
+					KubeconfigSecretRef: connectivityv1alpha1.SecretKeyReference{Name: "cluster-b-kubeconfig"},
+				},
+			},
+			&connectivityv1alpha1.RegisteredCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "eks-cluster", Labels: map[string]string{"hasContour": "true"}},
+				Spec: connectivityv1alpha1.RegisteredClusterSpec{
+					KubeconfigSecretRef: connectivityv1alpha1.SecretKeyReference{Name: "eks-cluster-kubeconfig"},
+				},
+			},
+			&connectivityv1alpha1.RegisteredCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "other-namespace", Name: "gke-cluster"},
+				Spec: connectivityv1alpha1.RegisteredClusterSpec{
+					KubeconfigSecretRef: connectivityv1alpha1.SecretKeyReference{Name: "gke-cluster-kubeconfig"},
+				},
+			},
+		).Build()
+	})
+
+	Describe("Registered", func() {
+		It("lists the RegisteredClusters as Clusters with their metadata", func() {
+			clusters, err := inventory.Registered{Client: hubClient}.ListClusters(ctx, client.InNamespace("some-namespace"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterNames(clusters)).To(Equal([]string{"some-namespace/cluster-b", "some-namespace/eks-cluster"}))
+			Expect(clusters[0].Labels).To(Equal(map[string]string{"hasContour": "true"}))
+			Expect(clusters[0].Annotations).To(HaveKey(clusterv1beta1.PausedAnnotation))
+			Expect(string(clusters[0].UID)).To(Equal("some-uid"))
+			Expect(clusters[0].Status.Conditions).To(BeEmpty())
+		})
+	})
+
+	Describe("AsCluster", func() {
+		It("keeps the deletion timestamp", func() {
+			cluster := inventory.AsCluster(connectivityv1alpha1.RegisteredCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace", Name: "some-cluster", DeletionTimestamp: &deleteTime},
+			})
+			Expect(cluster.DeletionTimestamp).To(Equal(&deleteTime))
+		})
+	})
+
+	Describe("ListClusters", func() {
+		It("lists the clusters of every lister", func() {
+			clusters, err := inventory.Inventory{
+				inventory.Registered{Client: hubClient},
+				inventory.ClusterAPI{Client: hubClient},
+			}.ListClusters(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterNames(clusters)).To(ConsistOf(
+				"some-namespace/cluster-a",
+				"some-namespace/cluster-b",
+				"some-namespace/eks-cluster",
+				"other-namespace/gke-cluster",
+			))
+		})
+
+		It("takes a cluster listed by several listers from the first one", func() {
+			clusters, err := inventory.Inventory{
+				inventory.Registered{Client: hubClient},
+				inventory.ClusterAPI{Client: hubClient},
+			}.ListClusters(ctx, client.InNamespace("some-namespace"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterNames(clusters)).To(Equal([]string{
+				"some-namespace/cluster-b",
+				"some-namespace/eks-cluster",
+				"some-namespace/cluster-a",
+			}))
+			Expect(clusters[0].Annotations).To(HaveKey(clusterv1beta1.PausedAnnotation))
+		})
+
+		It("passes the list options to every lister", func() {
+			clusters, err := inventory.Inventory{
+				inventory.Registered{Client: hubClient},
+				inventory.ClusterAPI{Client: hubClient},
+			}.ListClusters(ctx, client.InNamespace("some-namespace"), client.MatchingLabels{"hasContour": "true"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterNames(clusters)).To(Equal([]string{
+				"some-namespace/cluster-b",
+				"some-namespace/eks-cluster",
+				"some-namespace/cluster-a",
+			}))
+		})
+
+		When("the kind of a lister is not installed", func() {
+			It("lists the clusters of the others", func() {
+				notInstalled := listerFunc(func(context.Context, ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+					return nil, &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "connectivity.tanzu.vmware.com", Kind: "RegisteredCluster"}}
+				})
+				clusters, err := inventory.Inventory{
+					notInstalled,
+					inventory.ClusterAPI{Client: hubClient},
+				}.ListClusters(ctx, client.InNamespace("some-namespace"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(clusterNames(clusters)).To(Equal([]string{"some-namespace/cluster-a", "some-namespace/cluster-b"}))
+			})
+		})
+
+		When("a lister fails", func() {
+			It("returns the error", func() {
+				failing := listerFunc(func(context.Context, ...client.ListOption) ([]clusterv1beta1.Cluster, error) {
+					return nil, errors.New("some-error")
+				})
+				_, err := inventory.Inventory{
+					inventory.ClusterAPI{Client: hubClient},
+					failing,
+				}.ListClusters(ctx)
+				Expect(err).To(MatchError("some-error"))
+			})
+		})
+	})
+
+	Describe("WithDefault", func() {
+		It("lists the Cluster API Clusters without a lister", func() {
+			clusters, err := inventory.WithDefault(nil, hubClient).ListClusters(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterNames(clusters)).To(ConsistOf("some-namespace/cluster-a", "some-namespace/cluster-b"))
+		})
+	})
+})